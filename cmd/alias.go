@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mia-platform/miactl/renderer"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const aliasesKey = "aliases"
+
+var (
+	errAliasNotFound       = errors.New("Alias not found")
+	errInvalidAliasName    = errors.New("Invalid alias name")
+	errAliasShadowsCommand = errors.New("Alias shadows the command")
+	errInvalidAlias        = errors.New("Invalid alias")
+	errAliasArgs           = errors.New("Missing alias arguments")
+)
+
+var (
+	aliasNameRegexp   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	placeholderRegexp = regexp.MustCompile(`\$[1-9]`)
+)
+
+func newAliasCmd() *cobra.Command {
+	aliasCmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage the shortcuts of the miactl commands",
+		Long: `Manage the shortcuts of the miactl commands.
+
+An alias is replaced by its command when it is the first argument of miactl,
+after the miactl flags. The $1 to $9 placeholders of the command are replaced by
+the alias arguments and $@ by all of them; the arguments not used by a
+placeholder are appended to the command.`,
+		// overrides the root hook: the aliases don't connect to a console.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	aliasCmd.AddCommand(newAliasSetCmd())
+	aliasCmd.AddCommand(newAliasListCmd())
+	aliasCmd.AddCommand(newAliasDeleteCmd())
+	return aliasCmd
+}
+
+func newAliasSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set NAME COMMAND",
+		Short: "Create or update an alias of a command",
+		Example: `  # promote staging to production and wait for the pipeline
+  miactl alias set prod-deploy 'deploy promote --from staging --to production --wait'
+
+  # promote the first environment to the second one
+  miactl alias set promote 'deploy promote --from $1 --to $2'`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, command := args[0], args[1]
+			if !aliasNameRegexp.MatchString(name) {
+				return fmt.Errorf("%w: %s", errInvalidAliasName, name)
+			}
+			if isCommand(cmd.Root(), name) {
+				return fmt.Errorf("%w: %s", errAliasShadowsCommand, name)
+			}
+			words, err := splitWords(command)
+			if err != nil {
+				return fmt.Errorf("%w: %s", errInvalidAlias, err)
+			}
+			if len(words) == 0 {
+				return fmt.Errorf("%w: empty command", errInvalidAlias)
+			}
+
+			aliases := viper.GetStringMapString(aliasesKey)
+			aliases[name] = command
+			viper.Set(aliasesKey, aliases)
+			if err := writeConfig(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Alias %s saved\n", name)
+			return nil
+		},
+	}
+}
+
+func newAliasListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the aliases",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkOutputFormat("table", "json", "yaml")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			aliases := viper.GetStringMapString(aliasesKey)
+
+			out := cmd.OutOrStdout()
+			switch outputFormat {
+			case "json":
+				return renderer.NewJSON(out, aliases)
+			case "yaml":
+				return renderer.NewYAML(out, aliases)
+			}
+			names := make([]string, 0, len(aliases))
+			for name := range aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			table := renderer.NewTable(out, []string{"Name", "Command"})
+			for _, name := range names {
+				table.Append([]string{name, aliases[name]})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+func newAliasDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete an alias",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			aliases := viper.GetStringMapString(aliasesKey)
+			if _, ok := aliases[name]; !ok {
+				return fmt.Errorf("%w: %s", errAliasNotFound, name)
+			}
+
+			delete(aliases, name)
+			viper.Set(aliasesKey, aliases)
+			if err := writeConfig(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Alias %s deleted\n", name)
+			return nil
+		},
+	}
+}
+
+// isCommand reports whether name is a command of rootCmd, plugins included.
+func isCommand(rootCmd *cobra.Command, name string) bool {
+	// the help command is added by cobra on execution
+	if name == "help" {
+		return true
+	}
+	cmd, _, err := rootCmd.Find([]string{name})
+	return err == nil && cmd != rootCmd
+}
+
+// expandAliases replaces the alias in args, if any, with its command. The
+// alias is the first argument after the persistent flags of rootCmd, which
+// are kept, and the aliases are read from the config file passed with
+// --config or from the default one. The commands of rootCmd are never
+// replaced.
+func expandAliases(rootCmd *cobra.Command, args []string) ([]string, error) {
+	index, configFile := aliasIndex(rootCmd.PersistentFlags(), args)
+	if index < 0 || isCommand(rootCmd, args[index]) {
+		return args, nil
+	}
+	aliases, err := readAliases(configFile)
+	if err != nil {
+		return nil, err
+	}
+	command, ok := aliases[args[index]]
+	if !ok {
+		return args, nil
+	}
+
+	expanded, err := expandAlias(command, args[index+1:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", args[index], err)
+	}
+	return append(args[:index:index], expanded...), nil
+}
+
+// aliasIndex returns the index of the first argument which is not a flag
+// of flags, or -1, and the value of the --config flag.
+func aliasIndex(flags *pflag.FlagSet, args []string) (int, string) {
+	var configFile string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return -1, configFile
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return i, configFile
+		}
+
+		var flag *pflag.Flag
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value, hasValue = parts[0], parts[1], true
+		}
+		if strings.HasPrefix(arg, "--") {
+			flag = flags.Lookup(name)
+		} else if len(name) == 1 {
+			flag = flags.ShorthandLookup(name)
+		}
+		// the value of a flag not taking an optional value is the next arg
+		if flag != nil && !hasValue && flag.NoOptDefVal == "" && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if flag != nil && flag.Name == "config" {
+			configFile = value
+		}
+	}
+	return -1, configFile
+}
+
+// readAliases reads the aliases from the config file, or from the default
+// one if configFile is empty.
+func readAliases(configFile string) (map[string]string, error) {
+	config := viper.New()
+	if configFile != "" {
+		config.SetConfigFile(configFile)
+	} else {
+		home, err := homedir.Dir()
+		if err != nil {
+			return nil, err
+		}
+		config.AddConfigPath(home)
+		config.SetConfigName(".miaplatformctl")
+	}
+	// as for the other settings, a missing config file means no aliases
+	if err := config.ReadInConfig(); err != nil {
+		return map[string]string{}, nil
+	}
+	return config.GetStringMapString(aliasesKey), nil
+}
+
+// expandAlias returns the args of command, with the placeholders replaced
+// by args.
+func expandAlias(command string, args []string) ([]string, error) {
+	words, err := splitWords(command)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidAlias, err)
+	}
+
+	var expanded []string
+	used, missing, all := 0, 0, false
+	for _, word := range words {
+		if word == "$@" {
+			expanded = append(expanded, args...)
+			all = true
+			continue
+		}
+		expanded = append(expanded, placeholderRegexp.ReplaceAllStringFunc(word, func(placeholder string) string {
+			n, _ := strconv.Atoi(placeholder[1:])
+			if n > used {
+				used = n
+			}
+			if n > len(args) {
+				missing = n
+				return placeholder
+			}
+			return args[n-1]
+		}))
+	}
+	if missing > 0 {
+		return nil, fmt.Errorf("%w: %d required, %d passed", errAliasArgs, used, len(args))
+	}
+	if !all {
+		expanded = append(expanded, args[used:]...)
+	}
+	return expanded, nil
+}
+
+// splitWords splits s in words as a shell does, handling single and double
+// quotes and backslash escapes.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped {
+		return nil, errors.New("unterminated escape")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
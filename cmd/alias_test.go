@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasCommands(t *testing.T) {
+	t.Run("set saves the alias", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "prod-deploy", "deploy promote --from staging --to production --wait", "--config", configPath)
+		require.NoError(t, err)
+		require.Equal(t, "Alias prod-deploy saved\n", out)
+
+		viper.Reset()
+		viper.SetConfigFile(configPath)
+		require.NoError(t, viper.ReadInConfig())
+		require.Equal(t, map[string]string{"prod-deploy": "deploy promote --from staging --to production --wait"}, viper.GetStringMapString(aliasesKey))
+	})
+
+	t.Run("set returns error if the alias shadows a command", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "get", "get projects", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: get", errAliasShadowsCommand))
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "help", "get projects", "--config", configPath)
+		require.True(t, errors.Is(err, errAliasShadowsCommand))
+	})
+
+	t.Run("set returns error on invalid alias", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "Prod.Deploy", "get projects", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: Prod.Deploy", errInvalidAliasName))
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "deploys", "get deployments --project 'my project", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: unterminated ' quote", errInvalidAlias))
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "alias", "set", "deploys", " ", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: empty command", errInvalidAlias))
+	})
+
+	t.Run("list prints the aliases", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `aliases:
+  projects: get projects
+  deploys: get deployments --project $1
+`)
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "alias", "list", "--config", configPath)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"NAME | COMMAND",
+			"deploys | get deployments --project $1",
+			"projects | get projects",
+		}, renderer.CleanTableRows(out))
+
+		out, err = executeRootCommandWithContext(sdk.MockClientError{}, "alias", "list", "--config", configPath, "-o", "json")
+		require.NoError(t, err)
+		var aliases map[string]string
+		require.NoError(t, json.Unmarshal([]byte(out), &aliases))
+		require.Equal(t, "get projects", aliases["projects"])
+	})
+
+	t.Run("delete removes the alias", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `aliases:
+  projects: get projects
+  deploys: get deployments
+`)
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "alias", "delete", "deploys", "--config", configPath)
+		require.NoError(t, err)
+		require.Equal(t, "Alias deploys deleted\n", out)
+
+		aliases, err := readAliases(configPath)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"projects": "get projects"}, aliases)
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "alias", "delete", "deploys", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: deploys", errAliasNotFound))
+	})
+}
+
+func TestExpandAliases(t *testing.T) {
+	configPath, cleanup := setupConfigFile(t, `aliases:
+  prod-deploy: deploy promote --from staging --to production --wait
+  promote: deploy promote --from $1 --to $2
+  get: get companies
+`)
+	defer cleanup()
+	configFlag := fmt.Sprintf("--config=%s", configPath)
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "appends the alias args",
+			args:     []string{configFlag, "prod-deploy", "--yes"},
+			expected: []string{configFlag, "deploy", "promote", "--from", "staging", "--to", "production", "--wait", "--yes"},
+		},
+		{
+			name:     "skips the flags and their values",
+			args:     []string{"--config", configPath, "-p", "my-project", "--timeout=5s", "prod-deploy"},
+			expected: []string{"--config", configPath, "-p", "my-project", "--timeout=5s", "deploy", "promote", "--from", "staging", "--to", "production", "--wait"},
+		},
+		{
+			name:     "replaces the placeholders",
+			args:     []string{configFlag, "promote", "staging", "production", "--yes"},
+			expected: []string{configFlag, "deploy", "promote", "--from", "staging", "--to", "production", "--yes"},
+		},
+		{
+			name:     "keeps the commands",
+			args:     []string{configFlag, "get", "projects"},
+			expected: []string{configFlag, "get", "projects"},
+		},
+		{
+			name:     "keeps the args without alias",
+			args:     []string{configFlag, "unknown"},
+			expected: []string{configFlag, "unknown"},
+		},
+		{
+			name:     "keeps the args without command",
+			args:     []string{configFlag, "--help"},
+			expected: []string{configFlag, "--help"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			args, err := expandAliases(NewRootCmd(), testCase.args)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, args)
+		})
+	}
+
+	t.Run("returns error on missing placeholder args", func(t *testing.T) {
+		_, err := expandAliases(NewRootCmd(), []string{configFlag, "promote", "staging"})
+		require.EqualError(t, err, fmt.Sprintf("promote: %s: 2 required, 1 passed", errAliasArgs))
+		require.True(t, errors.Is(err, errAliasArgs))
+	})
+
+	t.Run("executes the command of the alias", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `aliases:
+  companies: get companies
+`)
+		defer cleanup()
+
+		rootCmd := NewRootCmd()
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+			Renderer: renderer.New(buf),
+			miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{
+				Companies: sdk.Companies{{Name: "Company", TenantID: "company-1"}},
+			}),
+		})
+
+		code := executeRoot(ctx, rootCmd, []string{"--config", configPath, apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "companies"})
+		require.Equal(t, 0, code)
+		require.Equal(t, []string{"# | NAME | COMPANY ID", "1 | Company | company-1"}, renderer.CleanTableRows(buf.String()))
+	})
+}
+
+func TestExpandAlias(t *testing.T) {
+	testCases := []struct {
+		name     string
+		command  string
+		args     []string
+		expected []string
+	}{
+		{name: "without placeholders", command: "get projects", args: []string{"-o", "json"}, expected: []string{"get", "projects", "-o", "json"}},
+		{name: "with placeholders in words", command: "get deployments --project=$1", args: []string{"my-project", "-o", "json"}, expected: []string{"get", "deployments", "--project=my-project", "-o", "json"}},
+		{name: "with repeated placeholders", command: "deploy promote --from $1 --to $1", args: []string{"staging"}, expected: []string{"deploy", "promote", "--from", "staging", "--to", "staging"}},
+		{name: "with all args", command: "deploy rollback $@ --yes", args: []string{"--env", "production"}, expected: []string{"deploy", "rollback", "--env", "production", "--yes"}},
+		{name: "with quotes", command: `context set "my prod" --company 'my company'`, args: nil, expected: []string{"context", "set", "my prod", "--company", "my company"}},
+		{name: "with escapes", command: `get deployments --project my\ project $1`, args: []string{"x"}, expected: []string{"get", "deployments", "--project", "my project", "x"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expanded, err := expandAlias(testCase.command, testCase.args)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, expanded)
+		})
+	}
+}
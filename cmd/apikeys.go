@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// newAPIKeysCmd creates the command grouping the api keys operations
+func newAPIKeysCmd() *cobra.Command {
+	apiKeysCmd := &cobra.Command{
+		Use:   "api-keys",
+		Short: "Manage the api keys of a project",
+		// overrides the root hook, whose steps are repeated, to require the project.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the root hook starts the audit and fills the flags, so it must
+			// run before the project flag is checked
+			if err := cmd.Root().PersistentPreRunE(cmd, args); err != nil {
+				return err
+			}
+			return cmd.MarkFlagRequired("project")
+		},
+	}
+
+	apiKeysCmd.AddCommand(newAPIKeysListCmd())
+	apiKeysCmd.AddCommand(newAPIKeysCreateCmd())
+	apiKeysCmd.AddCommand(newAPIKeysDeleteCmd())
+	apiKeysCmd.AddCommand(newAPIKeysRotateCmd())
+	return apiKeysCmd
+}
+
+func newAPIKeysListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the api keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat("table", "json", "yaml"); err != nil {
+				return err
+			}
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			apiKeys, err := f.MiaClient.APIKeys.List(projectID)
+			if err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			if renderStructured(f, apiKeys) {
+				return nil
+			}
+			table := f.Renderer.Table([]string{"#", "Name", "Id", "Roles", "Created At"})
+			for i, apiKey := range apiKeys {
+				table.Append([]string{
+					strconv.Itoa(i + 1),
+					apiKey.Name,
+					apiKey.ID,
+					strings.Join(apiKey.Roles, ", "),
+					renderer.FormatDate(apiKey.CreatedAt),
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+func newAPIKeysCreateCmd() *cobra.Command {
+	var (
+		name          string
+		roles         []string
+		saveToContext bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an api key",
+		Long: `Create an api key with the given roles.
+
+The key is printed only once: use --save-to-context to store it in the current context.`,
+		Example: `  # create an api key for the pipelines
+  miactl api-keys create --project my-project --name ci --role developer`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if name == "" {
+				return fmt.Errorf("%w: --name", errMissingValue)
+			}
+			if len(roles) == 0 {
+				return fmt.Errorf("%w: --role", errMissingValue)
+			}
+			for _, role := range roles {
+				if err := validateRole(role); err != nil {
+					return err
+				}
+			}
+			if err := checkSaveToContext(saveToContext); err != nil {
+				return err
+			}
+
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			apiKey, err := f.MiaClient.APIKeys.Create(projectID, sdk.CreateAPIKeyRequest{Name: name, Roles: roles})
+			if err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Api key %s created (id %s)\n", apiKey.Name, apiKey.ID)
+			return printAPIKeySecret(cmd, apiKey, saveToContext)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "name of the api key")
+	cmd.Flags().StringSliceVar(&roles, "role", nil, fmt.Sprintf("roles of the api key, among %s", strings.Join(memberRoles, ", ")))
+	cmd.Flags().BoolVar(&saveToContext, "save-to-context", false, "save the key as api key of the current context")
+	return cmd
+}
+
+func newAPIKeysDeleteCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:          "delete ID",
+		Short:        "Delete an api key",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "The api key %s will stop working\n", args[0])
+			if !yes && !confirm(cmd, "Do you want to continue?") {
+				fmt.Fprintln(out, "Deletion aborted")
+				return nil
+			}
+			if err := f.MiaClient.APIKeys.Delete(projectID, args[0]); err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			fmt.Fprintf(out, "Api key %s deleted\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	return cmd
+}
+
+func newAPIKeysRotateCmd() *cobra.Command {
+	var (
+		yes           bool
+		saveToContext bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rotate ID",
+		Short: "Replace the key of an api key",
+		Long: `Replace the key of an api key, keeping its name and roles. The previous key stops working.
+
+The new key is printed only once: use --save-to-context to store it in the current context.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkSaveToContext(saveToContext); err != nil {
+				return err
+			}
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "The current key of api key %s will stop working\n", args[0])
+			if !yes && !confirm(cmd, "Do you want to continue?") {
+				fmt.Fprintln(out, "Rotation aborted")
+				return nil
+			}
+			apiKey, err := f.MiaClient.APIKeys.Rotate(projectID, args[0])
+			if err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			fmt.Fprintf(out, "Api key %s rotated\n", apiKey.ID)
+			return printAPIKeySecret(cmd, apiKey, saveToContext)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	cmd.Flags().BoolVar(&saveToContext, "save-to-context", false, "save the new key as api key of the current context")
+	return cmd
+}
+
+// checkSaveToContext returns an error if the key must be saved and there is
+// no current context, so that the key is not created to be lost.
+func checkSaveToContext(saveToContext bool) error {
+	if !saveToContext {
+		return nil
+	}
+	name, _, err := currentContext()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errNoCurrentContext
+	}
+	return nil
+}
+
+// printAPIKeySecret prints the key, which can not be retrieved again, and
+// saves it in the current context if requested.
+func printAPIKeySecret(cmd *cobra.Command, apiKey *sdk.APIKey, saveToContext bool) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Key: %s\n", apiKey.Key)
+	fmt.Fprintln(out, "The key will not be shown again, store it safely.")
+	if !saveToContext {
+		return nil
+	}
+
+	name, err := setCurrentContextValue("apiKey", apiKey.Key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Key saved in context %s\n", name)
+	return nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysList(t *testing.T) {
+	t.Run("lists api keys", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeys: sdk.APIKeys{
+				{ID: "key-1", Name: "ci", Roles: []string{"developer"}, CreatedAt: time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)},
+				{ID: "key-2", Name: "monitoring", Roles: []string{"reporter", "guest"}, CreatedAt: time.Date(2020, 4, 15, 8, 30, 0, 0, time.UTC)},
+			},
+		}, "api-keys", "list", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"# | NAME | ID | ROLES | CREATED AT",
+			"1 | ci | key-1 | developer | 01 Apr 2020 10:00 UTC",
+			"2 | monitoring | key-2 | reporter, guest | 15 Apr 2020 08:30 UTC",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("returns error without project", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "api-keys", "list", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, `required flag(s) "project" not set`)
+	})
+}
+
+func TestAPIKeysCreate(t *testing.T) {
+	flags := []string{"api-keys", "create", "--project=project-id", "--name=ci", "--role=developer,reporter", apiKeyFlag, apiBaseURLFlag, apiCookieFlag}
+
+	t.Run("creates api key and prints the key", func(t *testing.T) {
+		var created bool
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeyCreateAssertFn: func(projectID string, request sdk.CreateAPIKeyRequest) {
+				created = true
+				require.Equal(t, "project-id", projectID)
+				require.Equal(t, sdk.CreateAPIKeyRequest{Name: "ci", Roles: []string{"developer", "reporter"}}, request)
+			},
+		}, flags...)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, "Api key ci created (id created-id)\nKey: created-key\nThe key will not be shown again, store it safely.\n", out)
+	})
+
+	t.Run("returns error on invalid role", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "api-keys", "create", "--project=project-id", "--name=ci", "--role=admin", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, fmt.Sprintf("%s: admin", errInvalidRole))
+	})
+
+	t.Run("saves the key in the current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    apiKey: old-key
+`)
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, append(flags, "--config", configPath, "--save-to-context")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Key saved in context prod\n")
+
+		viper.Reset()
+		viper.SetConfigFile(configPath)
+		require.NoError(t, viper.ReadInConfig())
+		contexts, err := readContexts()
+		require.NoError(t, err)
+		require.Equal(t, miaContext{APIBaseURL: "https://prod/", APIKey: "created-key"}, contexts["prod"])
+	})
+
+	t.Run("returns error saving the key without current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeyCreateAssertFn: func(string, sdk.CreateAPIKeyRequest) {
+				t.Fatal("api key should not be created")
+			},
+		}, append(flags, "--config", configPath, "--save-to-context")...)
+		require.EqualError(t, err, errNoCurrentContext.Error())
+		require.NotContains(t, out, "Key: created-key\n")
+	})
+}
+
+func TestAPIKeysDeleteAndRotate(t *testing.T) {
+	t.Run("delete asks for confirmation", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			APIKeyDeleteAssertFn: func(string, string) {
+				t.Fatal("api key should not be deleted")
+			},
+		}, "n\n", "api-keys", "delete", "key-1", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, "The api key key-1 will stop working\nDo you want to continue? [y/N]: Deletion aborted\n", out)
+	})
+
+	t.Run("deletes api key", func(t *testing.T) {
+		var deleted string
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeyDeleteAssertFn: func(projectID, apiKeyID string) {
+				require.Equal(t, "project-id", projectID)
+				deleted = apiKeyID
+			},
+		}, "api-keys", "delete", "key-1", "--yes", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, "key-1", deleted)
+		require.Equal(t, "The api key key-1 will stop working\nApi key key-1 deleted\n", out)
+	})
+
+	t.Run("rotates api key", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{}, "y\n", "api-keys", "rotate", "key-1", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, "The current key of api key key-1 will stop working\nDo you want to continue? [y/N]: Api key key-1 rotated\nKey: rotated-key\nThe key will not be shown again, store it safely.\n", out)
+	})
+
+	t.Run("rotate renders sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeysError: sdk.ErrHTTP,
+		}, "api-keys", "rotate", "key-1", "--yes", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("The current key of api key key-1 will stop working\n%s\n", sdk.ErrHTTP), out)
+	})
+
+	t.Run("rotate returns error saving the key without current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			APIKeyRotateAssertFn: func(string, string) {
+				t.Fatal("api key should not be rotated")
+			},
+		}, "api-keys", "rotate", "key-1", "--yes", "--save-to-context", "--config", configPath, "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, errNoCurrentContext.Error())
+		require.NotContains(t, out, "will stop working")
+	})
+}
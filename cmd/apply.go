@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// notificationClient sends the deploy notifications to the webhooks.
+var notificationClient = &http.Client{Timeout: 10 * time.Second}
+
+var applyPlanHeaders = []string{"#", "Project", "Environment", "Ref", "Deploy Type", "Deployed Ref", "Action"}
+
+// applyStep is the deploy planned for a manifest.
+type applyStep struct {
+	manifest    deployManifest
+	config      sdk.DeployConfig
+	deployedRef string
+	upToDate    bool
+}
+
+func newApplyCmd() *cobra.Command {
+	var (
+		files              []string
+		dryRun, yes, force bool
+		policy             policyOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Deploy the project environments described by manifest files",
+		Long: `Deploy the project environments described by manifest files.
+
+Each YAML document of the files is a deploy manifest:
+
+  apiVersion: miactl/v1
+  kind: Deploy
+  project: my-project              # required
+  environment: production          # required
+  ref: v1.2.0                      # required, branch or tag
+  deployType: smart_deploy         # smart_deploy or deploy_all, default the last one of the environment
+  forceDeployWhenNoSemver: false
+  wait: true                       # wait for the deploy pipeline to finish
+  timeout: 10m                     # maximum wait, default 15m
+  notifications:
+    - webhook: https://hooks.example.com/deploys
+      on: [triggered, success, failed, canceled]  # default all
+
+The manifests are validated and the plan of the deploys is printed before
+applying them in order. The environments whose last successful deploy has the
+ref of the manifest are skipped, unless --force is set. The apply stops at the
+first deploy which does not succeed.
+
+The webhooks receive a POST request with a json body for each event.
+
+The deploys are checked against the deploy policy of the current context
+before asking for confirmation.`,
+		Example: `  # show what would be deployed
+  miactl apply -f deploy.yaml --dry-run
+
+  # deploy without confirmation
+  miactl apply -f staging.yaml -f production.yaml --yes`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifests, err := readManifestFiles(cmd, files)
+			if err != nil {
+				return err
+			}
+			if err := validateManifests(manifests); err != nil {
+				return err
+			}
+
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			steps, err := planManifests(f, manifests, force)
+			if err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			return applyManifests(cmd, f, steps, dryRun, yes, policy)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&files, "filename", "f", nil, "manifest file, - to read it from the standard input")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the plan without deploying")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	cmd.Flags().BoolVar(&force, "force", false, "deploy also the environments where the ref is already deployed")
+	addPolicyFlags(cmd, &policy)
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+// readManifestFiles reads the manifests of the files, in order.
+func readManifestFiles(cmd *cobra.Command, files []string) ([]deployManifest, error) {
+	var manifests []deployManifest
+	for _, file := range files {
+		var r io.Reader
+		name := file
+		if file == "-" {
+			r, name = cmd.InOrStdin(), "stdin"
+		} else {
+			manifestFile, err := os.Open(file)
+			if err != nil {
+				return nil, err
+			}
+			defer manifestFile.Close()
+			r = manifestFile
+		}
+
+		fileManifests, err := readManifests(r, name)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, fileManifests...)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("%w: no manifest found", errInvalidManifest)
+	}
+	return manifests, nil
+}
+
+// planManifests checks that the projects and environments of the
+// manifests exist and returns the deploy of each manifest.
+func planManifests(f *Factory, manifests []deployManifest, force bool) ([]applyStep, error) {
+	projects, err := f.MiaClient.Projects.Get()
+	if err != nil {
+		return nil, err
+	}
+	histories := map[string][]sdk.DeployItem{}
+
+	steps := make([]applyStep, 0, len(manifests))
+	for _, manifest := range manifests {
+		project := findProject(projects, manifest.Project)
+		if project == nil {
+			return nil, fmt.Errorf("%s: %w: %s", manifest.source, sdk.ErrProjectNotFound, manifest.Project)
+		}
+		if !hasEnvironment(project, manifest.Environment) {
+			return nil, fmt.Errorf("%s: %w: %s", manifest.source, errEnvironmentNotFound, manifest.Environment)
+		}
+
+		history, ok := histories[manifest.Project]
+		if !ok {
+			envs := manifestEnvironments(manifests, manifest.Project)
+			history, err = getHistoryUntil(f, manifest.Project, func(history []sdk.DeployItem) bool {
+				for _, env := range envs {
+					if _, deployed := lastSuccessfulDeploy(history, env); !deployed {
+						return false
+					}
+				}
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+			histories[manifest.Project] = history
+		}
+
+		step := applyStep{
+			manifest: manifest,
+			config: sdk.DeployConfig{
+				Environment:             manifest.Environment,
+				Revision:                manifest.Ref,
+				DeployType:              manifest.DeployType,
+				ForceDeployWhenNoSemver: manifest.ForceDeployWhenNoSemver,
+			},
+		}
+		if deployed, ok := lastSuccessfulDeploy(history, manifest.Environment); ok {
+			step.deployedRef = deployed.Ref
+			step.upToDate = deployed.Ref == manifest.Ref && !force
+		}
+		if step.config.DeployType == "" {
+			step.config.DeployType = lastDeployType(history, manifest.Environment)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// manifestEnvironments returns the environments of the manifests of project.
+func manifestEnvironments(manifests []deployManifest, project string) []string {
+	var envs []string
+	for _, manifest := range manifests {
+		if manifest.Project == project {
+			envs = append(envs, manifest.Environment)
+		}
+	}
+	return envs
+}
+
+func findProject(projects sdk.Projects, id string) *sdk.Project {
+	for i := range projects {
+		if projects[i].ProjectID == id {
+			return &projects[i]
+		}
+	}
+	return nil
+}
+
+// applyManifests prints the plan and, once checked against the policy and
+// confirmed, triggers the deploys in order.
+func applyManifests(cmd *cobra.Command, f *Factory, steps []applyStep, dryRun, yes bool, policy policyOptions) error {
+	var deploys int
+	table := f.Renderer.Table(applyPlanHeaders)
+	for i, step := range steps {
+		action := "deploy"
+		if step.upToDate {
+			action = "skip, already deployed"
+		} else {
+			deploys++
+		}
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			step.manifest.Project,
+			step.manifest.Environment,
+			step.manifest.Ref,
+			step.config.DeployType,
+			step.deployedRef,
+			action,
+		})
+	}
+	table.Render()
+
+	out := cmd.OutOrStdout()
+	if deploys == 0 {
+		fmt.Fprintln(out, "Nothing to apply")
+		return nil
+	}
+	if dryRun {
+		return nil
+	}
+	gate, err := newDeployGate(cmd, policy)
+	if err != nil {
+		return err
+	}
+	for _, step := range steps {
+		if step.upToDate {
+			continue
+		}
+		if err := gate.check(step.manifest.Project, step.manifest.Environment, step.manifest.Ref); err != nil {
+			return fmt.Errorf("%s: %w", step.manifest.source, err)
+		}
+	}
+	if !yes && !gate.confirm(fmt.Sprintf("Do you want to apply %d deploys?", deploys)) {
+		fmt.Fprintln(out, "Apply aborted")
+		return nil
+	}
+
+	for _, step := range steps {
+		if step.upToDate {
+			continue
+		}
+		ok, err := applyDeploy(out, f, step)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDeploy triggers the deploy of step, waits for it if requested and
+// sends the notifications. It returns false if the apply must stop.
+func applyDeploy(out io.Writer, f *Factory, step applyStep) (bool, error) {
+	manifest := step.manifest
+	response, err := f.MiaClient.Deploy.Trigger(manifest.Project, step.config)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", manifest.source, err)
+	}
+
+	fmt.Fprintf(out, "Deploy pipeline #%d of %s triggered on %s of %s: %s\n", response.ID, manifest.Ref, manifest.Environment, manifest.Project, response.URL)
+	notification := deployNotification{
+		Event:       notificationTriggered,
+		ProjectID:   manifest.Project,
+		Environment: manifest.Environment,
+		Ref:         manifest.Ref,
+		PipelineID:  response.ID,
+		PipelineURL: response.URL,
+		Time:        timeNow(),
+	}
+	sendNotifications(out, manifest.Notifications, notification)
+	if !manifest.Wait {
+		return true, nil
+	}
+
+	status, err := waitForDeploy(f, manifest.Project, response.ID, manifest.Environment, manifest.timeout)
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprintf(out, "Deploy pipeline #%d finished with status %s\n", response.ID, status)
+	notification.Event, notification.Time = status, timeNow()
+	sendNotifications(out, manifest.Notifications, notification)
+	if status != sdk.DeployStatusSuccess {
+		return false, fmt.Errorf("%s: %w: %s", manifest.source, errDeployNotSucceeded, status)
+	}
+	return true, nil
+}
+
+// deployNotification is the body of the requests sent to the webhooks of a
+// manifest. Event is triggered or the final status of the pipeline.
+type deployNotification struct {
+	Event       string    `json:"event"`
+	ProjectID   string    `json:"projectId"`
+	Environment string    `json:"environment"`
+	Ref         string    `json:"ref"`
+	PipelineID  int       `json:"pipelineId"`
+	PipelineURL string    `json:"pipelineUrl"`
+	Time        time.Time `json:"time"`
+}
+
+// sendNotifications posts the notification to the webhooks of its event.
+// A failed notification is reported without stopping the apply.
+func sendNotifications(out io.Writer, notifications []manifestNotification, notification deployNotification) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		fmt.Fprintf(out, "Notification failed: %s\n", err)
+		return
+	}
+	for _, webhook := range notifications {
+		if !webhook.notifies(notification.Event) {
+			continue
+		}
+		if err := postNotification(webhook.Webhook, body); err != nil {
+			fmt.Fprintf(out, "Notification to %s failed: %s\n", webhook.Webhook, err)
+		}
+	}
+}
+
+func postNotification(url string, body []byte) error {
+	response, err := notificationClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("status code %d", response.StatusCode)
+	}
+	return nil
+}
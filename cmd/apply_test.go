@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const applyTestManifests = `apiVersion: miactl/v1
+kind: Deploy
+project: project-id
+environment: staging
+ref: v1.3.0
+---
+# the empty documents are skipped
+---
+apiVersion: miactl/v1
+kind: Deploy
+project: project-id
+environment: production
+ref: v1.3.0
+deployType: smart_deploy
+wait: true
+timeout: 1m
+`
+
+func writeManifest(t *testing.T, content string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "miactl-manifests")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "deploy.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestReadManifests(t *testing.T) {
+	t.Run("reads the documents", func(t *testing.T) {
+		manifests, err := readManifests(strings.NewReader(applyTestManifests), "deploy.yaml")
+		require.NoError(t, err)
+		require.Len(t, manifests, 2)
+		require.Equal(t, "staging", manifests[0].Environment)
+		require.Equal(t, "deploy.yaml document 1", manifests[0].source)
+		require.Equal(t, "smart_deploy", manifests[1].DeployType)
+		require.True(t, manifests[1].Wait)
+		require.Equal(t, "deploy.yaml document 3", manifests[1].source)
+	})
+
+	t.Run("returns error on unknown fields", func(t *testing.T) {
+		_, err := readManifests(strings.NewReader("apiVersion: miactl/v1\nkind: Deploy\nrevision: v1.0.0\n"), "deploy.yaml")
+		require.True(t, errors.Is(err, errInvalidManifest))
+		require.Contains(t, err.Error(), "deploy.yaml document 1: yaml: unmarshal errors")
+		require.Contains(t, err.Error(), "field revision not found")
+	})
+}
+
+func TestValidateManifests(t *testing.T) {
+	t.Run("accepts valid manifests", func(t *testing.T) {
+		manifests, err := readManifests(strings.NewReader(applyTestManifests), "deploy.yaml")
+		require.NoError(t, err)
+		require.NoError(t, validateManifests(manifests))
+		require.Equal(t, defaultManifestTimeout, manifests[0].timeout)
+		require.Equal(t, time.Minute, manifests[1].timeout)
+	})
+
+	t.Run("lists all the problems", func(t *testing.T) {
+		manifests, err := readManifests(strings.NewReader(`apiVersion: v1
+kind: Deployment
+project: project-id
+deployType: fast
+timeout: soon
+notifications:
+  - webhook: hooks.example.com
+    on: [started]
+---
+apiVersion: miactl/v1
+kind: Deploy
+project: project-id
+environment: production
+ref: v1.3.0
+timeout: 5m
+`), "deploy.yaml")
+		require.NoError(t, err)
+
+		err = validateManifests(manifests)
+		require.EqualError(t, err, fmt.Sprintf(`%s:
+  deploy.yaml document 1: apiVersion must be miactl/v1
+  deploy.yaml document 1: kind must be Deploy
+  deploy.yaml document 1: environment is required
+  deploy.yaml document 1: ref is required
+  deploy.yaml document 1: deployType must be one of smart_deploy, deploy_all
+  deploy.yaml document 1: timeout "soon" is not a duration
+  deploy.yaml document 1: notifications[0].webhook must be an http or https url
+  deploy.yaml document 1: notifications[0].on must contain only triggered, success, failed, canceled
+  deploy.yaml document 2: timeout requires wait`, errInvalidManifest))
+	})
+}
+
+func TestApply(t *testing.T) {
+	deployStatusPollInterval = time.Millisecond
+	baseArgs := []string{"apply", apiKeyFlag, apiBaseURLFlag, apiCookieFlag}
+	manifestPath, cleanup := writeManifest(t, applyTestManifests)
+	defer cleanup()
+
+	t.Run("returns error without files", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, baseArgs...)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"filename\" not set"))
+	})
+
+	t.Run("returns error on invalid manifests", func(t *testing.T) {
+		_, err := executeRootCommandWithInput(sdk.MockClientError{}, "apiVersion: miactl/v1\nkind: Deploy\n", append(baseArgs, "-f", "-")...)
+		require.EqualError(t, err, fmt.Sprintf("%s:\n  stdin document 1: project is required\n  stdin document 1: environment is required\n  stdin document 1: ref is required", errInvalidManifest))
+	})
+
+	t.Run("returns error without manifests", func(t *testing.T) {
+		_, err := executeRootCommandWithInput(sdk.MockClientError{}, "---\n", append(baseArgs, "-f", "-")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: no manifest found", errInvalidManifest))
+	})
+
+	t.Run("renders error if environment does not exist", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, "apiVersion: miactl/v1\nkind: Deploy\nproject: project-id\nenvironment: qa\nref: v1.0.0\n", append(baseArgs, "-f", "-")...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("stdin document 1: %s: qa\n", errEnvironmentNotFound), out)
+	})
+
+	t.Run("prints the plan on dry run", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(string, sdk.DeployConfig) {
+				t.Fatal("deploy should not be triggered")
+			},
+		}, append(baseArgs, "-f", manifestPath, "--dry-run")...)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"# | PROJECT | ENVIRONMENT | REF | DEPLOY TYPE | DEPLOYED REF | ACTION",
+			"1 | project-id | staging | v1.3.0 |  | v1.3.0 | skip, already deployed",
+			"2 | project-id | production | v1.3.0 | smart_deploy | v1.2.0 | deploy",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("prints nothing to apply when up to date", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+		}, "apiVersion: miactl/v1\nkind: Deploy\nproject: project-id\nenvironment: staging\nref: v1.3.0\n", append(baseArgs, "-f", "-")...)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(out, "Nothing to apply\n"))
+	})
+
+	t.Run("aborts without confirmation", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(string, sdk.DeployConfig) {
+				t.Fatal("deploy should not be triggered")
+			},
+		}, "n\n", append(baseArgs, "-f", manifestPath)...)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(out, "Do you want to apply 1 deploys? [y/N]: Apply aborted\n"))
+	})
+
+	t.Run("deploys the manifests in order", func(t *testing.T) {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger, "project-id", mock.Anything).Return(sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"}, nil)
+		m.On(mock.DeployGetStatus, "project-id", 42, "production").Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusSuccess}, nil)
+
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "-f", manifestPath, "--force", "--yes")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Deploy pipeline #42 of v1.3.0 triggered on staging of project-id: https://pipeline/42\nDeploy pipeline #42 of v1.3.0 triggered on production of project-id: https://pipeline/42\nDeploy pipeline #42 finished with status success\n")
+
+		require.Equal(t, []mock.Call{
+			{Method: mock.DeployTrigger, Args: []interface{}{"project-id", sdk.DeployConfig{Environment: "staging", Revision: "v1.3.0"}}},
+			{Method: mock.DeployTrigger, Args: []interface{}{"project-id", sdk.DeployConfig{Environment: "production", Revision: "v1.3.0", DeployType: "smart_deploy"}}},
+		}, m.Calls(mock.DeployTrigger))
+		m.AssertNumberOfCalls(t, mock.DeployGetHistory, 1)
+		m.AssertCalledOnceWith(t, mock.DeployGetStatus, "project-id", 42, "production")
+	})
+
+	t.Run("stops at the first deploy which does not succeed", func(t *testing.T) {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 43}, nil)
+		m.On(mock.DeployGetStatus).Return(sdk.PipelineStatus{ID: 43, Status: sdk.DeployStatusFailed}, nil)
+
+		_, err := executeRootCommandWithMock(m, append(baseArgs, "-f", manifestPath, "-f", manifestPath, "--yes")...)
+		require.EqualError(t, err, fmt.Sprintf("%s document 3: %s: failed", manifestPath, errDeployNotSucceeded))
+		m.AssertNumberOfCalls(t, mock.DeployTrigger, 1)
+	})
+
+	t.Run("returns error if the trigger fails", func(t *testing.T) {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{}, sdk.ErrHTTP)
+
+		_, err := executeRootCommandWithMock(m, append(baseArgs, "-f", manifestPath, "--force", "--yes")...)
+		require.True(t, errors.Is(err, sdk.ErrHTTP))
+		require.True(t, strings.HasPrefix(err.Error(), manifestPath+" document 1: "))
+		m.AssertNumberOfCalls(t, mock.DeployTrigger, 1)
+		m.AssertNotCalled(t, mock.DeployGetStatus)
+	})
+
+	t.Run("plans the deploys paging the history", func(t *testing.T) {
+		var pages []int
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: pagedDeployTestHistory(),
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+		}, append(baseArgs, "-f", manifestPath, "--dry-run")...)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, pages)
+		require.Equal(t, []string{
+			"# | PROJECT | ENVIRONMENT | REF | DEPLOY TYPE | DEPLOYED REF | ACTION",
+			"1 | project-id | staging | v1.3.0 |  | v1.3.0 | skip, already deployed",
+			"2 | project-id | production | v1.3.0 | smart_deploy | v1.2.0 | deploy",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("sends the notifications", func(t *testing.T) {
+		timeNow = func() time.Time { return time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC) }
+		defer func() { timeNow = time.Now }()
+
+		var mu sync.Mutex
+		var notifications []deployNotification
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var notification deployNotification
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			mu.Lock()
+			notifications = append(notifications, notification)
+			mu.Unlock()
+			if r.URL.Path == "/broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+
+		manifest := fmt.Sprintf(`apiVersion: miactl/v1
+kind: Deploy
+project: project-id
+environment: production
+ref: v1.3.0
+wait: true
+notifications:
+  - webhook: %[1]s/all
+  - webhook: %[1]s/broken
+    on: [failed]
+`, server.URL)
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+			DeployStatuses:        []sdk.PipelineStatus{{ID: 42, Status: sdk.DeployStatusFailed}},
+		}, manifest, append(baseArgs, "-f", "-", "--yes")...)
+		require.Error(t, err)
+		require.Contains(t, out, fmt.Sprintf("Notification to %s/broken failed: status code 500\n", server.URL))
+
+		notification := deployNotification{
+			Event:       "triggered",
+			ProjectID:   "project-id",
+			Environment: "production",
+			Ref:         "v1.3.0",
+			PipelineID:  42,
+			PipelineURL: "https://pipeline/42",
+			Time:        timeNow(),
+		}
+		failed := notification
+		failed.Event = "failed"
+		require.Equal(t, []deployNotification{notification, failed, failed}, notifications)
+	})
+}
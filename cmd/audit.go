@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	auditFileKey = "audit.file"
+	auditSinkKey = "audit.sink"
+
+	// auditAnnotation marks the commands whose runs are audited.
+	auditAnnotation = "miactl-audit"
+
+	auditResultSuccess = "success"
+	auditResultError   = "error"
+)
+
+// auditedCommands are the commands changing the console, whose runs are
+// written to the audit log.
+var auditedCommands = [][]string{
+	{"apply"},
+	{"deploy", "promote"},
+	{"deploy", "rollback"},
+	{"deploy", "batch"},
+	{"project", "create"},
+	{"service", "create"},
+	{"members", "add"},
+	{"members", "remove"},
+	{"members", "set-role"},
+	{"api-keys", "create"},
+	{"api-keys", "delete"},
+	{"api-keys", "rotate"},
+}
+
+// auditLogFile is the audit log, the default is the audit.file key of the
+// config file or the audit.log file in the .miactl folder of the home
+// directory.
+var auditLogFile string
+
+var errInvalidAuditLog = errors.New("Invalid audit log")
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Context string    `json:"context,omitempty"`
+	Project string    `json:"project,omitempty"`
+	Command string    `json:"command"`
+	Args    []string  `json:"args"`
+	Result  string    `json:"result"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// auditRunKey is the context key of the auditRun of the command execution.
+type auditRunKey struct{}
+
+// auditRun tracks the audited command of an execution. The errors printed
+// by the renderer, instead of being returned, are recorded in err.
+type auditRun struct {
+	cmd     *cobra.Command
+	err     error
+	written bool
+}
+
+func withAuditRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditRunKey{}, &auditRun{})
+}
+
+// auditRunFromContext returns the auditRun of ctx, nil outside executeRoot.
+func auditRunFromContext(ctx context.Context) *auditRun {
+	run, _ := ctx.Value(auditRunKey{}).(*auditRun)
+	return run
+}
+
+// auditRenderer records in run the errors it renders.
+type auditRenderer struct {
+	renderer.IRenderer
+	run *auditRun
+}
+
+func (r auditRenderer) Error(err error) renderer.IError {
+	if err != nil {
+		r.run.err = err
+	}
+	return r.IRenderer.Error(err)
+}
+
+// markAuditedCommands annotates the audited commands of rootCmd.
+func markAuditedCommands(rootCmd *cobra.Command) {
+	for _, path := range auditedCommands {
+		cmd, _, err := rootCmd.Find(path)
+		if err != nil || cmd == rootCmd {
+			continue
+		}
+		if cmd.Annotations == nil {
+			cmd.Annotations = map[string]string{}
+		}
+		cmd.Annotations[auditAnnotation] = "true"
+	}
+}
+
+// startAudit records cmd as the command of the execution of ctx, so that
+// it is audited also when it fails. The hooks overriding the root
+// PersistentPreRunE on an audited command must call the root hook.
+func startAudit(ctx context.Context, cmd *cobra.Command) {
+	if run := auditRunFromContext(ctx); run != nil {
+		run.cmd = cmd
+	}
+}
+
+// finishAudit writes the entry of the run of cmd, if audited, to the audit
+// log and to the sink, failing with err or with the error rendered. An
+// entry is written once per execution. The audit failures are reported
+// without changing the result of the command.
+func finishAudit(ctx context.Context, cmd *cobra.Command, err error) {
+	if _, audited := cmd.Annotations[auditAnnotation]; !audited {
+		return
+	}
+	run := auditRunFromContext(ctx)
+	if run == nil {
+		run = &auditRun{}
+	}
+	if run.written {
+		return
+	}
+	run.written = true
+	if err == nil {
+		err = run.err
+	}
+
+	for _, problem := range writeAuditEntry(newAuditEntry(cmd, err)) {
+		fmt.Fprintln(cmd.ErrOrStderr(), problem)
+	}
+}
+
+// writeAuditEntry appends entry to the audit log and sends it to the sink,
+// returning the description of the failures.
+func writeAuditEntry(entry auditEntry) []string {
+	problems := []string{}
+	if err := appendAuditLog(entry); err != nil {
+		problems = append(problems, fmt.Sprintf("Audit log error: %s", err))
+	}
+	if sink := viper.GetString(auditSinkKey); sink != "" {
+		body, _ := json.Marshal(entry)
+		if err := postNotification(sink, body); err != nil {
+			problems = append(problems, fmt.Sprintf("Audit sink error: %s", err))
+		}
+	}
+	return problems
+}
+
+func newAuditEntry(cmd *cobra.Command, err error) auditEntry {
+	entry := auditEntry{
+		Time:    timeNow(),
+		User:    currentUsername(),
+		Context: viper.GetString(currentContextKey),
+		Project: projectID,
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		Args:    auditArgs(cmd),
+		Result:  auditResultSuccess,
+	}
+	if err != nil {
+		entry.Result, entry.Error = auditResultError, err.Error()
+	}
+	return entry
+}
+
+// auditArgs returns the args of cmd followed by the flags set, from the
+// command line or the other config sources, with the secrets redacted.
+func auditArgs(cmd *cobra.Command) []string {
+	secrets := map[string]bool{}
+	for _, key := range configKeys {
+		secrets[key.flag] = key.secret
+	}
+
+	args := append([]string{}, cmd.Flags().Args()...)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		if secrets[flag.Name] && value != "" {
+			value = redactedValue
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+	})
+	return args
+}
+
+func auditLogPath() (string, error) {
+	if auditLogFile != "" {
+		return auditLogFile, nil
+	}
+	if file := viper.GetString(auditFileKey); file != "" {
+		return homedir.Expand(file)
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".miactl", "audit.log"), nil
+}
+
+// appendAuditLog appends entry to the audit log, one json object per line.
+func appendAuditLog(entry auditEntry) error {
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(entry)
+}
+
+// readAuditLog returns the entries of the audit log, none if it does not
+// exist.
+func readAuditLog() ([]auditEntry, error) {
+	path, err := auditLogPath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%w: %s line %d: %s", errInvalidAuditLog, path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// auditFilter selects the entries shown by audit show.
+type auditFilter struct {
+	since   string
+	command string
+	user    string
+	result  string
+	limit   int
+}
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the audit log of the miactl commands changing the console",
+		Long: `Inspect the audit log of the miactl commands changing the console.
+
+Each run of a command changing the console, like deploy promote or members add,
+and each deploy triggered from the ui, as ui deploy, is appended to the audit log
+with the time, the user, the context, the project, the command, its args and
+flags, with the secrets redacted, and the result.
+
+The audit log is the audit.log file of the .miactl folder of the home directory,
+or the file of the audit.file key of the config file. When the audit.sink key
+is set, each entry is also sent in a POST request with a json body to its url.`,
+		// overrides the root hook: the audit log is local.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	auditCmd.AddCommand(newAuditShowCmd())
+	return auditCmd
+}
+
+func newAuditShowCmd() *cobra.Command {
+	var filter auditFilter
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the entries of the audit log, from the oldest",
+		Example: `  # the failed deploys of the last day
+  miactl audit show --since 24h --command deploy --result error
+
+  # the last 10 commands run on a project
+  miactl audit show --project my-project --limit 10`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if filter.result != "" && filter.result != auditResultSuccess && filter.result != auditResultError {
+				return fmt.Errorf("--result must be %s or %s", auditResultSuccess, auditResultError)
+			}
+			return checkOutputFormat("table", "json", "yaml")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := readAuditLog()
+			if err != nil {
+				return err
+			}
+			entries, err = filterAuditEntries(entries, filter, projectID)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch outputFormat {
+			case "json":
+				return renderer.NewJSON(out, entries)
+			case "yaml":
+				return renderer.NewYAML(out, entries)
+			}
+			if len(entries) == 0 {
+				fmt.Fprintln(out, "No audit entry found")
+				return nil
+			}
+			table := renderer.NewTable(out, []string{"Time", "User", "Context", "Project", "Command", "Result", "Error"})
+			for _, entry := range entries {
+				table.Append([]string{
+					renderer.FormatDate(entry.Time),
+					entry.User,
+					entry.Context,
+					entry.Project,
+					entry.Command,
+					entry.Result,
+					entry.Error,
+				})
+			}
+			table.Render()
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&filter.since, "since", "", "show the entries after a duration ago, like 24h, or a date, like 2020-04-25")
+	cmd.Flags().StringVar(&filter.command, "command", "", "show the entries of the command and of its subcommands, like deploy")
+	cmd.Flags().StringVar(&filter.user, "user", "", "show the entries of the user")
+	cmd.Flags().StringVar(&filter.result, "result", "", "show the entries with the result, success or error")
+	cmd.Flags().IntVar(&filter.limit, "limit", 0, "show only the last entries (default all)")
+	return cmd
+}
+
+// filterAuditEntries returns the entries selected by filter and, if not
+// empty, of project.
+func filterAuditEntries(entries []auditEntry, filter auditFilter, project string) ([]auditEntry, error) {
+	var since time.Time
+	if filter.since != "" {
+		if duration, err := time.ParseDuration(filter.since); err == nil {
+			since = timeNow().Add(-duration)
+		} else if since, err = parseFreezeTime(filter.since, time.Local, false); err != nil {
+			return nil, fmt.Errorf("invalid --since %q: a duration or a date is required", filter.since)
+		}
+	}
+
+	selected := []auditEntry{}
+	for _, entry := range entries {
+		switch {
+		case entry.Time.Before(since),
+			filter.command != "" && entry.Command != filter.command && !strings.HasPrefix(entry.Command, filter.command+" "),
+			filter.user != "" && entry.User != filter.user,
+			filter.result != "" && entry.Result != filter.result,
+			project != "" && entry.Project != project:
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	if filter.limit > 0 && len(selected) > filter.limit {
+		selected = selected[len(selected)-filter.limit:]
+	}
+	return selected, nil
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain keeps the audit log of the audited commands run by the tests out
+// of the home directory.
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "miactl-audit")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	auditLogFile = filepath.Join(dir, "audit.log")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// useAuditLog sets an empty audit log, returning the function reading its
+// entries and the one restoring the previous log.
+func useAuditLog(t *testing.T) (func() []auditEntry, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "miactl-audit")
+	require.NoError(t, err)
+	previous := auditLogFile
+	auditLogFile = filepath.Join(dir, "audit.log")
+	read := func() []auditEntry {
+		t.Helper()
+		entries, err := readAuditLog()
+		require.NoError(t, err)
+		for i := range entries {
+			entries[i].User = ""
+		}
+		return entries
+	}
+	return read, func() {
+		auditLogFile = previous
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC) }
+	defer func() { timeNow = time.Now }()
+	promoteArgs := []string{"deploy", "promote", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--from=staging", "--to=production", "--yes"}
+
+	t.Run("writes the successful runs of the audited commands", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+		}, promoteArgs...)
+		require.NoError(t, err)
+
+		require.Equal(t, []auditEntry{{
+			Time:    timeNow(),
+			Project: "project-id",
+			Command: "deploy promote",
+			Args: []string{
+				"--apiBaseUrl=https://local.io/base-path/",
+				"--apiCookie=********",
+				"--apiKey=********",
+				"--from=staging",
+				"--project=project-id",
+				"--to=production",
+				"--yes=true",
+			},
+			Result: auditResultSuccess,
+		}}, readEntries())
+	})
+
+	t.Run("writes the errors rendered by the audited commands", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		rootCmd := NewRootCmd()
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+			Renderer:         renderer.New(buf),
+			miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{ProjectsError: sdk.ErrHTTP}),
+		})
+		require.Equal(t, 0, executeRoot(ctx, rootCmd, promoteArgs))
+
+		entries := readEntries()
+		require.Len(t, entries, 1)
+		require.Equal(t, auditResultError, entries[0].Result)
+		require.Equal(t, sdk.ErrHTTP.Error(), entries[0].Error)
+	})
+
+	t.Run("writes the errors returned by the audited commands", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+		deployStatusPollInterval = time.Millisecond
+
+		rootCmd := NewRootCmd()
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+			Renderer: renderer.New(buf),
+			miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{
+				Projects:              deployTestProjects,
+				DeployHistory:         deployTestHistory,
+				DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+				DeployStatuses:        []sdk.PipelineStatus{{ID: 42, Status: sdk.DeployStatusFailed}},
+			}),
+		})
+		require.Equal(t, 1, executeRoot(ctx, rootCmd, append(promoteArgs, "--wait")))
+
+		entries := readEntries()
+		require.Len(t, entries, 1)
+		require.Equal(t, auditResultError, entries[0].Result)
+		require.Equal(t, fmt.Sprintf("%s: failed", errDeployNotSucceeded), entries[0].Error)
+	})
+
+	t.Run("writes the runs of the commands of groups overriding the root hook", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+		apiKeysArgs := []string{"api-keys", "create", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--name=ci"}
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, append(apiKeysArgs, "--role=developer")...)
+		require.NoError(t, err)
+
+		rootCmd := NewRootCmd()
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+			Renderer:         renderer.New(buf),
+			miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{}),
+		})
+		require.Equal(t, 1, executeRoot(ctx, rootCmd, append(apiKeysArgs, "--role=admin")))
+
+		entries := readEntries()
+		require.Len(t, entries, 2)
+		require.Equal(t, "api-keys create", entries[0].Command)
+		require.Equal(t, auditResultSuccess, entries[0].Result)
+		require.Equal(t, "api-keys create", entries[1].Command)
+		require.Equal(t, auditResultError, entries[1].Result)
+		require.Equal(t, fmt.Sprintf("%s: admin", errInvalidRole), entries[1].Error)
+	})
+
+	t.Run("does not write the other commands", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Empty(t, readEntries())
+	})
+
+	t.Run("sends the entries to the sink", func(t *testing.T) {
+		_, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		var received []auditEntry
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var entry auditEntry
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&entry))
+			received = append(received, entry)
+		}))
+		defer server.Close()
+		configPath, configCleanup := setupConfigFile(t, fmt.Sprintf("audit:\n  sink: %s\n", server.URL))
+		defer configCleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+		}, append(promoteArgs, "--config", configPath)...)
+		require.NoError(t, err)
+		require.Len(t, received, 1)
+		require.Equal(t, "deploy promote", received[0].Command)
+	})
+
+	t.Run("reports the sink errors", func(t *testing.T) {
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		configPath, configCleanup := setupConfigFile(t, fmt.Sprintf("audit:\n  sink: %s\n", server.URL))
+		defer configCleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+		}, append(promoteArgs, "--config", configPath)...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Audit sink error: status code 503\n")
+		require.Len(t, readEntries(), 1)
+	})
+}
+
+func TestAuditShow(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC) }
+	defer func() { timeNow = time.Now }()
+	_, cleanup := useAuditLog(t)
+	defer cleanup()
+
+	for _, entry := range []auditEntry{
+		{Time: timeNow().Add(-48 * time.Hour), User: "alice", Project: "project-1", Command: "members add", Result: auditResultSuccess},
+		{Time: timeNow().Add(-2 * time.Hour), User: "bob", Context: "prod", Project: "project-1", Command: "deploy promote", Result: auditResultError, Error: "Deploy did not succeed: failed"},
+		{Time: timeNow().Add(-time.Hour), User: "alice", Context: "prod", Project: "project-2", Command: "deploy rollback", Result: auditResultSuccess},
+	} {
+		require.NoError(t, appendAuditLog(entry))
+	}
+
+	t.Run("shows all the entries", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "audit", "show")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"TIME | USER | CONTEXT | PROJECT | COMMAND | RESULT | ERROR",
+			"23 Apr 2020 12:00 UTC | alice |  | project-1 | members add | success",
+			"25 Apr 2020 10:00 UTC | bob | prod | project-1 | deploy promote | error | Deploy did not succeed: failed",
+			"25 Apr 2020 11:00 UTC | alice | prod | project-2 | deploy rollback | success",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("filters the entries", func(t *testing.T) {
+		testCases := []struct {
+			args     []string
+			commands []string
+		}{
+			{[]string{"--since", "24h"}, []string{"deploy promote", "deploy rollback"}},
+			{[]string{"--since", "2020-04-25T00:00:00Z"}, []string{"deploy promote", "deploy rollback"}},
+			{[]string{"--command", "deploy"}, []string{"deploy promote", "deploy rollback"}},
+			{[]string{"--command", "deploy promote"}, []string{"deploy promote"}},
+			{[]string{"--command", "dep"}, []string{}},
+			{[]string{"--user", "alice"}, []string{"members add", "deploy rollback"}},
+			{[]string{"--result", "error"}, []string{"deploy promote"}},
+			{[]string{"--project", "project-1"}, []string{"members add", "deploy promote"}},
+			{[]string{"--limit", "2"}, []string{"deploy promote", "deploy rollback"}},
+		}
+		for _, testCase := range testCases {
+			out, err := executeRootCommandWithContext(sdk.MockClientError{}, append([]string{"audit", "show", "-o", "json"}, testCase.args...)...)
+			require.NoError(t, err)
+			var entries []auditEntry
+			require.NoError(t, json.Unmarshal([]byte(out), &entries))
+			commands := []string{}
+			for _, entry := range entries {
+				commands = append(commands, entry.Command)
+			}
+			require.Equal(t, testCase.commands, commands, testCase.args)
+		}
+	})
+
+	t.Run("returns error on invalid filters", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "audit", "show", "--result", "ok")
+		require.EqualError(t, err, "--result must be success or error")
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "audit", "show", "--since", "yesterday")
+		require.EqualError(t, err, `invalid --since "yesterday": a duration or a date is required`)
+	})
+
+	t.Run("returns error on invalid audit log", func(t *testing.T) {
+		file, err := os.OpenFile(auditLogFile, os.O_APPEND|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		_, err = file.WriteString("not json\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "audit", "show")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), fmt.Sprintf("%s: %s line 4: ", errInvalidAuditLog, auditLogFile))
+	})
+}
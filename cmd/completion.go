@@ -1,9 +1,40 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+
+	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 )
 
+// completionCacheTTL is how long the values fetched from the console are
+// reused by the following completion requests.
+const completionCacheTTL = time.Minute
+
+// completionHistoryPages is the maximum number of deploy history pages read
+// to complete the deploy ids, so that completing stays fast on a long
+// history.
+const completionHistoryPages = 3
+
+// completionCacheFile is the file where the completion values are cached,
+// the default is in the .miactl folder of the home directory.
+var completionCacheFile string
+
+// completionCacheEntry holds the completions returned for a cache key.
+type completionCacheEntry struct {
+	Expiration  time.Time `json:"expiration"`
+	Completions []string  `json:"completions"`
+}
+
 // completionCmd represents the completion command
 func newCompletionCmd(rootCmd *cobra.Command) *cobra.Command {
 	longUsage := `To load completion run
@@ -25,12 +56,20 @@ func newCompletionCmd(rootCmd *cobra.Command) *cobra.Command {
 	To generate the completion script, run miactl completion zsh
 	the generated completion script should be put somewhere in your $fpath named _miactl
 
+	For powershell:
+
+	miactl completion powershell | Out-String | Invoke-Expression
+
+	To load completions for each session, add the output of the command above to your powershell profile.
+
 	---
 
 	After reloading your shell, miactl autocompletion should be working.
+	With bash and fish, project ids, environment ids and deploy ids are completed
+	with the values read from the console, cached for a minute.
 	`
 
-	validArgs := []string{"bash", "fish", "zsh"}
+	validArgs := []string{"bash", "fish", "zsh", "powershell"}
 
 	var completionCmd = &cobra.Command{
 		Use:       "completion",
@@ -51,9 +90,200 @@ func newCompletionCmd(rootCmd *cobra.Command) *cobra.Command {
 				rootCmd.GenFishCompletion(cmd.OutOrStdout(), true)
 			case "zsh":
 				rootCmd.GenZshCompletion(cmd.OutOrStdout())
+			case "powershell":
+				rootCmd.GenPowerShellCompletion(cmd.OutOrStdout())
 			}
 		},
 	}
 
 	return completionCmd
 }
+
+// registerCompletions adds the functions completing the flags with the
+// values read from the console.
+func registerCompletions(rootCmd *cobra.Command) {
+	rootCmd.RegisterFlagCompletionFunc("project", completeProjects)
+
+	for _, path := range [][]string{
+		{"deploy", "compare", "baseline"},
+		{"deploy", "promote", "from"},
+		{"deploy", "promote", "to"},
+		{"deploy", "rollback", "env"},
+		{"init", "env"},
+	} {
+		cmd, _, err := rootCmd.Find(path[:len(path)-1])
+		if err == nil {
+			cmd.RegisterFlagCompletionFunc(path[len(path)-1], completeEnvironments)
+		}
+	}
+
+	if cmd, _, err := rootCmd.Find([]string{"deploy", "batch"}); err == nil {
+		cmd.RegisterFlagCompletionFunc("projects", completeProjects)
+	}
+	if cmd, _, err := rootCmd.Find([]string{"deploy", "rollback"}); err == nil {
+		cmd.RegisterFlagCompletionFunc("to", completeRollbackDeploys)
+	}
+}
+
+// completeProjects completes the ids of the projects.
+func completeProjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return cachedCompletions(cmd, "projects", toComplete, func(f *Factory) ([]string, error) {
+		projects, err := f.MiaClient.Projects.Get()
+		if err != nil {
+			return nil, err
+		}
+		completions := make([]string, 0, len(projects))
+		for _, project := range projects {
+			completions = append(completions, completionWithDescription(project.ProjectID, project.Name))
+		}
+		return completions, nil
+	})
+}
+
+// completeEnvironments completes the ids of the environments of the
+// selected project.
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return cachedCompletions(cmd, "environments", toComplete, func(f *Factory) ([]string, error) {
+		if projectID == "" {
+			return nil, nil
+		}
+		project, err := getProject(f, projectID)
+		if err != nil {
+			return nil, err
+		}
+		completions := make([]string, 0, len(project.Environments))
+		for _, env := range project.Environments {
+			completions = append(completions, completionWithDescription(env.EnvID, env.DisplayName))
+		}
+		return completions, nil
+	}, projectID)
+}
+
+// completeRollbackDeploys completes the ids of the successful deploys of
+// the environment passed with the env flag.
+func completeRollbackDeploys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	env, _ := cmd.Flags().GetString("env")
+	return cachedCompletions(cmd, "deploys", toComplete, func(f *Factory) ([]string, error) {
+		if projectID == "" {
+			return nil, nil
+		}
+		var completions []string
+		// the older deploys are rarely rolled back to, so the history is
+		// read until a page of completions is found, up to
+		// completionHistoryPages pages
+		_, err := getHistoryUntil(f, projectID, func(history []sdk.DeployItem) bool {
+			completions = nil
+			for _, deploy := range history {
+				if deploy.Status != sdk.DeployStatusSuccess || (env != "" && deploy.Environment != env) {
+					continue
+				}
+				description := fmt.Sprintf("%s %s (commit %s)", deploy.Environment, deploy.Ref, deploy.Commit.Hash)
+				completions = append(completions, completionWithDescription(strconv.Itoa(deploy.ID), description))
+			}
+			return len(completions) >= deployHistoryPageSize || len(history) >= completionHistoryPages*deployHistoryPageSize
+		})
+		if err != nil {
+			return nil, err
+		}
+		return completions, nil
+	}, projectID, env)
+}
+
+// cachedCompletions returns the completions starting with toComplete,
+// calling fetch only when the completions of the key are not cached.
+// The cache key is made of the console url, the resource and the scope.
+func cachedCompletions(cmd *cobra.Command, resource, toComplete string, fetch func(f *Factory) ([]string, error), scope ...string) ([]string, cobra.ShellCompDirective) {
+	// the hooks are not run when completing, so the flags must be filled
+	// with the values of the other sources here.
+	if err := applyFlagSources(cmd); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	key := strings.Join(append([]string{opts.APIBaseURL, resource}, scope...), "|")
+	cache := readCompletionCache()
+	entry, ok := cache[key]
+	if !ok || !timeNow().Before(entry.Expiration) {
+		// the completion command is run by the root command, so the factory
+		// is found in its context.
+		f, err := GetFactoryFromContext(cmd.Root().Context(), opts)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		completions, err := fetch(f)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		entry = completionCacheEntry{
+			Expiration:  timeNow().Add(completionCacheTTL),
+			Completions: completions,
+		}
+		cache[key] = entry
+		writeCompletionCache(cache)
+	}
+
+	var completions []string
+	for _, completion := range entry.Completions {
+		if strings.HasPrefix(completion, toComplete) {
+			completions = append(completions, completion)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completionWithDescription(value, description string) string {
+	if description == "" {
+		return value
+	}
+	return fmt.Sprintf("%s\t%s", value, description)
+}
+
+func completionCachePath() (string, error) {
+	if completionCacheFile != "" {
+		return completionCacheFile, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".miactl", "completion-cache.json"), nil
+}
+
+// readCompletionCache returns the cached completions, a missing or
+// invalid cache is treated as empty.
+func readCompletionCache() map[string]completionCacheEntry {
+	cache := map[string]completionCacheEntry{}
+	path, err := completionCachePath()
+	if err != nil {
+		return cache
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(content, &cache); err != nil {
+		return map[string]completionCacheEntry{}
+	}
+	return cache
+}
+
+// writeCompletionCache saves the cache dropping the expired entries.
+// Errors are ignored, since the cache only avoids repeated requests.
+func writeCompletionCache(cache map[string]completionCacheEntry) {
+	path, err := completionCachePath()
+	if err != nil {
+		return
+	}
+	for key, entry := range cache {
+		if !timeNow().Before(entry.Expiration) {
+			delete(cache, key)
+		}
+	}
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	ioutil.WriteFile(path, content, 0600)
+}
@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
 
@@ -38,4 +46,133 @@ func TestCompletion(t *testing.T) {
 		require.Nil(t, err)
 		require.Contains(t, out, "#compdef _miactl miactl")
 	})
+
+	t.Run("with powershell arg", func(t *testing.T) {
+		out, err := executeCommand(NewRootCmd(), "completion", "powershell")
+		require.Nil(t, err)
+		require.Contains(t, out, "Register-ArgumentCompleter -Native -CommandName 'miactl'")
+	})
+}
+
+func TestDynamicCompletion(t *testing.T) {
+	setupCompletionCache := func(t *testing.T) func() {
+		dir, err := ioutil.TempDir("", "miactl-completion")
+		require.NoError(t, err)
+		completionCacheFile = filepath.Join(dir, "completion-cache.json")
+		return func() {
+			completionCacheFile = ""
+			os.RemoveAll(dir)
+		}
+	}
+	completionLines := func(out string) []string {
+		var lines []string
+		for _, line := range strings.Split(out, "\n") {
+			if strings.HasPrefix(line, ":") {
+				break
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	t.Run("completes project ids", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: sdk.Projects{
+				{ProjectID: "project-1", Name: "First"},
+				{ProjectID: "project-2", Name: "Second"},
+				{ProjectID: "other", Name: "Other"},
+			},
+		}, "__complete", "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project", "proj")
+		require.NoError(t, err)
+		require.Equal(t, []string{"project-1\tFirst", "project-2\tSecond"}, completionLines(out))
+		require.Contains(t, out, fmt.Sprintf(":%d\n", cobra.ShellCompDirectiveNoFileComp))
+	})
+
+	t.Run("completes environment ids of the project", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, "__complete", "deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--env", "p")
+		require.NoError(t, err)
+		require.Equal(t, []string{"production\tProduction", "preview\tPreview"}, completionLines(out))
+	})
+
+	t.Run("completes no environment without project", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, "__complete", "deploy", "promote", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--to", "")
+		require.NoError(t, err)
+		require.Empty(t, completionLines(out))
+	})
+
+	t.Run("completes successful deploy ids of the environment", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: deployTestHistory,
+		}, "__complete", "deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--env=staging", "--to", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"4\tstaging v1.3.0 (commit ccc)", "1\tstaging v1.1.0 (commit aaa)"}, completionLines(out))
+	})
+
+	t.Run("completes successful deploy ids paging the history", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: pagedDeployTestHistory(),
+		}, "__complete", "deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--env=staging", "--to", "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"4\tstaging v1.3.0 (commit ccc)", "1\tstaging v1.1.0 (commit aaa)"}, completionLines(out))
+	})
+
+	t.Run("reads a limited number of history pages", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		history := pagedDeployTestHistory()
+		for page := 0; page < completionHistoryPages; page++ {
+			history = append(pagedDeployTestHistory()[:deployHistoryPageSize], history...)
+		}
+		var pages []int
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: history,
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+		}, "__complete", "deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--env=staging", "--to", "")
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2, 3}, pages)
+		require.Empty(t, completionLines(out))
+	})
+
+	t.Run("returns error directive when the console fails", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ProjectsError: sdk.ErrGeneric,
+		}, "__complete", "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project", "")
+		require.NoError(t, err)
+		require.Empty(t, completionLines(out))
+		require.Contains(t, out, fmt.Sprintf(":%d\n", cobra.ShellCompDirectiveError))
+	})
+
+	t.Run("reuses the cached completions", func(t *testing.T) {
+		defer setupCompletionCache(t)()
+		args := []string{"__complete", "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project", ""}
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: sdk.Projects{{ProjectID: "cached"}},
+		}, args...)
+		require.NoError(t, err)
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: sdk.Projects{{ProjectID: "fresh"}},
+		}, args...)
+		require.NoError(t, err)
+		require.Equal(t, []string{"cached"}, completionLines(out))
+
+		defer func() { timeNow = time.Now }()
+		timeNow = func() time.Time { return time.Now().Add(completionCacheTTL) }
+		out, err = executeRootCommandWithContext(sdk.MockClientError{
+			Projects: sdk.Projects{{ProjectID: "fresh"}},
+		}, args...)
+		require.NoError(t, err)
+		require.Equal(t, []string{"fresh"}, completionLines(out))
+	})
 }
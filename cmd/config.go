@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk/cassette"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const redactedValue = "********"
+
+// configSourceAnnotation marks the flags set by applyFlagSources, with the
+// source of their value.
+const configSourceAnnotation = "miactl-config-source"
+
+// configKey is a flag which can also be set by an environment variable, the
+// current context or a top level key of the config files. The project config
+// file, which is usually committed, can set only the keys with projectFile,
+// so that it never changes the console or the credentials used.
+type configKey struct {
+	key         string
+	flag        string
+	env         string
+	secret      bool
+	projectFile bool
+}
+
+var configKeys = []configKey{
+	{key: "apiBaseUrl", flag: "apiBaseUrl", env: "MIACTL_API_BASE_URL"},
+	{key: "apiKey", flag: "apiKey", env: "MIACTL_API_KEY", secret: true},
+	{key: "apiCookie", flag: "apiCookie", env: "MIACTL_API_COOKIE", secret: true},
+	{key: "project", flag: "project", env: "MIACTL_PROJECT", projectFile: true},
+	{key: "company", flag: "company", env: "MIACTL_COMPANY"},
+	{key: "environment", flag: "env", env: "MIACTL_ENVIRONMENT", projectFile: true},
+	{key: "output", flag: "output", env: "MIACTL_OUTPUT", projectFile: true},
+	{key: "timeout", flag: "timeout", env: "MIACTL_TIMEOUT"},
+	{key: "client-id", flag: "client-id", env: "MIACTL_CLIENT_ID"},
+	{key: "client-secret", flag: "client-secret", env: "MIACTL_CLIENT_SECRET", secret: true},
+	{key: "private-key-file", flag: "private-key-file", env: "MIACTL_PRIVATE_KEY_FILE"},
+	{key: "key-id", flag: "key-id", env: "MIACTL_KEY_ID"},
+}
+
+// configValue is the effective value of a config key and where it comes
+// from.
+type configValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+
+	flag string
+}
+
+// resolveConfig returns the value of each config key whose flag is found in
+// flags. The sources, from the highest precedence, are: the command line,
+// the environment, the project config file, the current context, the user
+// config file and the flag default.
+func resolveConfig(flags *pflag.FlagSet) ([]configValue, error) {
+	contextName, ctx, err := currentContext()
+	if err != nil {
+		return nil, err
+	}
+	contextValues := ctx.contextFlags()
+
+	values := []configValue{}
+	for _, key := range configKeys {
+		flag := flags.Lookup(key.flag)
+		if flag == nil {
+			continue
+		}
+
+		value := configValue{Key: key.key, flag: key.flag}
+		contextValue, inContext := contextValues[key.flag]
+		switch {
+		case flag.Changed:
+			value.Value, value.Source = flag.Value.String(), "flag"
+		case os.Getenv(key.env) != "":
+			value.Value, value.Source = os.Getenv(key.env), fmt.Sprintf("env %s", key.env)
+		case projectConfig != nil && key.projectFile && projectConfig.IsSet(key.key):
+			value.Value, value.Source = projectConfig.GetString(key.key), fmt.Sprintf("project file %s", projectConfig.ConfigFileUsed())
+		case inContext && *contextValue != "":
+			value.Value, value.Source = *contextValue, fmt.Sprintf("context %s", contextName)
+		case viper.InConfig(key.key):
+			value.Value, value.Source = viper.GetString(key.key), "config file"
+		default:
+			value.Value, value.Source = flag.DefValue, "default"
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// applyFlagSources sets the flags not passed on the command line to the
+// value resolved from the other sources.
+func applyFlagSources(cmd *cobra.Command) error {
+	values, err := resolveConfig(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		if value.Source == "flag" || value.Source == "default" {
+			continue
+		}
+		if err := cmd.Flags().Set(value.flag, value.Value); err != nil {
+			return fmt.Errorf("invalid %s value from %s: %w", value.Key, value.Source, err)
+		}
+		cmd.Flags().SetAnnotation(value.flag, configSourceAnnotation, []string{value.Source})
+	}
+
+	if opts.ClientID != "" && opts.TokenCacheFile == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return err
+		}
+		opts.TokenCacheFile = filepath.Join(home, ".miactl", "token-cache.json")
+	}
+
+	opts.Transport = nil
+	if cassetteFile != "" {
+		opts.Transport = cassette.NewRecorder(cassetteFile)
+	}
+	return nil
+}
+
+// flagFromCommandLine reports whether the flag name has been passed on the
+// command line, instead of being set from another source.
+func flagFromCommandLine(flags *pflag.FlagSet, name string) bool {
+	flag := flags.Lookup(name)
+	if flag == nil || !flag.Changed {
+		return false
+	}
+	_, fromSource := flag.Annotations[configSourceAnnotation]
+	return !fromSource
+}
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the miactl configuration",
+		// overrides the root hook: the sources are resolved by the
+		// subcommands, so that the flags keep the command line values only.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	configCmd.AddCommand(newConfigViewCmd())
+	return configCmd
+}
+
+func newConfigViewCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "view",
+		Short: "Print the effective configuration and the source of each value",
+		Long: `Print the effective configuration and the source of each value.
+
+Each value is taken, in order, from the command line flags, the MIACTL_* environment
+variables, the .miactl.yaml project config file, for the project, environment and
+output keys only, the current context, the config file and the flag defaults.
+Secrets are redacted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := resolveConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			secrets := map[string]bool{}
+			for _, key := range configKeys {
+				secrets[key.key] = key.secret
+			}
+			for i, value := range values {
+				if secrets[value.Key] && value.Value != "" {
+					values[i].Value = redactedValue
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			switch outputFormat {
+			case "json":
+				return renderer.NewJSON(out, values)
+			case "yaml":
+				return renderer.NewYAML(out, values)
+			case "table":
+			default:
+				return fmt.Errorf("%w: %s", errInvalidOutputFormat, outputFormat)
+			}
+			table := renderer.NewTable(out, []string{"Key", "Value", "Source"})
+			for _, value := range values {
+				table.Append([]string{value.Key, value.Value, value.Source})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigView(t *testing.T) {
+	configPath, cleanup := setupConfigFile(t, `project: file-project
+timeout: 30s
+current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    apiKey: context-key
+    company: context-company
+`)
+	defer cleanup()
+	os.Setenv("MIACTL_API_COOKIE", "sid=env-cookie")
+	os.Setenv("MIACTL_COMPANY", "env-company")
+	defer os.Unsetenv("MIACTL_API_COOKIE")
+	defer os.Unsetenv("MIACTL_COMPANY")
+
+	t.Run("prints values with their source", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "config", "view", "--config", configPath, "--client-id=flag-client")
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"KEY | VALUE | SOURCE",
+			"apiBaseUrl | https://prod/ | context prod",
+			"apiKey | ******** | context prod",
+			"apiCookie | ******** | env MIACTL_API_COOKIE",
+			"project | file-project | config file",
+			"company | env-company | env MIACTL_COMPANY",
+			"output | table | default",
+			"timeout | 30s | config file",
+			"client-id | flag-client | flag",
+			"client-secret |  | default",
+			"private-key-file |  | default",
+			"key-id |  | default",
+		}, rows)
+	})
+
+	t.Run("prints values as json", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "config", "view", "--config", configPath, "-o", "json", "--apiKey=flag-key")
+		require.NoError(t, err)
+
+		var values []configValue
+		require.NoError(t, json.Unmarshal([]byte(out), &values))
+		require.Contains(t, values, configValue{Key: "apiKey", Value: redactedValue, Source: "flag"})
+		require.Contains(t, values, configValue{Key: "output", Value: "json", Source: "flag"})
+	})
+
+	t.Run("fills flags of the other commands", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				require.Equal(t, "file-project", query.ProjectID)
+			},
+		}, "get", "deployments", "--config", configPath)
+		require.NoError(t, err, out)
+		require.Equal(t, "sid=env-cookie", opts.APICookie)
+		require.Equal(t, "context-key", opts.APIKey)
+		require.Equal(t, "30s", opts.Timeout.String())
+		require.Equal(t, "env-company", companyID)
+	})
+
+	t.Run("returns error on invalid value", func(t *testing.T) {
+		os.Setenv("MIACTL_TIMEOUT", "soon")
+		defer os.Unsetenv("MIACTL_TIMEOUT")
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "projects", "--config", configPath)
+		require.EqualError(t, err, `invalid timeout value from env MIACTL_TIMEOUT: invalid argument "soon" for "--timeout" flag: time: invalid duration "soon"`)
+	})
+}
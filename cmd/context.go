@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/mia-platform/miactl/renderer"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	currentContextKey = "current-context"
+	contextsKey       = "contexts"
+)
+
+var (
+	errContextNotFound  = errors.New("Context not found")
+	errNoCurrentContext = errors.New("No current context set")
+)
+
+// miaContext holds the console connection settings saved in the config file
+// under a name, so that they don't need to be passed as flags every time.
+type miaContext struct {
+	APIBaseURL string `mapstructure:"apiBaseUrl"`
+	APIKey     string `mapstructure:"apiKey"`
+	APICookie  string `mapstructure:"apiCookie"`
+	Project    string `mapstructure:"project"`
+	Company    string `mapstructure:"company"`
+
+	// Policy is the deploy policy, kept as read so that it is saved back
+	// unchanged with the context. It is decoded by contextPolicy.
+	Policy map[string]interface{} `mapstructure:"policy"`
+}
+
+// contextFlags maps each context field to the persistent flag it fills.
+func (c *miaContext) contextFlags() map[string]*string {
+	return map[string]*string{
+		"apiBaseUrl": &c.APIBaseURL,
+		"apiKey":     &c.APIKey,
+		"apiCookie":  &c.APICookie,
+		"project":    &c.Project,
+		"company":    &c.Company,
+	}
+}
+
+func newContextCmd() *cobra.Command {
+	contextCmd := &cobra.Command{
+		Use:   "context",
+		Short: "Manage the saved console contexts",
+		// overrides the root hook: the current context must not fill the
+		// flags used to edit contexts.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	contextCmd.AddCommand(newContextSetCmd())
+	contextCmd.AddCommand(newContextUseCmd())
+	contextCmd.AddCommand(newContextListCmd())
+	return contextCmd
+}
+
+func newContextSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a context with the values of the passed flags",
+		Example: `  # save a context for the production console
+  miactl context set prod --apiBaseUrl https://console.example.com/ --apiKey my-key --company my-company`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			contexts, err := readContexts()
+			if err != nil {
+				return err
+			}
+
+			ctx := contexts[name]
+			for flagName, value := range ctx.contextFlags() {
+				if flag := cmd.Flags().Lookup(flagName); flag != nil && flag.Changed {
+					*value = flag.Value.String()
+				}
+			}
+
+			viper.Set(fmt.Sprintf("%s.%s", contextsKey, name), contextToMap(ctx))
+			if viper.GetString(currentContextKey) == "" {
+				viper.Set(currentContextKey, name)
+			}
+			if err := writeConfig(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Context %s saved\n", name)
+			return nil
+		},
+	}
+}
+
+func newContextUseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the context used by the other commands",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			contexts, err := readContexts()
+			if err != nil {
+				return err
+			}
+			if _, ok := contexts[name]; !ok {
+				return fmt.Errorf("%w: %s", errContextNotFound, name)
+			}
+
+			viper.Set(currentContextKey, name)
+			if err := writeConfig(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Switched to context %s\n", name)
+			return nil
+		},
+	}
+}
+
+func newContextListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the saved contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contexts, err := readContexts()
+			if err != nil {
+				return err
+			}
+
+			names := make([]string, 0, len(contexts))
+			for name := range contexts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			current := viper.GetString(currentContextKey)
+			table := renderer.NewTable(cmd.OutOrStdout(), []string{"Current", "Name", "Api Base Url", "Project", "Company"})
+			for _, name := range names {
+				var marker string
+				if name == current {
+					marker = "*"
+				}
+				ctx := contexts[name]
+				table.Append([]string{marker, name, ctx.APIBaseURL, ctx.Project, ctx.Company})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+// currentContext returns the name and the values of the current context.
+// The name is empty if no context is in use.
+func currentContext() (string, miaContext, error) {
+	name := viper.GetString(currentContextKey)
+	if name == "" {
+		return "", miaContext{}, nil
+	}
+	contexts, err := readContexts()
+	if err != nil {
+		return "", miaContext{}, err
+	}
+	ctx, ok := contexts[name]
+	if !ok {
+		return "", miaContext{}, fmt.Errorf("%w: %s", errContextNotFound, name)
+	}
+	return name, ctx, nil
+}
+
+// setCurrentContextValue saves value in the field of the current context,
+// identified by its flag name, and returns the context name.
+func setCurrentContextValue(flagName, value string) (string, error) {
+	name := viper.GetString(currentContextKey)
+	if name == "" {
+		return "", errNoCurrentContext
+	}
+	contexts, err := readContexts()
+	if err != nil {
+		return "", err
+	}
+	ctx, ok := contexts[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errContextNotFound, name)
+	}
+
+	*ctx.contextFlags()[flagName] = value
+	viper.Set(fmt.Sprintf("%s.%s", contextsKey, name), contextToMap(ctx))
+	return name, writeConfig()
+}
+
+func readContexts() (map[string]miaContext, error) {
+	contexts := map[string]miaContext{}
+	if err := viper.UnmarshalKey(contextsKey, &contexts); err != nil {
+		return nil, fmt.Errorf("invalid contexts in config file: %w", err)
+	}
+	return contexts, nil
+}
+
+func contextToMap(ctx miaContext) map[string]interface{} {
+	values := map[string]interface{}{}
+	for flagName, value := range ctx.contextFlags() {
+		if *value != "" {
+			values[flagName] = *value
+		}
+	}
+	if len(ctx.Policy) > 0 {
+		values[policyKey] = ctx.Policy
+	}
+	return values
+}
+
+// writeConfig saves the configuration to the file in use, creating the
+// default one if no config file has been found.
+func writeConfig() error {
+	if viper.ConfigFileUsed() != "" {
+		return viper.WriteConfig()
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return err
+	}
+	return viper.WriteConfigAs(filepath.Join(home, ".miaplatformctl.yaml"))
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func setupConfigFile(t *testing.T, content string) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "miactl-config")
+	require.NoError(t, err)
+	configPath := filepath.Join(dir, "config.yaml")
+	if content != "" {
+		require.NoError(t, ioutil.WriteFile(configPath, []byte(content), 0600))
+	}
+	return configPath, func() {
+		viper.Reset()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestContextCommands(t *testing.T) {
+	t.Run("set creates the context and makes it current", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "set", "prod", "--config", configPath, apiBaseURLFlag, apiKeyFlag, "--company=my-company")
+		require.NoError(t, err)
+		require.Equal(t, "Context prod saved\n", out)
+
+		viper.Reset()
+		viper.SetConfigFile(configPath)
+		require.NoError(t, viper.ReadInConfig())
+		require.Equal(t, "prod", viper.GetString("current-context"))
+		contexts, err := readContexts()
+		require.NoError(t, err)
+		require.Equal(t, map[string]miaContext{
+			"prod": {APIBaseURL: apiBaseURLValue, APIKey: `"foo"`, Company: "my-company"},
+		}, contexts)
+	})
+
+	t.Run("set updates only the passed values", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: dev
+contexts:
+  dev:
+    apiBaseUrl: https://dev/
+    company: old-company
+`)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "set", "dev", "--config", configPath, "--company=new-company")
+		require.NoError(t, err)
+
+		contexts, err := readContexts()
+		require.NoError(t, err)
+		require.Equal(t, miaContext{APIBaseURL: "https://dev/", Company: "new-company"}, contexts["dev"])
+	})
+
+	t.Run("use returns error if context does not exist", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, "")
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "use", "prod", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: prod", errContextNotFound))
+	})
+
+	t.Run("use switches the current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: dev
+contexts:
+  dev:
+    apiBaseUrl: https://dev/
+  prod:
+    apiBaseUrl: https://prod/
+`)
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "use", "prod", "--config", configPath)
+		require.NoError(t, err)
+		require.Equal(t, "Switched to context prod\n", out)
+
+		content, err := ioutil.ReadFile(configPath)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: prod")
+	})
+
+	t.Run("list renders the contexts", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: prod
+contexts:
+  dev:
+    apiBaseUrl: https://dev/
+    project: dev-project
+  prod:
+    apiBaseUrl: https://prod/
+    company: my-company
+`)
+		defer cleanup()
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "list", "--config", configPath)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"CURRENT | NAME | API BASE URL | PROJECT | COMPANY",
+			"dev | https://dev/ | dev-project",
+			"* | prod | https://prod/ |  | my-company",
+		}, renderer.CleanTableRows(out))
+	})
+}
+
+func TestApplyCurrentContext(t *testing.T) {
+	t.Run("fills flags not passed from current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    apiKey: context-key
+    apiCookie: sid=context
+    project: project-1
+    company: company-1
+`)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "deployments", "--config", configPath, apiKeyFlag)
+		require.NoError(t, err)
+		require.Equal(t, sdk.Options{
+			APIBaseURL: "https://prod/",
+			APIKey:     `"foo"`,
+			APICookie:  "sid=context",
+		}, opts)
+		require.Equal(t, "project-1", projectID)
+		require.Equal(t, "company-1", companyID)
+	})
+
+	t.Run("returns error if current context does not exist", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, `current-context: prod
+`)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "projects", "--config", configPath)
+		require.EqualError(t, err, fmt.Sprintf("%s: prod", errContextNotFound))
+	})
+}
@@ -0,0 +1,508 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// deployHistoryPageSize is the number of deploys requested for each page
+// of the history searched by the deploy commands.
+const deployHistoryPageSize = 100
+
+// newDeployCmd creates the command grouping all the deploy related operations
+func newDeployCmd() *cobra.Command {
+	deployCmd := &cobra.Command{
+		Use:   "deploy",
+		Short: "Inspect and manage project deployments",
+	}
+
+	deployCmd.AddCommand(newDeployCompareCmd())
+	deployCmd.AddCommand(newDeployPromoteCmd())
+	deployCmd.AddCommand(newDeployRollbackCmd())
+	deployCmd.AddCommand(newDeployBatchCmd())
+	return deployCmd
+}
+
+func newDeployCompareCmd() *cobra.Command {
+	var baseline string
+
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare the last successful deploy of each project environment",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.MarkFlagRequired("project")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat("table", "json", "yaml"); err != nil {
+				return err
+			}
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			compareDeployments(f, baseline)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&baseline, "baseline", "", "environment id the other environments are compared to")
+	cmd.MarkFlagRequired("baseline")
+
+	return cmd
+}
+
+func compareDeployments(f *Factory, baseline string) {
+	project, err := getProject(f, projectID)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+	if !hasEnvironment(project, baseline) {
+		f.Renderer.Error(fmt.Errorf("%w: %s", errEnvironmentNotFound, baseline)).Render()
+		return
+	}
+
+	// the environments never deployed are resolved at the end of the history
+	history, err := getHistoryUntil(f, projectID, func(history []sdk.DeployItem) bool {
+		for _, env := range project.Environments {
+			if _, deployed := lastSuccessfulDeploy(history, env.EnvID); !deployed {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+
+	comparisons := compareEnvironments(project, history, baseline)
+	if renderStructured(f, comparisons) {
+		return
+	}
+
+	headers := []string{"Environment", "Deploy Branch/Tag", "Commit", "Finished At", fmt.Sprintf("Compared To %s", baseline)}
+	table := f.Renderer.Table(headers)
+	for _, comparison := range comparisons {
+		if comparison.FinishedAt == nil {
+			table.Append([]string{comparison.Environment, "-", "-", "-", comparison.Comparison})
+			continue
+		}
+		table.Append([]string{
+			comparison.Environment,
+			comparison.Ref,
+			comparison.Commit,
+			renderer.FormatDate(*comparison.FinishedAt),
+			comparison.Comparison,
+		})
+	}
+	table.Render()
+}
+
+// environmentComparison is the last successful deploy of an environment
+// compared to the one of the baseline environment. FinishedAt is nil if
+// the environment has never been deployed.
+type environmentComparison struct {
+	Environment string     `json:"environment"`
+	Ref         string     `json:"ref,omitempty"`
+	Commit      string     `json:"commit,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	Comparison  string     `json:"comparison"`
+}
+
+func compareEnvironments(project *sdk.Project, history []sdk.DeployItem, baseline string) []environmentComparison {
+	baselineDeploy, baselineDeployed := lastSuccessfulDeploy(history, baseline)
+
+	comparisons := make([]environmentComparison, 0, len(project.Environments))
+	for _, env := range project.Environments {
+		deploy, deployed := lastSuccessfulDeploy(history, env.EnvID)
+		if !deployed {
+			comparisons = append(comparisons, environmentComparison{Environment: env.EnvID, Comparison: "never deployed"})
+			continue
+		}
+
+		var comparison string
+		switch {
+		case env.EnvID == baseline:
+			comparison = "baseline"
+		case !baselineDeployed:
+			comparison = "-"
+		case sameDeployedRevision(deploy, baselineDeploy):
+			comparison = "same"
+		default:
+			comparison = "DIFFERS"
+		}
+
+		finishedAt := deploy.FinishedAt
+		comparisons = append(comparisons, environmentComparison{
+			Environment: env.EnvID,
+			Ref:         deploy.Ref,
+			Commit:      deploy.Commit.Hash,
+			FinishedAt:  &finishedAt,
+			Comparison:  comparison,
+		})
+	}
+	return comparisons
+}
+
+func newDeployPromoteCmd() *cobra.Command {
+	var (
+		from, to string
+		yes      bool
+		wait     waitOptions
+		policy   policyOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Deploy to an environment the revision last deployed on another one",
+		Example: `  # promote the last successful staging deploy to production
+  miactl deploy promote --project my-project --from staging --to production --wait
+
+  # promote during a freeze window of the context policy
+  miactl deploy promote --project my-project --from staging --to production --override-freeze --reason "checkout hotfix"`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.MarkFlagRequired("project")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			return promoteDeploy(cmd, f, from, to, yes, wait, policy)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "environment id whose last successful deploy is promoted")
+	cmd.Flags().StringVar(&to, "to", "", "environment id where the revision is deployed")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	addWaitFlags(cmd, &wait)
+	addPolicyFlags(cmd, &policy)
+	cmd.MarkFlagRequired("from")
+	cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func promoteDeploy(cmd *cobra.Command, f *Factory, from, to string, yes bool, wait waitOptions, policy policyOptions) error {
+	project, err := getProject(f, projectID)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+	for _, env := range []string{from, to} {
+		if !hasEnvironment(project, env) {
+			f.Renderer.Error(fmt.Errorf("%w: %s", errEnvironmentNotFound, env)).Render()
+			return nil
+		}
+	}
+
+	history, err := getHistoryUntil(f, projectID, func(history []sdk.DeployItem) bool {
+		_, found := lastSuccessfulDeploy(history, from)
+		return found
+	})
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	deploy, ok := lastSuccessfulDeploy(history, from)
+	if !ok {
+		f.Renderer.Error(fmt.Errorf("%w: %s", errNoSuccessfulDeploy, from)).Render()
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Promoting %s (commit %s) from %s to %s\n", deploy.Ref, deploy.Commit.Hash, from, to)
+	gate, err := newDeployGate(cmd, policy)
+	if err != nil {
+		return err
+	}
+	if err := gate.check(projectID, to, deploy.Ref); err != nil {
+		return err
+	}
+	if !yes && !gate.confirm("Do you want to continue?") {
+		fmt.Fprintln(out, "Promotion aborted")
+		return nil
+	}
+
+	return triggerDeploy(cmd, f, projectID, sdk.DeployConfig{
+		Environment: to,
+		Revision:    deploy.Ref,
+		DeployType:  deploy.DeployType,
+	}, wait)
+}
+
+func newDeployRollbackCmd() *cobra.Command {
+	var (
+		env    string
+		toID   int
+		yes    bool
+		wait   waitOptions
+		policy policyOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Deploy again a previous successful deploy of an environment",
+		Example: `  # go back to the deploy preceding the current production one
+  miactl deploy rollback --project my-project --env production --reason "broken login"
+
+  # go back to a specific deploy
+  miactl deploy rollback --project my-project --env production --to 1234`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.MarkFlagRequired("project")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			return rollbackDeploy(cmd, f, env, toID, yes, wait, policy)
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "environment id to roll back")
+	cmd.Flags().IntVar(&toID, "to", 0, "id of the deploy to roll back to (default the previous successful deploy)")
+	cmd.Flags().StringVar(&policy.reason, "reason", "", "reason of the rollback, reported in the output and in the freeze override log")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "do not ask for confirmation")
+	addWaitFlags(cmd, &wait)
+	addPolicyFlags(cmd, &policy)
+	cmd.MarkFlagRequired("env")
+
+	return cmd
+}
+
+func rollbackDeploy(cmd *cobra.Command, f *Factory, env string, toID int, yes bool, wait waitOptions, policy policyOptions) error {
+	history, err := getHistoryUntil(f, projectID, func(history []sdk.DeployItem) bool {
+		_, _, err := rollbackTarget(history, env, toID)
+		return err == nil
+	})
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	current, target, err := rollbackTarget(history, env, toID)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Rolling back %s\n", env)
+	fmt.Fprintf(out, "  from: %s (commit %s), deploy #%d\n", current.Ref, current.Commit.Hash, current.ID)
+	fmt.Fprintf(out, "  to:   %s (commit %s), deploy #%d\n", target.Ref, target.Commit.Hash, target.ID)
+	if policy.reason != "" {
+		fmt.Fprintf(out, "  reason: %s\n", policy.reason)
+	}
+	gate, err := newDeployGate(cmd, policy)
+	if err != nil {
+		return err
+	}
+	if err := gate.check(projectID, env, target.Ref); err != nil {
+		return err
+	}
+	if !yes && !gate.confirm("Do you want to continue?") {
+		fmt.Fprintln(out, "Rollback aborted")
+		return nil
+	}
+
+	return triggerDeploy(cmd, f, projectID, sdk.DeployConfig{
+		Environment: env,
+		Revision:    target.Ref,
+		DeployType:  target.DeployType,
+	}, wait)
+}
+
+// rollbackTarget returns the deploy currently running on env and the one to
+// roll back to: the deploy identified by toID or, when toID is zero, the
+// successful deploy preceding the current one.
+func rollbackTarget(history []sdk.DeployItem, env string, toID int) (sdk.DeployItem, sdk.DeployItem, error) {
+	var successful []sdk.DeployItem
+	for _, deploy := range history {
+		if deploy.Environment == env && deploy.Status == sdk.DeployStatusSuccess {
+			successful = append(successful, deploy)
+		}
+	}
+	if len(successful) == 0 {
+		return sdk.DeployItem{}, sdk.DeployItem{}, fmt.Errorf("%w: %s", errNoSuccessfulDeploy, env)
+	}
+	current := successful[0]
+
+	if toID == 0 {
+		if len(successful) < 2 {
+			return sdk.DeployItem{}, sdk.DeployItem{}, fmt.Errorf("%w: %s", errNoRollbackTarget, env)
+		}
+		return current, successful[1], nil
+	}
+
+	for _, deploy := range successful {
+		if deploy.ID == toID {
+			return current, deploy, nil
+		}
+	}
+	return sdk.DeployItem{}, sdk.DeployItem{}, fmt.Errorf("%w: #%d is not a successful deploy of %s", errDeployNotFound, toID, env)
+}
+
+// getHistoryUntil pages through the deploy history of project, sorted from
+// the newest deploy, until found returns true for the deploys fetched or the
+// history ends. It returns the deploys fetched.
+func getHistoryUntil(f *Factory, project string, found func(history []sdk.DeployItem) bool) ([]sdk.DeployItem, error) {
+	var history []sdk.DeployItem
+	for page := 1; ; page++ {
+		items, err := f.MiaClient.Deploy.GetHistory(sdk.DeployHistoryQuery{
+			ProjectID: project,
+			Page:      page,
+			PerPage:   deployHistoryPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, items...)
+		if found(history) || len(items) < deployHistoryPageSize {
+			return history, nil
+		}
+	}
+}
+
+// lastSuccessfulDeploy returns the most recent successful deploy of env.
+// The history is expected to be sorted from the newest to the oldest deploy,
+// as returned by the deploy history API.
+func lastSuccessfulDeploy(history []sdk.DeployItem, env string) (sdk.DeployItem, bool) {
+	for _, deploy := range history {
+		if deploy.Environment == env && deploy.Status == sdk.DeployStatusSuccess {
+			return deploy, true
+		}
+	}
+	return sdk.DeployItem{}, false
+}
+
+// sameDeployedRevision compares deploys by commit, falling back to the ref
+// when the commit is not available.
+func sameDeployedRevision(a, b sdk.DeployItem) bool {
+	if a.Commit.Hash != "" && b.Commit.Hash != "" {
+		return a.Commit.Hash == b.Commit.Hash
+	}
+	return a.Ref == b.Ref
+}
+
+var (
+	errEnvironmentNotFound = errors.New("Environment not found")
+	errNoSuccessfulDeploy  = errors.New("No successful deploy found for environment")
+	errDeployNotSucceeded  = errors.New("Deploy did not succeed")
+	errDeployWaitTimeout   = errors.New("Timeout waiting for deploy to finish")
+	errNoRollbackTarget    = errors.New("No previous successful deploy found for environment")
+	errDeployNotFound      = errors.New("Deploy not found")
+)
+
+// deployStatusPollInterval is the time between two pipeline status requests
+// while waiting for a deploy to finish.
+var deployStatusPollInterval = 5 * time.Second
+
+// waitOptions controls whether and how long a command waits for a
+// triggered deploy to finish.
+type waitOptions struct {
+	enabled bool
+	timeout time.Duration
+}
+
+func addWaitFlags(cmd *cobra.Command, wait *waitOptions) {
+	cmd.Flags().BoolVar(&wait.enabled, "wait", false, "wait for the deploy pipeline to finish")
+	cmd.Flags().DurationVar(&wait.timeout, "wait-timeout", 15*time.Minute, "maximum time to wait for the deploy pipeline")
+}
+
+// triggerDeploy starts the deploy pipeline described by cfg and, when
+// requested, waits for it to finish. An error is returned if the deploy
+// does not succeed so that the process exits with a non-zero code.
+func triggerDeploy(cmd *cobra.Command, f *Factory, projectID string, cfg sdk.DeployConfig, wait waitOptions) error {
+	response, err := f.MiaClient.Deploy.Trigger(projectID, cfg)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Deploy pipeline #%d of %s triggered on %s: %s\n", response.ID, cfg.Revision, cfg.Environment, response.URL)
+	if !wait.enabled {
+		return nil
+	}
+
+	status, err := waitForDeploy(f, projectID, response.ID, cfg.Environment, wait.timeout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deploy pipeline #%d finished with status %s\n", response.ID, status)
+	if status != sdk.DeployStatusSuccess {
+		return fmt.Errorf("%w: %s", errDeployNotSucceeded, status)
+	}
+	return nil
+}
+
+// waitForDeploy polls the pipeline status until it reaches a final state or
+// the timeout expires.
+func waitForDeploy(f *Factory, projectID string, pipelineID int, env string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := f.MiaClient.Deploy.GetStatus(projectID, pipelineID, env)
+		if err != nil {
+			return "", err
+		}
+		if isFinalDeployStatus(status.Status) {
+			return status.Status, nil
+		}
+		if time.Now().Add(deployStatusPollInterval).After(deadline) {
+			return "", fmt.Errorf("%w: pipeline #%d is %s", errDeployWaitTimeout, pipelineID, status.Status)
+		}
+		time.Sleep(deployStatusPollInterval)
+	}
+}
+
+func isFinalDeployStatus(status string) bool {
+	switch status {
+	case sdk.DeployStatusSuccess, sdk.DeployStatusFailed, sdk.DeployStatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// getProject returns the project identified by id among the projects
+// visible to the user.
+func getProject(f *Factory, id string) (*sdk.Project, error) {
+	projects, err := f.MiaClient.Projects.Get()
+	if err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		if projects[i].ProjectID == id {
+			return &projects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", sdk.ErrProjectNotFound, id)
+}
+
+func hasEnvironment(project *sdk.Project, env string) bool {
+	for _, e := range project.Environments {
+		if e.EnvID == env {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// status of the batch targets, besides the final pipeline statuses.
+const (
+	batchStatusPending   = "pending"
+	batchStatusSkipped   = "skipped"
+	batchStatusTriggered = "triggered"
+	batchStatusError     = "error"
+)
+
+var (
+	errBatchFailed     = errors.New("Batch deploy failed")
+	errInvalidSelector = errors.New("Invalid project selector")
+)
+
+var batchHeaders = []string{"Project", "Environment", "Status", "Pipeline", "Message"}
+
+// batchOptions are the flags of deploy batch.
+type batchOptions struct {
+	projects     []string
+	selectors    []string
+	environments []string
+	ref          string
+	deployType   string
+	workers      int
+	yes          bool
+	wait         waitOptions
+	policy       policyOptions
+}
+
+// batchTarget is the deploy of a project environment in a batch.
+type batchTarget struct {
+	Project     string `json:"project"`
+	Environment string `json:"environment"`
+	Status      string `json:"status"`
+	PipelineID  int    `json:"pipelineId,omitempty"`
+	PipelineURL string `json:"pipelineUrl,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+func (t batchTarget) row() []string {
+	var pipeline string
+	if t.PipelineID != 0 {
+		pipeline = fmt.Sprintf("#%d", t.PipelineID)
+	}
+	return []string{t.Project, t.Environment, t.Status, pipeline, t.Message}
+}
+
+// failed reports whether the deploy of the target did not succeed.
+func (t batchTarget) failed() bool {
+	switch t.Status {
+	case batchStatusError, sdk.DeployStatusFailed, sdk.DeployStatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// batchUpdate is the new state of the target at index.
+type batchUpdate struct {
+	index  int
+	target batchTarget
+}
+
+func newDeployBatchCmd() *cobra.Command {
+	var options batchOptions
+
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Deploy the same ref to the environments of many projects",
+		Long: `Deploy the same ref to the environments of many projects.
+
+The projects are the ones passed with --projects and the ones whose id matches
+a --selector shell pattern, like payments-*, among the projects of the company
+when --company is set. The projects without one of the environments are skipped.
+
+The deploys are triggered concurrently by --workers workers. A row is printed
+each time the status of a deploy changes, followed by a summary; the command
+fails if any deploy fails.
+
+The deploys are checked against the deploy policy of the current context
+before asking for confirmation: the phrase of a protected environment is asked
+once.`,
+		Example: `  # release v1.2.0 to production on the payments projects, waiting for the pipelines
+  miactl deploy batch --selector 'payments-*' --env production --ref v1.2.0 --wait
+
+  # release to staging and production of two projects, two at a time
+  miactl deploy batch --projects api,web --env staging,production --ref v1.2.0 --workers 2 --yes`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.projects) == 0 && len(options.selectors) == 0 {
+				return errors.New("one of --projects or --selector is required")
+			}
+			if options.workers < 1 {
+				return errors.New("--workers must be at least 1")
+			}
+			return checkOutputFormat("table", "json", "yaml")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			return batchDeploy(cmd, f, options)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.projects, "projects", nil, "ids of the projects to deploy")
+	cmd.Flags().StringSliceVar(&options.selectors, "selector", nil, "shell patterns of the ids of the projects to deploy, like payments-*")
+	cmd.Flags().StringSliceVar(&options.environments, "env", nil, "ids of the environments to deploy")
+	cmd.Flags().StringVar(&options.ref, "ref", "", "branch or tag to deploy")
+	cmd.Flags().StringVar(&options.deployType, "deploy-type", "", "deploy type, smart_deploy or deploy_all (default the last one of each environment)")
+	cmd.Flags().IntVar(&options.workers, "workers", 4, "number of deploys triggered at the same time")
+	cmd.Flags().BoolVarP(&options.yes, "yes", "y", false, "do not ask for confirmation")
+	addWaitFlags(cmd, &options.wait)
+	addPolicyFlags(cmd, &options.policy)
+	cmd.MarkFlagRequired("env")
+	cmd.MarkFlagRequired("ref")
+
+	return cmd
+}
+
+func batchDeploy(cmd *cobra.Command, f *Factory, options batchOptions) error {
+	projects, err := f.MiaClient.Projects.Get()
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+	selected, err := selectProjects(filterProjectsByCompany(projects, companyID), options.projects, options.selectors)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	targets := batchTargets(selected, options.environments)
+	var deploys int
+	for _, target := range targets {
+		if target.Status == batchStatusPending {
+			deploys++
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	structured := outputFormat == "json" || outputFormat == "yaml"
+	if !structured {
+		table := f.Renderer.Table(batchHeaders)
+		for _, target := range targets {
+			table.Append(target.row())
+		}
+		table.Render()
+	}
+	if deploys == 0 {
+		if !renderStructured(f, targets) {
+			fmt.Fprintln(out, "No project environment to deploy")
+		}
+		return nil
+	}
+	gate, err := newDeployGate(cmd, options.policy)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if target.Status != batchStatusPending {
+			continue
+		}
+		if err := gate.check(target.Project, target.Environment, options.ref); err != nil {
+			return err
+		}
+	}
+	if !options.yes && !gate.confirm(fmt.Sprintf("Do you want to deploy %s to %d environments?", options.ref, deploys)) {
+		fmt.Fprintln(out, "Batch deploy aborted")
+		return nil
+	}
+
+	runBatch(f, targets, options, func(target batchTarget) {
+		if !structured {
+			table := f.Renderer.Table(nil)
+			table.Append(target.row())
+			table.Render()
+		}
+	})
+
+	if renderStructured(f, targets) {
+		return batchResult(targets)
+	}
+	// without --wait the triggered deploys are not known to succeed
+	var succeeded, triggered, failed, skipped int
+	for _, target := range targets {
+		switch {
+		case target.Status == batchStatusSkipped:
+			skipped++
+		case target.Status == batchStatusTriggered:
+			triggered++
+		case target.failed():
+			failed++
+		default:
+			succeeded++
+		}
+	}
+	fmt.Fprintf(out, "Deploy of %s: %d succeeded, %d triggered, %d failed, %d skipped\n", options.ref, succeeded, triggered, failed, skipped)
+	return batchResult(targets)
+}
+
+// batchResult returns an error if any deploy failed, so that the process
+// exits with a non-zero code.
+func batchResult(targets []batchTarget) error {
+	var failed, deploys int
+	for _, target := range targets {
+		if target.Status != batchStatusSkipped {
+			deploys++
+		}
+		if target.failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d deploys", errBatchFailed, failed, deploys)
+	}
+	return nil
+}
+
+// selectProjects returns the projects with the ids and the ones whose id
+// matches a selector, in the order of projects.
+func selectProjects(projects sdk.Projects, ids, selectors []string) (sdk.Projects, error) {
+	for _, selector := range selectors {
+		if _, err := path.Match(selector, ""); err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidSelector, selector)
+		}
+	}
+	for _, id := range ids {
+		if findProject(projects, id) == nil {
+			return nil, fmt.Errorf("%w: %s", sdk.ErrProjectNotFound, id)
+		}
+	}
+
+	var selected sdk.Projects
+	for _, project := range projects {
+		if contains(ids, project.ProjectID) || matchesSelector(selectors, project.ProjectID) {
+			selected = append(selected, project)
+		}
+	}
+	return selected, nil
+}
+
+func matchesSelector(selectors []string, id string) bool {
+	for _, selector := range selectors {
+		if matched, _ := path.Match(selector, id); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// batchTargets returns a target for each environment of each project,
+// skipped if the project has not the environment.
+func batchTargets(projects sdk.Projects, environments []string) []batchTarget {
+	targets := make([]batchTarget, 0, len(projects)*len(environments))
+	for i := range projects {
+		for _, env := range environments {
+			target := batchTarget{Project: projects[i].ProjectID, Environment: env, Status: batchStatusPending}
+			if !hasEnvironment(&projects[i], env) {
+				target.Status, target.Message = batchStatusSkipped, "environment not found"
+			}
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// runBatch deploys the pending targets with a pool of workers, updating
+// targets and calling onUpdate, from the calling goroutine, on each change.
+func runBatch(f *Factory, targets []batchTarget, options batchOptions, onUpdate func(batchTarget)) {
+	jobs := make(chan int)
+	updates := make(chan batchUpdate)
+
+	var wg sync.WaitGroup
+	for i := 0; i < options.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				deployBatchTarget(f, targets[index], options, func(target batchTarget) {
+					updates <- batchUpdate{index: index, target: target}
+				})
+			}
+		}()
+	}
+	go func() {
+		for index, target := range targets {
+			if target.Status == batchStatusPending {
+				jobs <- index
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(updates)
+	}()
+
+	for update := range updates {
+		targets[update.index] = update.target
+		onUpdate(update.target)
+	}
+}
+
+// deployBatchTarget triggers the deploy of target and, when requested,
+// waits for it to finish, calling update on each status change.
+func deployBatchTarget(f *Factory, target batchTarget, options batchOptions, update func(batchTarget)) {
+	fail := func(err error) {
+		target.Status, target.Message = batchStatusError, err.Error()
+		update(target)
+	}
+
+	cfg := sdk.DeployConfig{
+		Environment: target.Environment,
+		Revision:    options.ref,
+		DeployType:  options.deployType,
+	}
+	if cfg.DeployType == "" {
+		history, err := getHistoryUntil(f, target.Project, func(history []sdk.DeployItem) bool {
+			for _, deploy := range history {
+				if deploy.Environment == target.Environment {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		cfg.DeployType = lastDeployType(history, target.Environment)
+	}
+
+	response, err := f.MiaClient.Deploy.Trigger(target.Project, cfg)
+	if err != nil {
+		fail(err)
+		return
+	}
+	target.Status, target.PipelineID, target.PipelineURL = batchStatusTriggered, response.ID, response.URL
+	update(target)
+	if !options.wait.enabled {
+		return
+	}
+
+	status, err := waitForDeploy(f, target.Project, response.ID, target.Environment, options.wait.timeout)
+	if err != nil {
+		fail(err)
+		return
+	}
+	target.Status = status
+	update(target)
+}
+
+// lastDeployType returns the deploy type of the last deploy of env, which is
+// the one the console proposes for the next deploy.
+func lastDeployType(history []sdk.DeployItem, env string) string {
+	for _, deploy := range history {
+		if deploy.Environment == env {
+			return deploy.DeployType
+		}
+	}
+	return ""
+}
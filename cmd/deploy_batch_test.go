@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var batchTestProjects = sdk.Projects{
+	{
+		ProjectID: "payments-api",
+		Environments: []sdk.Environment{
+			{EnvID: "staging"},
+			{EnvID: "production"},
+		},
+	},
+	{
+		ProjectID: "payments-web",
+		Environments: []sdk.Environment{
+			{EnvID: "production"},
+		},
+	},
+	{
+		ProjectID: "catalog",
+		Environments: []sdk.Environment{
+			{EnvID: "production"},
+		},
+	},
+}
+
+func newBatchMock() *mock.Mock {
+	m := mock.New()
+	m.On(mock.NewMiaClient).Return(nil)
+	m.On(mock.ProjectsGet).Return(batchTestProjects, nil)
+	m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+	return m
+}
+
+func TestDeployBatch(t *testing.T) {
+	deployStatusPollInterval = time.Millisecond
+	baseArgs := []string{"deploy", "batch", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--ref", "v1.2.0"}
+
+	t.Run("returns error without projects", func(t *testing.T) {
+		_, err := executeRootCommandWithMock(newBatchMock(), append(baseArgs, "--env", "production")...)
+		require.EqualError(t, err, "one of --projects or --selector is required")
+	})
+
+	t.Run("returns error without environments", func(t *testing.T) {
+		out, err := executeRootCommandWithMock(newBatchMock(), append(baseArgs, "--projects", "catalog")...)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"env\" not set"))
+	})
+
+	t.Run("returns error without workers", func(t *testing.T) {
+		_, err := executeRootCommandWithMock(newBatchMock(), append(baseArgs, "--projects", "catalog", "--env", "production", "--workers", "0")...)
+		require.EqualError(t, err, "--workers must be at least 1")
+	})
+
+	t.Run("prints error on unknown project", func(t *testing.T) {
+		m := newBatchMock()
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--projects", "unknown", "--env", "production")...)
+		require.NoError(t, err)
+		require.Contains(t, out, fmt.Sprintf("%s: unknown", sdk.ErrProjectNotFound))
+		m.AssertNotCalled(t, mock.DeployTrigger)
+	})
+
+	t.Run("prints error on invalid selector", func(t *testing.T) {
+		out, err := executeRootCommandWithMock(newBatchMock(), append(baseArgs, "--selector", "payments-[", "--env", "production")...)
+		require.NoError(t, err)
+		require.Contains(t, out, fmt.Sprintf("%s: payments-[", errInvalidSelector))
+	})
+
+	t.Run("prints nothing to deploy without environments", func(t *testing.T) {
+		m := newBatchMock()
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--projects", "catalog", "--env", "staging")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "catalog\tstaging    \tskipped\t        \tenvironment not found\t\n")
+		require.True(t, strings.HasSuffix(out, "No project environment to deploy\n"))
+		m.AssertNotCalled(t, mock.DeployTrigger)
+	})
+
+	t.Run("renders the skipped environments as json without deploys", func(t *testing.T) {
+		out, err := executeRootCommandWithMock(newBatchMock(), append(baseArgs, "--projects", "catalog", "--env", "staging", "-o", "json")...)
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"project": "catalog", "environment": "staging", "status": "skipped", "message": "environment not found"}]`, out)
+	})
+
+	t.Run("aborts without confirmation", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{Projects: batchTestProjects}, "n\n", append(baseArgs, "--selector", "payments-*", "--env", "production")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Do you want to deploy v1.2.0 to 2 environments?")
+		require.True(t, strings.HasSuffix(out, "Batch deploy aborted\n"))
+	})
+
+	t.Run("deploys the selected projects", func(t *testing.T) {
+		m := newBatchMock()
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"}, nil)
+
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--projects", "catalog", "--selector", "payments-*", "--env", "staging,production", "--workers", "1", "--yes")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "payments-web\tstaging    \tskipped\t        \tenvironment not found\t\n")
+		require.Equal(t, 4, strings.Count(out, "\ttriggered\t#42\t"))
+		require.True(t, strings.HasSuffix(out, "Deploy of v1.2.0: 0 succeeded, 4 triggered, 0 failed, 2 skipped\n"))
+		m.AssertNumberOfCalls(t, mock.DeployTrigger, 4)
+		m.AssertCalled(t, mock.DeployTrigger, "payments-api", sdk.DeployConfig{Environment: "staging", Revision: "v1.2.0"})
+		m.AssertCalled(t, mock.DeployTrigger, "catalog", sdk.DeployConfig{Environment: "production", Revision: "v1.2.0"})
+		m.AssertNotCalled(t, mock.DeployGetStatus)
+	})
+
+	t.Run("uses the deploy type flag", func(t *testing.T) {
+		m := newBatchMock()
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42}, nil)
+
+		_, err := executeRootCommandWithMock(m, append(baseArgs, "--projects", "catalog", "--env", "production", "--deploy-type", "deploy_all", "--yes")...)
+		require.NoError(t, err)
+		m.AssertCalledOnceWith(t, mock.DeployTrigger, "catalog", sdk.DeployConfig{Environment: "production", Revision: "v1.2.0", DeployType: "deploy_all"})
+		m.AssertNotCalled(t, mock.DeployGetHistory)
+	})
+
+	t.Run("uses the deploy type found paging the history", func(t *testing.T) {
+		history := append(pagedDeployTestHistory()[:deployHistoryPageSize], sdk.DeployItem{ID: 5, Status: sdk.DeployStatusSuccess, Environment: "production", DeployType: "smart_deploy"})
+		var pages []int
+		var triggered []sdk.DeployConfig
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: history,
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+			DeployTriggerAssertFn: func(_ string, cfg sdk.DeployConfig) {
+				triggered = append(triggered, cfg)
+			},
+		}, append(baseArgs, "--projects", "project-id", "--env", "production", "--yes")...)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, pages)
+		require.Equal(t, []sdk.DeployConfig{{Environment: "production", Revision: "v1.2.0", DeployType: "smart_deploy"}}, triggered)
+	})
+
+	t.Run("deploys concurrently", func(t *testing.T) {
+		m := newBatchMock()
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42}, nil)
+		m.On(mock.DeployGetStatus).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusSuccess}, nil)
+
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--selector", "*", "--env", "production", "--workers", "3", "--yes", "--wait")...)
+		require.NoError(t, err)
+		require.Equal(t, 3, strings.Count(out, "\tproduction\tsuccess\t#42\t"))
+		require.True(t, strings.HasSuffix(out, "Deploy of v1.2.0: 3 succeeded, 0 triggered, 0 failed, 0 skipped\n"))
+		m.AssertNumberOfCalls(t, mock.DeployTrigger, 3)
+		m.AssertNumberOfCalls(t, mock.DeployGetStatus, 3)
+	})
+
+	t.Run("returns error if a deploy fails", func(t *testing.T) {
+		m := newBatchMock()
+		m.On(mock.DeployTrigger, "payments-web", mock.Anything).Return(sdk.DeployResponse{}, sdk.ErrGeneric)
+		m.On(mock.DeployTrigger, "payments-api", mock.Anything).Return(sdk.DeployResponse{ID: 43}, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42}, nil)
+		m.On(mock.DeployGetStatus, "payments-api", 43, "production").Return(sdk.PipelineStatus{ID: 43, Status: sdk.DeployStatusFailed}, nil)
+		m.On(mock.DeployGetStatus).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusSuccess}, nil)
+
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--selector", "*", "--env", "production", "--yes", "--wait")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: 2 of 3 deploys", errBatchFailed))
+		require.Contains(t, out, fmt.Sprintf("payments-web\tproduction\terror\t\t%s\t\n", sdk.ErrGeneric))
+		require.Contains(t, out, "payments-api\tproduction\tfailed\t#43\t\t\n")
+		require.Contains(t, out, "Deploy of v1.2.0: 1 succeeded, 0 triggered, 2 failed, 0 skipped\n")
+	})
+
+	t.Run("renders the results as json", func(t *testing.T) {
+		m := newBatchMock()
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"}, nil)
+
+		out, err := executeRootCommandWithMock(m, append(baseArgs, "--projects", "catalog", "--env", "staging,production", "--yes", "-o", "json")...)
+		require.NoError(t, err)
+		require.JSONEq(t, `[
+			{"project": "catalog", "environment": "staging", "status": "skipped", "message": "environment not found"},
+			{"project": "catalog", "environment": "production", "status": "triggered", "pipelineId": 42, "pipelineUrl": "https://pipeline/42"}
+		]`, out)
+	})
+}
+
+func TestSelectProjects(t *testing.T) {
+	ids := func(projects sdk.Projects) []string {
+		var ids []string
+		for _, project := range projects {
+			ids = append(ids, project.ProjectID)
+		}
+		return ids
+	}
+
+	selected, err := selectProjects(batchTestProjects, []string{"catalog"}, []string{"payments-w*"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"payments-web", "catalog"}, ids(selected))
+
+	selected, err = selectProjects(batchTestProjects, []string{"catalog"}, []string{"*"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"payments-api", "payments-web", "catalog"}, ids(selected))
+
+	selected, err = selectProjects(batchTestProjects, nil, []string{"orders-*"})
+	require.NoError(t, err)
+	require.Empty(t, selected)
+}
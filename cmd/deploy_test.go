@@ -0,0 +1,417 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var deployTestProjects = sdk.Projects{
+	{
+		ID:        "mongo-id",
+		Name:      "Project",
+		ProjectID: "project-id",
+		Environments: []sdk.Environment{
+			{DisplayName: "Development", EnvID: "development"},
+			{DisplayName: "Staging", EnvID: "staging"},
+			{DisplayName: "Production", EnvID: "production"},
+			{DisplayName: "Preview", EnvID: "preview"},
+		},
+	},
+}
+
+var deployTestHistory = []sdk.DeployItem{
+	{
+		ID:          5,
+		Status:      sdk.DeployStatusFailed,
+		Ref:         "v1.3.0",
+		Commit:      sdk.CommitInfo{Hash: "ccc"},
+		Environment: "production",
+		FinishedAt:  time.Date(2020, 04, 24, 21, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          4,
+		Status:      sdk.DeployStatusSuccess,
+		Ref:         "v1.3.0",
+		Commit:      sdk.CommitInfo{Hash: "ccc"},
+		Environment: "staging",
+		FinishedAt:  time.Date(2020, 04, 24, 20, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          3,
+		Status:      sdk.DeployStatusSuccess,
+		Ref:         "master",
+		Commit:      sdk.CommitInfo{Hash: "ccc"},
+		Environment: "development",
+		FinishedAt:  time.Date(2020, 04, 24, 19, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          2,
+		Status:      sdk.DeployStatusSuccess,
+		Ref:         "v1.2.0",
+		Commit:      sdk.CommitInfo{Hash: "bbb"},
+		Environment: "production",
+		FinishedAt:  time.Date(2020, 04, 23, 10, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          1,
+		Status:      sdk.DeployStatusSuccess,
+		Ref:         "v1.1.0",
+		Commit:      sdk.CommitInfo{Hash: "aaa"},
+		Environment: "staging",
+		FinishedAt:  time.Date(2020, 04, 22, 10, 00, 00, 00, time.UTC),
+	},
+}
+
+// pagedDeployTestHistory returns deployTestHistory preceded by a page of
+// failed deploys, so that its deploys are found only by paging the history.
+func pagedDeployTestHistory() []sdk.DeployItem {
+	history := make([]sdk.DeployItem, 0, deployHistoryPageSize+len(deployTestHistory))
+	for i := 0; i < deployHistoryPageSize; i++ {
+		history = append(history, sdk.DeployItem{
+			ID:          1000 - i,
+			Status:      sdk.DeployStatusFailed,
+			Ref:         "master",
+			Environment: "development",
+		})
+	}
+	return append(history, deployTestHistory...)
+}
+
+func TestDeployCompare(t *testing.T) {
+	projectIDFlag := fmt.Sprintf("--project=%s", "project-id")
+
+	t.Run("returns error if no baseline is provided", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "deploy", "compare", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"baseline\" not set"))
+	})
+
+	t.Run("returns error if no project ID is provided", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "deploy", "compare", "--baseline=production", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"project\" not set"))
+	})
+
+	t.Run("renders error if project does not exist", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, "deploy", "compare", "--baseline=production", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=not-exists")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: not-exists\n", sdk.ErrProjectNotFound), out)
+	})
+
+	t.Run("renders error if baseline environment does not exist", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, "deploy", "compare", "--baseline=qa", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: qa\n", errEnvironmentNotFound), out)
+	})
+
+	t.Run("renders error on sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:    deployTestProjects,
+			DeployError: fmt.Errorf("Some error"),
+		}, "deploy", "compare", "--baseline=production", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.NoError(t, err)
+		require.Equal(t, "Some error\n", out)
+	})
+
+	t.Run("renders environments compared to baseline", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				require.Equal(t, sdk.DeployHistoryQuery{ProjectID: "project-id", Page: 1, PerPage: deployHistoryPageSize}, query)
+			},
+			DeployHistory: deployTestHistory,
+		}, "deploy", "compare", "--baseline=production", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"ENVIRONMENT | DEPLOY BRANCH/TAG | COMMIT | FINISHED AT | COMPARED TO PRODUCTION",
+			"development | master | ccc | 24 Apr 2020 19:00 UTC | DIFFERS",
+			"staging | v1.3.0 | ccc | 24 Apr 2020 20:00 UTC | DIFFERS",
+			"production | v1.2.0 | bbb | 23 Apr 2020 10:00 UTC | baseline",
+			"preview | - | - | - | never deployed",
+		}, rows)
+	})
+
+	t.Run("renders same when revision matches baseline", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+		}, "deploy", "compare", "--baseline=staging", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, "development | master | ccc | 24 Apr 2020 19:00 UTC | same", rows[1])
+		require.Equal(t, "staging | v1.3.0 | ccc | 24 Apr 2020 20:00 UTC | baseline", rows[2])
+	})
+
+	t.Run("pages the history until every environment is resolved", func(t *testing.T) {
+		var pages []int
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: pagedDeployTestHistory(),
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+		}, "deploy", "compare", "--baseline=production", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, pages)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, "development | master | ccc | 24 Apr 2020 19:00 UTC | DIFFERS", rows[1])
+		require.Equal(t, "production | v1.2.0 | bbb | 23 Apr 2020 10:00 UTC | baseline", rows[3])
+	})
+}
+
+func TestLastSuccessfulDeploy(t *testing.T) {
+	t.Run("skips failed deploys", func(t *testing.T) {
+		deploy, ok := lastSuccessfulDeploy(deployTestHistory, "production")
+		require.True(t, ok)
+		require.Equal(t, 2, deploy.ID)
+	})
+
+	t.Run("returns false if environment was never deployed", func(t *testing.T) {
+		deploy, ok := lastSuccessfulDeploy(deployTestHistory, "preview")
+		require.False(t, ok)
+		require.Equal(t, sdk.DeployItem{}, deploy)
+	})
+}
+
+func TestDeployPromote(t *testing.T) {
+	deployStatusPollInterval = time.Millisecond
+	projectIDFlag := fmt.Sprintf("--project=%s", "project-id")
+	baseArgs := []string{"deploy", "promote", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag}
+
+	t.Run("returns error if environments are not provided", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, baseArgs...)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"from\", \"to\" not set"))
+	})
+
+	t.Run("renders error if environment does not exist", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: deployTestProjects,
+		}, append(baseArgs, "--from=staging", "--to=qa")...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: qa\n", errEnvironmentNotFound), out)
+	})
+
+	t.Run("renders error if source environment was never deployed", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+		}, append(baseArgs, "--from=preview", "--to=production")...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: preview\n", errNoSuccessfulDeploy), out)
+	})
+
+	t.Run("promotes a deploy found paging the history", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         pagedDeployTestHistory(),
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+		}, append(baseArgs, "--from=staging", "--to=production", "--yes")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Promoting v1.3.0 (commit ccc) from staging to production\n")
+	})
+
+	t.Run("aborts without confirmation", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(string, sdk.DeployConfig) {
+				t.Fatal("deploy should not be triggered")
+			},
+		}, "n\n", append(baseArgs, "--from=staging", "--to=production")...)
+		require.NoError(t, err)
+		require.Equal(t, "Promoting v1.3.0 (commit ccc) from staging to production\nDo you want to continue? [y/N]: Promotion aborted\n", out)
+	})
+
+	t.Run("triggers deploy after confirmation", func(t *testing.T) {
+		var triggered bool
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(projectID string, cfg sdk.DeployConfig) {
+				triggered = true
+				require.Equal(t, "project-id", projectID)
+				require.Equal(t, sdk.DeployConfig{
+					Environment: "production",
+					Revision:    "v1.3.0",
+				}, cfg)
+			},
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+		}, "y\n", append(baseArgs, "--from=staging", "--to=production")...)
+		require.NoError(t, err)
+		require.True(t, triggered)
+		require.Contains(t, out, "Deploy pipeline #42 of v1.3.0 triggered on production: https://pipeline/42\n")
+	})
+
+	t.Run("waits for deploy to succeed", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+			DeployStatuses: []sdk.PipelineStatus{
+				{ID: 42, Status: sdk.DeployStatusRunning},
+				{ID: 42, Status: sdk.DeployStatusSuccess},
+			},
+		}, append(baseArgs, "--from=staging", "--to=production", "--yes", "--wait")...)
+		require.NoError(t, err)
+		require.Equal(t, "Promoting v1.3.0 (commit ccc) from staging to production\nDeploy pipeline #42 of v1.3.0 triggered on production: https://pipeline/42\nDeploy pipeline #42 finished with status success\n", out)
+	})
+
+	t.Run("triggers the promoted revision once and polls its pipeline", func(t *testing.T) {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42}, nil).Once()
+		m.On(mock.DeployGetStatus).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusRunning}, nil).Once()
+		m.On(mock.DeployGetStatus).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusSuccess}, nil)
+
+		_, err := executeRootCommandWithMock(m, append(baseArgs, "--from=staging", "--to=production", "--yes", "--wait")...)
+		require.NoError(t, err)
+		m.AssertCalledOnceWith(t, mock.DeployTrigger, "project-id", sdk.DeployConfig{Environment: "production", Revision: "v1.3.0"})
+		m.AssertCalled(t, mock.DeployGetStatus, "project-id", 42, "production")
+		m.AssertNumberOfCalls(t, mock.DeployGetStatus, 2)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("returns error if the trigger fails", func(t *testing.T) {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{}, sdk.ErrHTTP)
+
+		_, err := executeRootCommandWithMock(m, append(baseArgs, "--from=staging", "--to=production", "--yes")...)
+		require.True(t, errors.Is(err, sdk.ErrHTTP))
+		m.AssertNotCalled(t, mock.DeployGetStatus)
+	})
+
+	t.Run("returns error if deploy fails", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+			DeployStatuses:        []sdk.PipelineStatus{{ID: 42, Status: sdk.DeployStatusFailed}},
+		}, append(baseArgs, "--from=staging", "--to=production", "--yes", "--wait")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: failed", errDeployNotSucceeded))
+		require.Contains(t, out, "Deploy pipeline #42 finished with status failed\n")
+	})
+
+	t.Run("returns error on wait timeout", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+			DeployStatuses:        []sdk.PipelineStatus{{ID: 42, Status: sdk.DeployStatusRunning}},
+		}, append(baseArgs, "--from=staging", "--to=production", "--yes", "--wait", "--wait-timeout=5ms")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: pipeline #42 is running", errDeployWaitTimeout))
+	})
+}
+
+func TestDeployRollback(t *testing.T) {
+	deployStatusPollInterval = time.Millisecond
+	projectIDFlag := fmt.Sprintf("--project=%s", "project-id")
+	baseArgs := []string{"deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag}
+
+	t.Run("returns error if environment is not provided", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, baseArgs...)
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(out, "Error: required flag(s) \"env\" not set"))
+	})
+
+	t.Run("renders error if there is no previous deploy", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: deployTestHistory,
+		}, append(baseArgs, "--env=production")...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: production\n", errNoRollbackTarget), out)
+	})
+
+	t.Run("renders error if target deploy is not a successful deploy of the environment", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: deployTestHistory,
+		}, append(baseArgs, "--env=staging", "--to=2")...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: #2 is not a successful deploy of staging\n", errDeployNotFound), out)
+	})
+
+	t.Run("aborts without confirmation", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(string, sdk.DeployConfig) {
+				t.Fatal("deploy should not be triggered")
+			},
+		}, "\n", append(baseArgs, "--env=staging")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Rollback aborted\n")
+	})
+
+	t.Run("rolls back to the previous successful deploy", func(t *testing.T) {
+		var triggered bool
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: deployTestHistory,
+			DeployTriggerAssertFn: func(projectID string, cfg sdk.DeployConfig) {
+				triggered = true
+				require.Equal(t, "project-id", projectID)
+				require.Equal(t, sdk.DeployConfig{
+					Environment: "staging",
+					Revision:    "v1.1.0",
+				}, cfg)
+			},
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+		}, append(baseArgs, "--env=staging", "--reason=broken login", "--yes", "--wait")...)
+		require.NoError(t, err)
+		require.True(t, triggered)
+		require.Equal(t, `Rolling back staging
+  from: v1.3.0 (commit ccc), deploy #4
+  to:   v1.1.0 (commit aaa), deploy #1
+  reason: broken login
+Deploy pipeline #42 of v1.1.0 triggered on staging: https://pipeline/42
+Deploy pipeline #42 finished with status success
+`, out)
+	})
+
+	t.Run("rolls back to a deploy found paging the history", func(t *testing.T) {
+		var pages []int
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: pagedDeployTestHistory(),
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42, URL: "https://pipeline/42"},
+		}, append(baseArgs, "--env=staging", "--to=1", "--yes")...)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, pages)
+		require.Contains(t, out, "  to:   v1.1.0 (commit aaa), deploy #1\n")
+	})
+}
+
+func TestRollbackTarget(t *testing.T) {
+	t.Run("returns requested deploy", func(t *testing.T) {
+		current, target, err := rollbackTarget(deployTestHistory, "staging", 1)
+		require.NoError(t, err)
+		require.Equal(t, 4, current.ID)
+		require.Equal(t, 1, target.ID)
+	})
+
+	t.Run("returns error if environment was never deployed", func(t *testing.T) {
+		_, _, err := rollbackTarget(deployTestHistory, "preview", 0)
+		require.EqualError(t, err, fmt.Sprintf("%s: preview", errNoSuccessfulDeploy))
+	})
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/mia-platform/miactl/sdk/fake"
+	"github.com/spf13/cobra"
+)
+
+func newDevCmd() *cobra.Command {
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Tools to develop and test miactl and the scripts using it",
+		// overrides the root hook: the dev tools don't connect to a console.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	devCmd.AddCommand(newFakeServerCmd())
+	return devCmd
+}
+
+func newFakeServerCmd() *cobra.Command {
+	var addr, fixturesPath string
+
+	cmd := &cobra.Command{
+		Use:   "fake-server",
+		Short: "Serve a fake console with the projects and deploys of a fixtures file",
+		Long: `Serve a fake console with the projects and deploys of a fixtures file.
+
+The fake console serves the projects, deploy history, deploy trigger and pipeline
+status endpoints. The triggered pipelines go through the statuses set for their
+environment in the fixtures and, once finished, are added to the deploy history.
+
+The state can be scripted with the following endpoints:
+  GET /fake/triggers lists the triggered deploys
+  PUT /fake/pipelines/{id}/status sets the status of a pipeline, with a {"status": "failed"} body`,
+		Example: `  # serve the fixtures and deploy on the fake console
+  miactl dev fake-server --fixtures fixtures.json --addr 127.0.0.1:8080 &
+  miactl deploy promote --apiBaseUrl http://127.0.0.1:8080/ --apiKey fake --apiCookie sid=fake --project my-project --from staging --to production`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fixtures, err := fake.ReadFixtures(fixturesPath)
+			if err != nil {
+				return err
+			}
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			return serveFakeConsole(cmd.Context(), cmd.OutOrStdout(), listener, fake.NewConsole(fixtures))
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "address the fake console listens on")
+	cmd.Flags().StringVar(&fixturesPath, "fixtures", "", "json file with the projects, deploy history and pipeline statuses of the fake console")
+	cmd.MarkFlagRequired("fixtures")
+
+	return cmd
+}
+
+// serveFakeConsole serves the console until ctx is done or the process
+// is interrupted.
+func serveFakeConsole(ctx context.Context, out io.Writer, listener net.Listener, console *fake.Console) error {
+	server := &http.Server{Handler: console}
+	fmt.Fprintf(out, "Fake console listening on http://%s/\n", listener.Addr())
+	fmt.Fprintf(out, "Connect with --apiBaseUrl http://%s/ --apiKey fake --apiCookie sid=fake\n", listener.Addr())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	select {
+	case err := <-served:
+		return err
+	case <-interrupt:
+	case <-ctx.Done():
+	}
+	return server.Shutdown(context.Background())
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeServer(t *testing.T) {
+	t.Run("requires the fixtures", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "dev", "fake-server")
+		require.EqualError(t, err, `required flag(s) "fixtures" not set`)
+	})
+
+	t.Run("returns error if fixtures file does not exist", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "dev", "fake-server", "--fixtures=not-exists.json")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not-exists.json")
+	})
+
+	t.Run("serves the fake console until done", func(t *testing.T) {
+		fixtures, err := fake.ReadFixtures("../sdk/fake/testdata/fixtures.json")
+		require.NoError(t, err)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		out := &bytes.Buffer{}
+		served := make(chan error, 1)
+		go func() {
+			served <- serveFakeConsole(ctx, out, listener, fake.NewConsole(fixtures))
+		}()
+
+		client, err := sdk.New(sdk.Options{
+			APIBaseURL: "http://" + listener.Addr().String() + "/",
+			APIKey:     "fake",
+			APICookie:  "sid=fake",
+		})
+		require.NoError(t, err)
+		projects, err := client.Projects.Get()
+		require.NoError(t, err)
+		require.Equal(t, "project-1", projects[0].ProjectID)
+
+		cancel()
+		require.NoError(t, <-served)
+		require.Contains(t, out.String(), "Fake console listening on http://"+listener.Addr().String()+"/")
+	})
+}
@@ -54,6 +54,9 @@ func GetFactoryFromContext(ctx context.Context, opts sdk.Options) (*Factory, err
 	if err != nil {
 		return nil, err
 	}
+	if run := auditRunFromContext(ctx); run != nil {
+		factory.Renderer = auditRenderer{IRenderer: factory.Renderer, run: run}
+	}
 
 	return &factory, nil
 }
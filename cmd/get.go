@@ -1,6 +1,11 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"time"
 
@@ -12,11 +17,18 @@ import (
 var validArgs = []string{
 	"project", "projects",
 	"deployment", "deployments",
+	"company", "companies",
+	"marketplace",
 }
 
+var errInvalidMarketplaceType = errors.New("Invalid marketplace type")
+
 // NewGetCmd func creates a new command
 func newGetCmd() *cobra.Command {
-	return &cobra.Command{
+	var watch watchOptions
+	var marketplaceQuery sdk.MarketplaceQuery
+
+	cmd := &cobra.Command{
 		Use:       "get",
 		ValidArgs: validArgs,
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -27,8 +39,27 @@ func newGetCmd() *cobra.Command {
 			case "projects", "project":
 			case "deployment", "deployments":
 				cmd.MarkFlagRequired("project")
+			case "company", "companies":
+				if watch.enabled {
+					return errors.New("--watch is not supported for companies")
+				}
+			case "marketplace":
+				if watch.enabled {
+					return errors.New("--watch is not supported for marketplace")
+				}
+				switch marketplaceQuery.Type {
+				case "", sdk.MarketplaceTypeTemplate, sdk.MarketplaceTypePlugin, sdk.MarketplaceTypeExample:
+				default:
+					return fmt.Errorf("%w: %s", errInvalidMarketplaceType, marketplaceQuery.Type)
+				}
 			}
-			return nil
+			if watch.events && !watch.enabled {
+				return errors.New("--watch-events requires --watch")
+			}
+			if watch.enabled {
+				return checkOutputFormat("table")
+			}
+			return checkOutputFormat("table", "json", "yaml")
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			f, err := GetFactoryFromContext(cmd.Context(), opts)
@@ -38,17 +69,44 @@ func newGetCmd() *cobra.Command {
 
 			resource := args[0]
 
+			if watch.enabled {
+				switch resource {
+				case "projects", "project":
+					watchProjects(cmd.Context(), f, watch)
+				case "deployment", "deployments":
+					watchDeploysForProject(cmd.Context(), cmd.OutOrStdout(), f, watch)
+				}
+				return nil
+			}
+
 			switch resource {
 			case "projects", "project":
 				getProjects(f)
 			case "deployment", "deployments":
 				getDeploysForProject(f)
+			case "company", "companies":
+				getCompanies(f)
+			case "marketplace":
+				getMarketplace(f, marketplaceQuery)
 			}
 			return nil
 		},
 	}
+
+	addWatchFlags(cmd, &watch)
+	cmd.Flags().StringVar(&marketplaceQuery.Search, "search", "", "show only the marketplace items matching the text")
+	cmd.Flags().StringVar(&marketplaceQuery.Category, "category", "", "show only the marketplace items of the category id")
+	cmd.Flags().StringVar(&marketplaceQuery.Type, "type", "", "show only the marketplace items of the type (template, plugin or example)")
+	return cmd
 }
 
+var (
+	projectsHeaders    = []string{"#", "Name", "Configuration Git Path", "Project id"}
+	deploymentsHeaders = []string{"#", "Status", "Deploy Type", "Environment", "Deploy Branch/Tag", "Made By", "Duration", "Finished At", "View Log"}
+	companiesHeaders   = []string{"#", "Name", "Company id"}
+	marketplaceHeaders = []string{"#", "Name", "Id", "Type", "Category", "Description"}
+)
+
 func getProjects(f *Factory) {
 	projects, err := f.MiaClient.Projects.Get()
 	if err != nil {
@@ -56,19 +114,86 @@ func getProjects(f *Factory) {
 		return
 	}
 
-	headers := []string{"#", "Name", "Configuration Git Path", "Project id"}
-	table := f.Renderer.Table(headers)
+	projects = filterProjectsByCompany(projects, companyID)
+	if renderStructured(f, projects) {
+		return
+	}
+	table := f.Renderer.Table(projectsHeaders)
 	for i, project := range projects {
+		table.Append(projectRow(i, project))
+	}
+	table.Render()
+}
+
+// filterProjectsByCompany returns the projects of the company, or all the
+// projects if company is empty.
+func filterProjectsByCompany(projects sdk.Projects, company string) sdk.Projects {
+	if company == "" {
+		return projects
+	}
+	filtered := sdk.Projects{}
+	for _, project := range projects {
+		if project.TenantID == company {
+			filtered = append(filtered, project)
+		}
+	}
+	return filtered
+}
+
+func getCompanies(f *Factory) {
+	companies, err := f.MiaClient.Companies.Get()
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+
+	if renderStructured(f, companies) {
+		return
+	}
+	table := f.Renderer.Table(companiesHeaders)
+	for i, company := range companies {
 		table.Append([]string{
 			strconv.Itoa(i + 1),
-			project.Name,
-			project.ConfigurationGitPath,
-			project.ProjectID,
+			company.Name,
+			company.TenantID,
 		})
 	}
 	table.Render()
 }
 
+func getMarketplace(f *Factory, query sdk.MarketplaceQuery) {
+	items, err := f.MiaClient.Marketplace.Get(query)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+
+	if renderStructured(f, items) {
+		return
+	}
+	table := f.Renderer.Table(marketplaceHeaders)
+	for i, item := range items {
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			item.Name,
+			item.ID,
+			item.Type,
+			item.Category.Label,
+			item.Description,
+		})
+	}
+	table.Render()
+}
+
+func projectRow(i int, project sdk.Project) []string {
+	return []string{
+		strconv.Itoa(i + 1),
+		project.Name,
+		project.ConfigurationGitPath,
+		project.ProjectID,
+	}
+}
+
 func getDeploysForProject(f *Factory) {
 	query := sdk.DeployHistoryQuery{
 		ProjectID: projectID,
@@ -80,20 +205,135 @@ func getDeploysForProject(f *Factory) {
 		return
 	}
 
-	headers := []string{"#", "Status", "Deploy Type", "Environment", "Deploy Branch/Tag", "Made By", "Duration", "Finished At", "View Log"}
-	table := f.Renderer.Table(headers)
+	if renderStructured(f, history) {
+		return
+	}
+	table := f.Renderer.Table(deploymentsHeaders)
 	for _, deploy := range history {
-		table.Append([]string{
-			strconv.Itoa(deploy.ID),
-			deploy.Status,
-			deploy.DeployType,
-			deploy.Environment,
-			deploy.Ref,
-			deploy.User.Name,
-			time.Duration(time.Duration(deploy.Duration) * time.Second).String(),
-			renderer.FormatDate(deploy.FinishedAt),
-			deploy.WebURL,
-		})
+		table.Append(deployRow(deploy))
 	}
 	table.Render()
 }
+
+func deployRow(deploy sdk.DeployItem) []string {
+	return []string{
+		strconv.Itoa(deploy.ID),
+		deploy.Status,
+		deploy.DeployType,
+		deploy.Environment,
+		deploy.Ref,
+		deploy.User.Name,
+		time.Duration(time.Duration(deploy.Duration) * time.Second).String(),
+		renderer.FormatDate(deploy.FinishedAt),
+		deploy.WebURL,
+	}
+}
+
+func watchProjects(ctx context.Context, f *Factory, watch watchOptions) {
+	fetch := func() ([]watchRow, error) {
+		projects, err := f.MiaClient.Projects.Get()
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]watchRow, 0, len(projects))
+		for i, project := range filterProjectsByCompany(projects, companyID) {
+			cells := projectRow(i, project)
+			rows = append(rows, watchRow{ID: project.ProjectID, Cells: cells, Values: cells[1:]})
+		}
+		return rows, nil
+	}
+
+	watchResource(ctx, watch.interval, fetch, renderChangedRows(f, projectsHeaders), renderWatchError(f))
+}
+
+func watchDeploysForProject(ctx context.Context, out io.Writer, f *Factory, watch watchOptions) {
+	deploys := map[string]sdk.DeployItem{}
+	fetch := func() ([]watchRow, error) {
+		history, err := f.MiaClient.Deploy.GetHistory(sdk.DeployHistoryQuery{
+			ProjectID: projectID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]watchRow, 0, len(history))
+		for _, deploy := range history {
+			id := strconv.Itoa(deploy.ID)
+			deploys[id] = deploy
+			rows = append(rows, watchRow{ID: id, Status: deploy.Status, Cells: deployRow(deploy)})
+		}
+		return rows, nil
+	}
+
+	onPoll := renderChangedRows(f, deploymentsHeaders)
+	if watch.events {
+		encoder := json.NewEncoder(out)
+		onPoll = func(changes []rowChange, first bool) {
+			// the first poll is the starting state, not a transition
+			if first {
+				return
+			}
+			for _, change := range changes {
+				var from string
+				if change.Previous != nil {
+					from = change.Previous.Status
+				}
+				if from == change.Current.Status {
+					continue
+				}
+				deploy := deploys[change.Current.ID]
+				encoder.Encode(deployStatusEvent{
+					Type:        "deploy.status",
+					ProjectID:   projectID,
+					DeployID:    deploy.ID,
+					Environment: deploy.Environment,
+					Ref:         deploy.Ref,
+					From:        from,
+					To:          deploy.Status,
+					WebURL:      deploy.WebURL,
+					Time:        timeNow(),
+				})
+			}
+		}
+	}
+
+	watchResource(ctx, watch.interval, fetch, onPoll, renderWatchError(f))
+}
+
+// deployStatusEvent is emitted in watch mode for each deploy status
+// transition. From is empty for deploys which were not listed before.
+type deployStatusEvent struct {
+	Type        string    `json:"type"`
+	ProjectID   string    `json:"projectId"`
+	DeployID    int       `json:"deployId"`
+	Environment string    `json:"environment"`
+	Ref         string    `json:"ref"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	WebURL      string    `json:"webUrl"`
+	Time        time.Time `json:"time"`
+}
+
+// renderChangedRows renders the whole table on the first poll and only the
+// changed rows, without headers, on the following ones.
+func renderChangedRows(f *Factory, headers []string) func([]rowChange, bool) {
+	return func(changes []rowChange, first bool) {
+		if len(changes) == 0 {
+			return
+		}
+		tableHeaders := headers
+		if !first {
+			tableHeaders = nil
+		}
+		table := f.Renderer.Table(tableHeaders)
+		for _, change := range changes {
+			table.Append(change.Current.Cells)
+		}
+		table.Render()
+	}
+}
+
+func renderWatchError(f *Factory) func(error) {
+	return func(err error) {
+		f.Renderer.Error(err).Render()
+	}
+}
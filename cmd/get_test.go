@@ -233,3 +233,110 @@ func assertMockDeploymentsCorrectlyRendered(t *testing.T, rows []string) {
 	require.Equal(t, expectedRow1, rows[1])
 	require.Equal(t, expectedRow2, rows[2])
 }
+
+func TestGetCompanies(t *testing.T) {
+	companies := sdk.Companies{
+		{ID: "id-1", Name: "Company 1", TenantID: "company-1"},
+		{ID: "id-2", Name: "Company 2", TenantID: "company-2"},
+	}
+
+	t.Run("get companies", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Companies: companies,
+		}, "get", "companies", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"# | NAME | COMPANY ID",
+			"1 | Company 1 | company-1",
+			"2 | Company 2 | company-2",
+		}, rows)
+	})
+
+	t.Run("get companies returns error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			CompaniesError: sdk.ErrHTTP,
+		}, "get", "companies", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s\n", sdk.ErrHTTP), out)
+	})
+
+	t.Run("get projects filtered by company", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Projects: sdk.Projects{
+				{Name: "Project 1", ConfigurationGitPath: "/git/path", ProjectID: "project-1", TenantID: "company-1"},
+				{Name: "Project 2", ConfigurationGitPath: "/git/path", ProjectID: "project-2", TenantID: "company-2"},
+				{Name: "Project 3", ConfigurationGitPath: "/git/path", ProjectID: "project-3", TenantID: "company-2"},
+			},
+		}, "get", "projects", "--company=company-2", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"# | NAME | CONFIGURATION GIT PATH | PROJECT ID",
+			"1 | Project 2 | /git/path | project-2",
+			"2 | Project 3 | /git/path | project-3",
+		}, rows)
+	})
+}
+
+func TestGetMarketplace(t *testing.T) {
+	items := sdk.MarketplaceItems{
+		{
+			ID:          "node-template",
+			Name:        "Node.js Template",
+			Description: "A template to create Node.js services",
+			Type:        sdk.MarketplaceTypeTemplate,
+			Category:    sdk.MarketplaceCategory{ID: "nodejs", Label: "Node.js"},
+		},
+		{
+			ID:       "crud-service",
+			Name:     "CRUD Service",
+			Type:     sdk.MarketplaceTypePlugin,
+			Category: sdk.MarketplaceCategory{ID: "data", Label: "Data Stores"},
+		},
+	}
+
+	t.Run("get marketplace", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			MarketplaceItems: items,
+			MarketplaceAssertFn: func(query sdk.MarketplaceQuery) {
+				require.Equal(t, sdk.MarketplaceQuery{}, query)
+			},
+		}, "get", "marketplace", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"# | NAME | ID | TYPE | CATEGORY | DESCRIPTION",
+			"1 | Node.js Template | node-template | template | Node.js | A template to create Node.js services",
+			"2 | CRUD Service | crud-service | plugin | Data Stores",
+		}, rows)
+	})
+
+	t.Run("get marketplace with filters", func(t *testing.T) {
+		var called bool
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			MarketplaceAssertFn: func(query sdk.MarketplaceQuery) {
+				called = true
+				require.Equal(t, sdk.MarketplaceQuery{Search: "node", Category: "nodejs", Type: "template"}, query)
+			},
+		}, "get", "marketplace", "--search=node", "--category=nodejs", "--type=template", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.True(t, called)
+	})
+
+	t.Run("returns error on invalid type", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "marketplace", "--type=foo", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, fmt.Sprintf("%s: foo", errInvalidMarketplaceType))
+	})
+
+	t.Run("get marketplace returns error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			MarketplaceError: sdk.ErrHTTP,
+		}, "get", "marketplace", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s\n", sdk.ErrHTTP), out)
+	})
+}
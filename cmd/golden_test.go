@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+// goldenNow is the current time of the golden tests.
+var goldenNow = time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC)
+
+// goldenMock is the console data shared by the golden tests.
+var goldenMock = sdk.MockClientError{
+	Projects: sdk.Projects{
+		{
+			ID:                   "mongo-id",
+			Name:                 "Project",
+			ConfigurationGitPath: "/clients/project",
+			ProjectID:            "project-id",
+			TenantID:             "company-1",
+			Environments: []sdk.Environment{
+				{DisplayName: "Development", EnvID: "development"},
+				{DisplayName: "Staging", EnvID: "staging"},
+				{DisplayName: "Production", EnvID: "production"},
+				{DisplayName: "Preview", EnvID: "preview"},
+			},
+		},
+		{
+			ID:                   "mongo-id-2",
+			Name:                 "Other Project",
+			ConfigurationGitPath: "/clients/other",
+			ProjectID:            "other-project",
+			TenantID:             "company-2",
+		},
+	},
+	DeployHistory:         deployTestHistory,
+	DeployTriggerResponse: sdk.DeployResponse{ID: 6, URL: "https://the-repo/pipelines/6"},
+	Companies: sdk.Companies{
+		{Name: "Company 1", TenantID: "company-1"},
+		{Name: "Company 2", TenantID: "company-2"},
+	},
+	MarketplaceItems: sdk.MarketplaceItems{
+		{
+			ID:          "node-template",
+			Name:        "Node.js Template",
+			Description: "Node.js service template",
+			Type:        sdk.MarketplaceTypeTemplate,
+			Category:    sdk.MarketplaceCategory{ID: "nodejs", Label: "Node.js"},
+		},
+		{
+			ID:       "crud-service",
+			Name:     "CRUD Service",
+			Type:     sdk.MarketplaceTypePlugin,
+			Category: sdk.MarketplaceCategory{ID: "data", Label: "Data"},
+		},
+	},
+	ServiceCreateResponse: &sdk.CreateServiceResponse{
+		ID:            "service-id",
+		Name:          "my-service",
+		RepositoryURL: "https://the-repo/my-service",
+	},
+	Members: sdk.Members{
+		{ID: "member-1", Name: "Jane Doe", Email: "jane.doe@example.com", Role: sdk.RoleDeveloper},
+		{ID: "member-2", Name: "John Doe", Email: "john.doe@example.com", Role: sdk.RoleMaintainer},
+	},
+	APIKeys: sdk.APIKeys{
+		{ID: "key-1", Name: "ci", Roles: []string{sdk.RoleDeveloper}, CreatedAt: time.Date(2020, 04, 20, 10, 00, 00, 00, time.UTC)},
+		{ID: "key-2", Name: "monitoring", Roles: []string{sdk.RoleGuest, sdk.RoleReporter}, CreatedAt: time.Date(2020, 04, 21, 10, 00, 00, 00, time.UTC)},
+	},
+}
+
+// goldenCase runs miactl with args, appended to the console connection
+// flags, and compares the output with testdata/golden/name.golden.
+type goldenCase struct {
+	name  string
+	args  []string
+	input string
+	mock  *sdk.MockClientError
+	// env sets environment variables, like PATH to find the plugins
+	env map[string]string
+	// auditLog is the audit log read by the case
+	auditLog string
+}
+
+// runGolden executes the command and compares stdout, stderr and exit code
+// with the golden file. Run the tests with -update to write the golden
+// files with the current output.
+func runGolden(t *testing.T, test goldenCase) {
+	t.Helper()
+	defer viper.Reset()
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return goldenNow }
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "MIACTL_") {
+			name := strings.SplitN(env, "=", 2)[0]
+			value := os.Getenv(name)
+			os.Unsetenv(name)
+			defer os.Setenv(name, value)
+		}
+	}
+
+	for name, value := range test.env {
+		previous, set := os.LookupEnv(name)
+		os.Setenv(name, value)
+		if set {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+	}
+	if test.auditLog != "" {
+		defer func(previous string) { auditLogFile = previous }(auditLogFile)
+		auditLogFile = test.auditLog
+	}
+
+	mock := goldenMock
+	if test.mock != nil {
+		mock = *test.mock
+	}
+
+	// the real standard streams are captured, since cobra writes errors
+	// and usage to stderr only when the command output is not set.
+	stdout, stderr := captureOutput(t, "stdout", &os.Stdout), captureOutput(t, "stderr", &os.Stderr)
+	rootCmd := NewRootCmd()
+	rootCmd.SetIn(strings.NewReader(test.input))
+	// the missing config file replaces the one in the home directory, the
+	// cases can set their own one.
+	missingConfig := fmt.Sprintf("--config=%s", filepath.Join("testdata", "golden", "missing-config.yaml"))
+	args := append([]string{missingConfig, apiKeyFlag, apiBaseURLFlag, apiCookieFlag}, test.args...)
+	ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+		Renderer:         renderer.New(rootCmd.OutOrStdout()),
+		miaClientCreator: sdk.WrapperMockMiaClient(mock),
+	})
+	exitCode := executeRoot(ctx, rootCmd, args)
+	output := fmt.Sprintf("$ miactl %s\n--- stdout\n%s--- stderr\n%s--- exit code %d\n", strings.Join(test.args, " "), stdout(), stderr(), exitCode)
+
+	path := filepath.Join("testdata", "golden", fmt.Sprintf("%s.golden", test.name))
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(output), 0644))
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "missing golden file, run the tests with -update to create it")
+	require.Equal(t, string(expected), output)
+}
+
+// captureOutput replaces the stream with a pipe and returns the function
+// restoring the stream and returning what has been written.
+func captureOutput(t *testing.T, name string, stream **os.File) func() string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	original := *stream
+	*stream = writer
+
+	output := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, reader)
+		reader.Close()
+		output <- buf.String()
+	}()
+	return func() string {
+		*stream = original
+		writer.Close()
+		return <-output
+	}
+}
+
+// withOutputFormats returns a case for each output format, named with the
+// format as suffix, table excluded.
+func withOutputFormats(name string, args []string, formats ...string) []goldenCase {
+	cases := []goldenCase{{name: name, args: args}}
+	for _, format := range formats {
+		cases = append(cases, goldenCase{
+			name: fmt.Sprintf("%s-%s", name, format),
+			args: append(append([]string{}, args...), "-o", format),
+		})
+	}
+	return cases
+}
+
+func TestGolden(t *testing.T) {
+	contextsConfig := filepath.Join("testdata", "golden", "contexts.yaml")
+	manifest := filepath.Join("testdata", "golden", "deploy.yaml")
+	auditLog := filepath.Join("testdata", "golden", "audit.log")
+	pluginsPath := map[string]string{"PATH": filepath.Join("testdata", "golden", "plugins")}
+
+	var cases []goldenCase
+	for _, list := range []struct {
+		name string
+		args []string
+	}{
+		{name: "get-projects", args: []string{"get", "projects"}},
+		{name: "get-projects-company", args: []string{"get", "projects", "--company", "company-2"}},
+		{name: "get-deployments", args: []string{"get", "deployments", "--project", "project-id"}},
+		{name: "get-companies", args: []string{"get", "companies"}},
+		{name: "get-marketplace", args: []string{"get", "marketplace"}},
+		{name: "members-list", args: []string{"members", "list", "--project", "project-id"}},
+		{name: "api-keys-list", args: []string{"api-keys", "list", "--project", "project-id"}},
+		{name: "deploy-compare", args: []string{"deploy", "compare", "--project", "project-id", "--baseline", "production"}},
+		{name: "config-view", args: []string{"config", "view", "--config", filepath.Join("testdata", "golden", "config.yaml"), "--project", "project-id"}},
+		{name: "alias-list", args: []string{"alias", "list", "--config", contextsConfig}},
+		{name: "plugin-list", args: []string{"plugin", "list"}},
+	} {
+		cases = append(cases, withOutputFormats(list.name, list.args, "json", "yaml")...)
+	}
+	for i := range cases {
+		if strings.HasPrefix(cases[i].name, "plugin-") {
+			cases[i].env = pluginsPath
+		}
+	}
+	for _, test := range withOutputFormats("audit-show", []string{"audit", "show", "--since", "2020-04-24"}, "json", "yaml") {
+		test.auditLog = auditLog
+		cases = append(cases, test)
+	}
+	cases = append(cases, withOutputFormats("deploy-batch", []string{"deploy", "batch", "--projects", "project-id,other-project", "--env", "staging,production", "--ref", "v1.3.0", "--workers", "1", "--yes"}, "json", "yaml")...)
+	cases = append(cases, withOutputFormats("report-deployments", []string{"report", "deployments", "--project", "project-id", "--since", "2020-04-20"}, "json", "yaml", "csv")...)
+
+	failingMock := sdk.MockClientError{ProjectsError: sdk.ErrGeneric}
+	cases = append(cases,
+		goldenCase{name: "get-projects-error", args: []string{"get", "projects"}, mock: &failingMock},
+		goldenCase{name: "get-projects-invalid-output", args: []string{"get", "projects", "-o", "xml"}},
+		goldenCase{name: "get-deployments-without-project", args: []string{"get", "deployments"}},
+		goldenCase{name: "get-deployments-watch-json", args: []string{"get", "deployments", "--project", "project-id", "--watch", "-o", "json"}},
+		goldenCase{name: "deploy-promote", args: []string{"deploy", "promote", "--project", "project-id", "--from", "staging", "--to", "production", "--yes"}},
+		goldenCase{name: "deploy-promote-aborted", args: []string{"deploy", "promote", "--project", "project-id", "--from", "staging", "--to", "production"}, input: "n\n"},
+		goldenCase{name: "deploy-rollback", args: []string{"deploy", "rollback", "--project", "project-id", "--env", "staging", "--reason", "broken login", "--yes"}},
+		goldenCase{name: "service-create", args: []string{"service", "create", "--project", "project-id", "--from-template", "node-template", "--name", "my-service"}},
+		goldenCase{name: "project-create", args: []string{"project", "create", "--name", "My Project", "--id", "my-project", "--company", "company-1", "--environment", "development,production:Production", "--cluster-host", "cluster.example.com"}},
+		goldenCase{name: "members-add", args: []string{"members", "add", "--project", "project-id", "--email", "new.user@example.com", "--role", "guest"}},
+		goldenCase{name: "members-remove", args: []string{"members", "remove", "--project", "project-id", "--email", "jane.doe@example.com"}},
+		goldenCase{name: "members-set-role", args: []string{"members", "set-role", "--project", "project-id", "--email", "jane.doe@example.com", "--role", "maintainer"}},
+		goldenCase{name: "api-keys-create", args: []string{"api-keys", "create", "--project", "project-id", "--name", "ci", "--role", "developer"}},
+		goldenCase{name: "api-keys-rotate", args: []string{"api-keys", "rotate", "key-1", "--project", "project-id", "--yes"}},
+		goldenCase{name: "api-keys-delete", args: []string{"api-keys", "delete", "key-1", "--project", "project-id", "--yes"}},
+		goldenCase{name: "context-list", args: []string{"context", "list", "--config", contextsConfig}},
+		goldenCase{name: "alias-run", args: []string{"--config", contextsConfig, "prod-deploys"}},
+		goldenCase{name: "audit-show-filtered", args: []string{"audit", "show", "--command", "members", "--result", "error"}, auditLog: auditLog},
+		goldenCase{name: "apply-dry-run", args: []string{"apply", "-f", manifest, "--dry-run"}},
+		goldenCase{name: "apply", args: []string{"apply", "-f", manifest, "--force", "--yes"}},
+		goldenCase{name: "apply-aborted", args: []string{"apply", "-f", manifest}, input: "n\n"},
+		goldenCase{name: "deploy-batch-aborted", args: []string{"deploy", "batch", "--selector", "*", "--env", "production", "--ref", "v1.3.0"}, input: "n\n"},
+	)
+
+	for _, test := range cases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			runGolden(t, test)
+		})
+	}
+}
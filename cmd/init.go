@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const projectConfigFileName = ".miactl.yaml"
+
+var errProjectConfigExists = errors.New("Project config file already exists")
+
+// projectConfig holds the defaults of the project config file found walking
+// up from the working directory, or nil if there is none. It is kept apart
+// from the user config, so that it is never written in it.
+var projectConfig *viper.Viper
+
+// findProjectConfig returns the path of the project config file in dir or in
+// its nearest parent, or an empty string if there is none.
+func findProjectConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, projectConfigFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readProjectConfig reads the project config file for the working directory.
+func readProjectConfig() (*viper.Viper, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	path := findProjectConfig(dir)
+	if path == "" {
+		return nil, nil
+	}
+
+	config := viper.New()
+	config.SetConfigFile(path)
+	if err := config.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func newInitCmd() *cobra.Command {
+	var (
+		env   string
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the project config file in the current directory",
+		Long: `Create the .miactl.yaml project config file in the current directory.
+
+The file sets the default project, environment and output of the commands run in
+the directory and its subdirectories. The values of the missing flags are asked
+interactively.`,
+		Example: `  # map the repository to its console project
+  miactl init --project my-project --env development`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		// overrides the root hook: the defaults to write are the passed
+		// flags only.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, projectConfigFileName)
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("%w: %s, use --force to overwrite it", errProjectConfigExists, path)
+			}
+
+			p := newPrompter(cmd)
+			if projectID == "" {
+				projectID = p.ask("Project id", "")
+			}
+			if projectID == "" {
+				return fmt.Errorf("%w: project id", errMissingValue)
+			}
+			if !cmd.Flags().Changed("env") {
+				env = p.ask("Default environment (empty for none)", "")
+			}
+			if !cmd.Flags().Changed("output") {
+				outputFormat = p.ask("Output format", outputFormat)
+			}
+			if err := checkOutputFormat("table", "json", "yaml", "csv"); err != nil {
+				return err
+			}
+
+			config := viper.New()
+			config.Set("project", projectID)
+			if env != "" {
+				config.Set("environment", env)
+			}
+			config.Set("output", outputFormat)
+			if err := config.WriteConfigAs(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Project config file %s created\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "default environment id")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite the existing project config file")
+	return cmd
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+// setupWorkingDir changes the working directory to a new temporary one,
+// resolved from symlinks so that it matches os.Getwd.
+func setupWorkingDir(t *testing.T) (string, func()) {
+	t.Helper()
+	previous, err := os.Getwd()
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "miactl-project")
+	require.NoError(t, err)
+	dir, err = filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	return dir, func() {
+		os.Chdir(previous)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	dir, cleanup := setupWorkingDir(t)
+	defer cleanup()
+	nested := filepath.Join(dir, "service", "src")
+	require.NoError(t, os.MkdirAll(nested, 0700))
+
+	require.Equal(t, "", findProjectConfig(nested))
+
+	path := filepath.Join(dir, projectConfigFileName)
+	require.NoError(t, ioutil.WriteFile(path, []byte("project: my-project\n"), 0600))
+	require.Equal(t, path, findProjectConfig(nested))
+	require.Equal(t, path, findProjectConfig(dir))
+}
+
+func TestInit(t *testing.T) {
+	t.Run("creates the project config file from prompts", func(t *testing.T) {
+		dir, cleanup := setupWorkingDir(t)
+		defer cleanup()
+
+		out, err := executeRootCommandWithInput(sdk.MockClientError{}, "my-project\ndevelopment\n\n", "init")
+		require.NoError(t, err)
+		path := filepath.Join(dir, projectConfigFileName)
+		require.Equal(t, fmt.Sprintf("Project id: Default environment (empty for none): Output format [table]: Project config file %s created\n", path), out)
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "environment: development\noutput: table\nproject: my-project\n", string(content))
+	})
+
+	t.Run("does not overwrite the file without force", func(t *testing.T) {
+		dir, cleanup := setupWorkingDir(t)
+		defer cleanup()
+		path := filepath.Join(dir, projectConfigFileName)
+		require.NoError(t, ioutil.WriteFile(path, []byte("project: old-project\n"), 0600))
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "init", "--project=new-project", "--env=", "-o=json")
+		require.EqualError(t, err, fmt.Sprintf("%s: %s, use --force to overwrite it", errProjectConfigExists, path))
+
+		_, err = executeRootCommandWithContext(sdk.MockClientError{}, "init", "--project=new-project", "--env=", "-o=json", "--force")
+		require.NoError(t, err)
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "output: json\nproject: new-project\n", string(content))
+	})
+
+	t.Run("returns error on invalid output", func(t *testing.T) {
+		_, cleanup := setupWorkingDir(t)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "init", "--project=my-project", "--env=", "-o=xml")
+		require.EqualError(t, err, fmt.Sprintf("%s: xml", errInvalidOutputFormat))
+	})
+}
+
+func TestProjectConfigDefaults(t *testing.T) {
+	dir, cleanup := setupWorkingDir(t)
+	defer cleanup()
+	configPath, configCleanup := setupConfigFile(t, `current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    apiKey: context-key
+    apiCookie: sid=context-cookie
+    project: context-project
+`)
+	defer configCleanup()
+	path := filepath.Join(dir, projectConfigFileName)
+	require.NoError(t, ioutil.WriteFile(path, []byte("project: repo-project\nenvironment: production\napiKey: committed-key\napiBaseUrl: https://attacker/\n"), 0600))
+	nested := filepath.Join(dir, "src")
+	require.NoError(t, os.Mkdir(nested, 0700))
+	require.NoError(t, os.Chdir(nested))
+
+	t.Run("overrides the current context", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "config", "view", "--config", configPath)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Contains(t, rows, fmt.Sprintf("project | repo-project | project file %s", path))
+	})
+
+	t.Run("does not set the console and the credentials", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "config", "view", "--config", configPath)
+		require.NoError(t, err)
+
+		rows := renderer.CleanTableRows(out)
+		require.Contains(t, rows, "apiBaseUrl | https://prod/ | context prod")
+		require.Contains(t, rows, "apiKey | ******** | context prod")
+	})
+
+	t.Run("sets the default environment", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: []sdk.DeployItem{},
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				require.Equal(t, "repo-project", query.ProjectID)
+			},
+		}, "deploy", "rollback", "--config", configPath, "--yes")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s: production\n", errNoSuccessfulDeploy), out)
+	})
+}
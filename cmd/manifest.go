@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	manifestAPIVersion = "miactl/v1"
+	manifestKindDeploy = "Deploy"
+
+	defaultManifestTimeout = 15 * time.Minute
+)
+
+// notificationTriggered is the event sent when the deploy pipeline starts,
+// the other events are the final pipeline statuses.
+const notificationTriggered = "triggered"
+
+var (
+	manifestDeployTypes        = []string{"smart_deploy", "deploy_all"}
+	manifestNotificationEvents = []string{notificationTriggered, "success", "failed", "canceled"}
+)
+
+var errInvalidManifest = errors.New("Invalid manifest")
+
+// deployManifest describes a deploy of a project environment. The manifests
+// are YAML documents, validated by validate:
+//
+//	apiVersion: miactl/v1
+//	kind: Deploy
+//	project: my-project
+//	environment: production
+//	ref: v1.2.0
+//	deployType: smart_deploy
+//	wait: true
+//	timeout: 10m
+//	notifications:
+//	  - webhook: https://hooks.example.com/deploys
+//	    on: [success, failed]
+type deployManifest struct {
+	APIVersion              string                 `yaml:"apiVersion" json:"apiVersion"`
+	Kind                    string                 `yaml:"kind" json:"kind"`
+	Project                 string                 `yaml:"project" json:"project"`
+	Environment             string                 `yaml:"environment" json:"environment"`
+	Ref                     string                 `yaml:"ref" json:"ref"`
+	DeployType              string                 `yaml:"deployType,omitempty" json:"deployType,omitempty"`
+	ForceDeployWhenNoSemver bool                   `yaml:"forceDeployWhenNoSemver,omitempty" json:"forceDeployWhenNoSemver,omitempty"`
+	Wait                    bool                   `yaml:"wait,omitempty" json:"wait,omitempty"`
+	Timeout                 string                 `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Notifications           []manifestNotification `yaml:"notifications,omitempty" json:"notifications,omitempty"`
+
+	// source identifies the document in the error messages
+	source  string
+	timeout time.Duration
+}
+
+// manifestNotification is a webhook receiving the deploy events in on, or
+// all of them if on is empty.
+type manifestNotification struct {
+	Webhook string   `yaml:"webhook" json:"webhook"`
+	On      []string `yaml:"on,omitempty" json:"on,omitempty"`
+}
+
+// notifies reports whether the event is sent to the webhook.
+func (n manifestNotification) notifies(event string) bool {
+	return len(n.On) == 0 || contains(n.On, event)
+}
+
+// readManifests decodes the YAML documents of r, skipping the empty ones.
+// Unknown fields are rejected.
+func readManifests(r io.Reader, name string) ([]deployManifest, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.SetStrict(true)
+
+	var manifests []deployManifest
+	for document := 1; ; document++ {
+		var manifest *deployManifest
+		err := decoder.Decode(&manifest)
+		if err == io.EOF {
+			return manifests, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s document %d: %s", errInvalidManifest, name, document, err)
+		}
+		if manifest == nil {
+			continue
+		}
+		manifest.source = fmt.Sprintf("%s document %d", name, document)
+		manifests = append(manifests, *manifest)
+	}
+}
+
+// validateManifests validates each manifest and returns an error listing
+// all the problems found.
+func validateManifests(manifests []deployManifest) error {
+	var problems []string
+	for i := range manifests {
+		for _, problem := range manifests[i].validate() {
+			problems = append(problems, fmt.Sprintf("  %s: %s", manifests[i].source, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w:\n%s", errInvalidManifest, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// validate checks the manifest against its schema and parses the timeout.
+func (m *deployManifest) validate() []string {
+	var problems []string
+	if m.APIVersion != manifestAPIVersion {
+		problems = append(problems, fmt.Sprintf("apiVersion must be %s", manifestAPIVersion))
+	}
+	if m.Kind != manifestKindDeploy {
+		problems = append(problems, fmt.Sprintf("kind must be %s", manifestKindDeploy))
+	}
+	for _, field := range []struct{ name, value string }{
+		{"project", m.Project},
+		{"environment", m.Environment},
+		{"ref", m.Ref},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			problems = append(problems, fmt.Sprintf("%s is required", field.name))
+		}
+	}
+	if m.DeployType != "" && !contains(manifestDeployTypes, m.DeployType) {
+		problems = append(problems, fmt.Sprintf("deployType must be one of %s", strings.Join(manifestDeployTypes, ", ")))
+	}
+
+	m.timeout = defaultManifestTimeout
+	if m.Timeout != "" {
+		timeout, err := time.ParseDuration(m.Timeout)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("timeout %q is not a duration", m.Timeout))
+		case timeout <= 0:
+			problems = append(problems, "timeout must be positive")
+		case !m.Wait:
+			problems = append(problems, "timeout requires wait")
+		default:
+			m.timeout = timeout
+		}
+	}
+
+	for i, notification := range m.Notifications {
+		if webhook, err := url.Parse(notification.Webhook); err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			problems = append(problems, fmt.Sprintf("notifications[%d].webhook must be an http or https url", i))
+		}
+		for _, event := range notification.On {
+			if !contains(manifestNotificationEvents, event) {
+				problems = append(problems, fmt.Sprintf("notifications[%d].on must contain only %s", i, strings.Join(manifestNotificationEvents, ", ")))
+				break
+			}
+		}
+	}
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	errInvalidScope        = errors.New("Invalid members scope")
+	errInvalidRole         = errors.New("Invalid role")
+	errInvalidMembersFile  = errors.New("Invalid members file")
+	errMemberNotFound      = errors.New("Member not found")
+	errMemberChangesFailed = errors.New("Some member changes failed")
+)
+
+var memberRoles = []string{
+	sdk.RoleGuest,
+	sdk.RoleReporter,
+	sdk.RoleDeveloper,
+	sdk.RoleMaintainer,
+	sdk.RoleProjectAdmin,
+	sdk.RoleCompanyOwner,
+}
+
+// memberChange is a single row of a members operation: the role is not used
+// when removing members.
+type memberChange struct {
+	Email string
+	Role  string
+}
+
+// membersOptions holds the flags shared by the members subcommands.
+type membersOptions struct {
+	scope string
+	email string
+	role  string
+	file  string
+}
+
+// newMembersCmd creates the command grouping the members operations
+func newMembersCmd() *cobra.Command {
+	var options membersOptions
+
+	membersCmd := &cobra.Command{
+		Use:   "members",
+		Short: "Manage the members of a project or a company",
+		Long: `Manage the members of a project or a company.
+
+The members of the project are managed when --project is set, otherwise the
+ones of the company. A --project or --company passed on the command line is
+preferred to the one of the context or the environment. Use --scope to choose
+explicitly, required when both are passed.`,
+	}
+	membersCmd.PersistentFlags().StringVar(&options.scope, "scope", "", "members to manage: project or company")
+
+	membersCmd.AddCommand(newMembersListCmd(&options))
+	membersCmd.AddCommand(newMembersAddCmd(&options))
+	membersCmd.AddCommand(newMembersRemoveCmd(&options))
+	membersCmd.AddCommand(newMembersSetRoleCmd(&options))
+	return membersCmd
+}
+
+func newMembersListCmd(options *membersOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the members",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scope, err := membersScope(cmd.Flags(), options.scope)
+			if err != nil {
+				return err
+			}
+			if err := checkOutputFormat("table", "json", "yaml"); err != nil {
+				return err
+			}
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			members, err := f.MiaClient.Members.List(scope)
+			if err != nil {
+				f.Renderer.Error(err).Render()
+				return nil
+			}
+			if renderStructured(f, members) {
+				return nil
+			}
+			table := f.Renderer.Table([]string{"#", "Name", "Email", "Role"})
+			for i, member := range members {
+				table.Append([]string{strconv.Itoa(i + 1), member.Name, member.Email, member.Role})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
+
+func newMembersAddCmd(options *membersOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add users as members",
+		Example: `  # add a developer to the project
+  miactl members add --project my-project --email jane.doe@example.com --role developer
+
+  # add the users listed in a csv file, with email and role columns
+  miactl members add --company my-company --file members.csv`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMembersChange(cmd, options, true, func(f *Factory, scope sdk.MembersScope, _ sdk.Members, change memberChange) (string, error) {
+				if _, err := f.MiaClient.Members.Add(scope, sdk.AddMemberRequest{Email: change.Email, Role: change.Role}); err != nil {
+					return "", err
+				}
+				return "added", nil
+			})
+		},
+	}
+	addMemberChangeFlags(cmd, options, true)
+	return cmd
+}
+
+func newMembersRemoveCmd(options *membersOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove members",
+		Example: `  # remove a user from the project
+  miactl members remove --project my-project --email jane.doe@example.com`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMembersChange(cmd, options, false, func(f *Factory, scope sdk.MembersScope, members sdk.Members, change memberChange) (string, error) {
+				member, err := findMember(members, change.Email)
+				if err != nil {
+					return "", err
+				}
+				if err := f.MiaClient.Members.Remove(scope, member.ID); err != nil {
+					return "", err
+				}
+				return "removed", nil
+			})
+		},
+	}
+	addMemberChangeFlags(cmd, options, false)
+	return cmd
+}
+
+func newMembersSetRoleCmd(options *membersOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-role",
+		Short: "Change the role of members",
+		Example: `  # promote a user to maintainer of the project
+  miactl members set-role --project my-project --email jane.doe@example.com --role maintainer`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMembersChange(cmd, options, true, func(f *Factory, scope sdk.MembersScope, members sdk.Members, change memberChange) (string, error) {
+				member, err := findMember(members, change.Email)
+				if err != nil {
+					return "", err
+				}
+				if err := f.MiaClient.Members.SetRole(scope, member.ID, change.Role); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("role changed from %s", member.Role), nil
+			})
+		},
+	}
+	addMemberChangeFlags(cmd, options, true)
+	return cmd
+}
+
+func addMemberChangeFlags(cmd *cobra.Command, options *membersOptions, withRole bool) {
+	cmd.Flags().StringVar(&options.email, "email", "", "email of the user")
+	if withRole {
+		cmd.Flags().StringVar(&options.role, "role", "", fmt.Sprintf("role of the user, one of %s", strings.Join(memberRoles, ", ")))
+	}
+	cmd.Flags().StringVarP(&options.file, "file", "f", "", "csv file with a row for each user, with email and role columns")
+}
+
+// runMembersChange applies apply to each change read from the flags or the
+// file and prints the result of each one. A failed change does not stop the
+// following ones, but makes the command fail.
+func runMembersChange(cmd *cobra.Command, options *membersOptions, withRole bool, apply func(*Factory, sdk.MembersScope, sdk.Members, memberChange) (string, error)) error {
+	scope, err := membersScope(cmd.Flags(), options.scope)
+	if err != nil {
+		return err
+	}
+	changes, err := readMemberChanges(options)
+	if err != nil {
+		return err
+	}
+
+	f, err := GetFactoryFromContext(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	members, err := f.MiaClient.Members.List(scope)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	var failed int
+	table := f.Renderer.Table([]string{"Email", "Role", "Result"})
+	for _, change := range changes {
+		var result string
+		err := validateMemberChange(change, withRole)
+		if err == nil {
+			result, err = apply(f, scope, members, change)
+		}
+		if err != nil {
+			failed++
+			result = err.Error()
+		}
+		table.Append([]string{change.Email, change.Role, result})
+	}
+	table.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", errMemberChangesFailed, failed, len(changes))
+	}
+	return nil
+}
+
+// membersScope returns the project or company whose members are managed.
+// Without scope, the one passed on the command line is preferred to the one
+// set by the other config sources, and the project to the company.
+func membersScope(flags *pflag.FlagSet, scope string) (sdk.MembersScope, error) {
+	switch scope {
+	case "":
+		projectFlag, companyFlag := flagFromCommandLine(flags, "project"), flagFromCommandLine(flags, "company")
+		switch {
+		case projectFlag && companyFlag:
+			return sdk.MembersScope{}, fmt.Errorf("%w: --scope is required with both --project and --company", errInvalidScope)
+		case companyFlag:
+			return sdk.MembersScope{CompanyID: companyID}, nil
+		}
+		if projectID != "" {
+			return sdk.MembersScope{ProjectID: projectID}, nil
+		}
+		if companyID != "" {
+			return sdk.MembersScope{CompanyID: companyID}, nil
+		}
+		return sdk.MembersScope{}, fmt.Errorf("%w: --project or --company is required", errInvalidScope)
+	case "project":
+		if projectID == "" {
+			return sdk.MembersScope{}, fmt.Errorf("%w: --project is required", errInvalidScope)
+		}
+		return sdk.MembersScope{ProjectID: projectID}, nil
+	case "company":
+		if companyID == "" {
+			return sdk.MembersScope{}, fmt.Errorf("%w: --company is required", errInvalidScope)
+		}
+		return sdk.MembersScope{CompanyID: companyID}, nil
+	default:
+		return sdk.MembersScope{}, fmt.Errorf("%w: %s", errInvalidScope, scope)
+	}
+}
+
+func readMemberChanges(options *membersOptions) ([]memberChange, error) {
+	if options.file == "" {
+		if options.email == "" {
+			return nil, fmt.Errorf("%w: --email or --file", errMissingValue)
+		}
+		return []memberChange{{Email: options.email, Role: options.role}}, nil
+	}
+	if options.email != "" {
+		return nil, errors.New("--email and --file can not be used together")
+	}
+
+	file, err := os.Open(options.file)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseMemberChanges(file)
+}
+
+// parseMemberChanges reads a csv with the email and, optionally, the role of
+// each user. A first row with the email header is skipped.
+func parseMemberChanges(r io.Reader) ([]memberChange, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidMembersFile, err)
+	}
+
+	changes := []memberChange{}
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+			continue
+		}
+		if len(record) > 2 {
+			return nil, fmt.Errorf("%w: line %d has more than 2 columns", errInvalidMembersFile, i+1)
+		}
+		change := memberChange{Email: strings.TrimSpace(record[0])}
+		if len(record) == 2 {
+			change.Role = strings.TrimSpace(record[1])
+		}
+		if change.Email == "" {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("%w: no users found", errInvalidMembersFile)
+	}
+	return changes, nil
+}
+
+func validateMemberChange(change memberChange, withRole bool) error {
+	if !withRole {
+		return nil
+	}
+	if change.Role == "" {
+		return fmt.Errorf("%w: role", errMissingValue)
+	}
+	return validateRole(change.Role)
+}
+
+func validateRole(role string) error {
+	for _, valid := range memberRoles {
+		if role == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errInvalidRole, role)
+}
+
+func findMember(members sdk.Members, email string) (sdk.Member, error) {
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			return member, nil
+		}
+	}
+	return sdk.Member{}, fmt.Errorf("%w: %s", errMemberNotFound, email)
+}
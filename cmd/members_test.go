@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+var membersTestMembers = sdk.Members{
+	{ID: "user-1", Name: "Jane Doe", Email: "jane.doe@example.com", Role: sdk.RoleMaintainer},
+	{ID: "user-2", Name: "John Doe", Email: "john.doe@example.com", Role: sdk.RoleDeveloper},
+}
+
+func TestMembersList(t *testing.T) {
+	t.Run("lists project members", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Members: membersTestMembers,
+		}, "members", "list", "--project=project-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"# | NAME | EMAIL | ROLE",
+			"1 | Jane Doe | jane.doe@example.com | maintainer",
+			"2 | John Doe | john.doe@example.com | developer",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("renders sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			MembersError: sdk.ErrHTTP,
+		}, "members", "list", "--company=company-id", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s\n", sdk.ErrHTTP), out)
+	})
+
+	t.Run("prefers the company passed on the command line to the project of the environment", func(t *testing.T) {
+		os.Setenv("MIACTL_PROJECT", "env-project")
+		defer os.Unsetenv("MIACTL_PROJECT")
+
+		var scopes []sdk.MembersScope
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			Members: membersTestMembers,
+			MemberAddAssertFn: func(scope sdk.MembersScope, request sdk.AddMemberRequest) {
+				scopes = append(scopes, scope)
+			},
+		}, "members", "add", "--company=company-id", "--email=new@example.com", "--role=developer", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, []sdk.MembersScope{{CompanyID: "company-id"}}, scopes)
+	})
+
+	t.Run("returns error without project and company", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "members", "list", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, fmt.Sprintf("%s: --project or --company is required", errInvalidScope))
+	})
+}
+
+func TestMembersScope(t *testing.T) {
+	defer func() { projectID, companyID = "", "" }()
+	flags := pflag.NewFlagSet("members", pflag.ContinueOnError)
+	flags.String("project", "", "")
+	flags.String("company", "", "")
+
+	projectID, companyID = "project-id", "company-id"
+	scope, err := membersScope(flags, "")
+	require.NoError(t, err)
+	require.Equal(t, sdk.MembersScope{ProjectID: "project-id"}, scope)
+
+	scope, err = membersScope(flags, "company")
+	require.NoError(t, err)
+	require.Equal(t, sdk.MembersScope{CompanyID: "company-id"}, scope)
+
+	t.Run("prefers the flag passed on the command line", func(t *testing.T) {
+		flags := pflag.NewFlagSet("members", pflag.ContinueOnError)
+		flags.String("project", "", "")
+		flags.String("company", "", "")
+		require.NoError(t, flags.Set("project", "project-id"))
+		require.NoError(t, flags.SetAnnotation("project", configSourceAnnotation, []string{"context prod"}))
+		require.NoError(t, flags.Set("company", "company-id"))
+		scope, err := membersScope(flags, "")
+		require.NoError(t, err)
+		require.Equal(t, sdk.MembersScope{CompanyID: "company-id"}, scope)
+
+		flags.Lookup("project").Annotations = nil
+		_, err = membersScope(flags, "")
+		require.EqualError(t, err, fmt.Sprintf("%s: --scope is required with both --project and --company", errInvalidScope))
+
+		scope, err = membersScope(flags, "project")
+		require.NoError(t, err)
+		require.Equal(t, sdk.MembersScope{ProjectID: "project-id"}, scope)
+	})
+
+	projectID = ""
+	_, err = membersScope(flags, "project")
+	require.EqualError(t, err, fmt.Sprintf("%s: --project is required", errInvalidScope))
+
+	_, err = membersScope(flags, "team")
+	require.EqualError(t, err, fmt.Sprintf("%s: team", errInvalidScope))
+}
+
+func TestMembersChange(t *testing.T) {
+	t.Run("adds member", func(t *testing.T) {
+		var added []sdk.AddMemberRequest
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			MemberAddAssertFn: func(scope sdk.MembersScope, request sdk.AddMemberRequest) {
+				require.Equal(t, sdk.MembersScope{ProjectID: "project-id"}, scope)
+				added = append(added, request)
+			},
+		}, "members", "add", "--project=project-id", "--email=new@example.com", "--role=developer", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, []sdk.AddMemberRequest{{Email: "new@example.com", Role: "developer"}}, added)
+		require.Equal(t, []string{
+			"EMAIL | ROLE | RESULT",
+			"new@example.com | developer | added",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("removes member by email", func(t *testing.T) {
+		var removed []string
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Members: membersTestMembers,
+			MemberRemoveAssertFn: func(scope sdk.MembersScope, memberID string) {
+				require.Equal(t, sdk.MembersScope{CompanyID: "company-id"}, scope)
+				removed = append(removed, memberID)
+			},
+		}, "members", "remove", "--company=company-id", "--email=John.Doe@example.com", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Equal(t, []string{"user-2"}, removed)
+		require.Equal(t, []string{
+			"EMAIL | ROLE | RESULT",
+			"John.Doe@example.com |  | removed",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("sets role of members from csv file and reports failures", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "members-*.csv")
+		require.NoError(t, err)
+		defer os.Remove(file.Name())
+		_, err = file.WriteString("email,role\njane.doe@example.com,guest\nunknown@example.com,guest\njohn.doe@example.com,owner\n")
+		require.NoError(t, err)
+		file.Close()
+
+		type roleChange struct{ id, role string }
+		var changed []roleChange
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			Members: membersTestMembers,
+			MemberSetRoleAssertFn: func(scope sdk.MembersScope, memberID, role string) {
+				changed = append(changed, roleChange{memberID, role})
+			},
+		}, "members", "set-role", "--project=project-id", "--file", file.Name(), apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, fmt.Sprintf("%s: 2 of 3", errMemberChangesFailed))
+		require.Equal(t, []roleChange{{"user-1", "guest"}}, changed)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"EMAIL | ROLE | RESULT",
+			"jane.doe@example.com | guest | role changed from maintainer",
+			fmt.Sprintf("unknown@example.com | guest | %s: unknown@example.com", errMemberNotFound),
+			fmt.Sprintf("john.doe@example.com | owner | %s: owner", errInvalidRole),
+		}, rows[:4])
+	})
+
+	t.Run("returns error without email and file", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "members", "add", "--project=project-id", "--role=developer", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, fmt.Sprintf("%s: --email or --file", errMissingValue))
+	})
+}
+
+func TestParseMemberChanges(t *testing.T) {
+	t.Run("parses rows with and without header", func(t *testing.T) {
+		changes, err := parseMemberChanges(strings.NewReader("jane@example.com, developer\n\njohn@example.com\n"))
+		require.NoError(t, err)
+		require.Equal(t, []memberChange{
+			{Email: "jane@example.com", Role: "developer"},
+			{Email: "john@example.com"},
+		}, changes)
+
+		changes, err = parseMemberChanges(strings.NewReader("Email,Role\njane@example.com,guest\n"))
+		require.NoError(t, err)
+		require.Equal(t, []memberChange{{Email: "jane@example.com", Role: "guest"}}, changes)
+	})
+
+	t.Run("returns error on too many columns", func(t *testing.T) {
+		_, err := parseMemberChanges(strings.NewReader("jane@example.com,guest,extra\n"))
+		require.EqualError(t, err, fmt.Sprintf("%s: line 1 has more than 2 columns", errInvalidMembersFile))
+	})
+
+	t.Run("returns error on empty file", func(t *testing.T) {
+		_, err := parseMemberChanges(strings.NewReader("email,role\n"))
+		require.EqualError(t, err, fmt.Sprintf("%s: no users found", errInvalidMembersFile))
+	})
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// checkOutputFormat returns an error if the output flag is not one of the
+// formats supported by the command.
+func checkOutputFormat(formats ...string) error {
+	for _, format := range formats {
+		if outputFormat == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errInvalidOutputFormat, outputFormat)
+}
+
+// renderStructured writes v when the output format is json or yaml and
+// reports whether it did, so that the caller renders the table otherwise.
+func renderStructured(f *Factory, v interface{}) bool {
+	var err error
+	switch outputFormat {
+	case "json":
+		err = f.Renderer.JSON(v)
+	case "yaml":
+		err = f.Renderer.YAML(v)
+	default:
+		return false
+	}
+	if err != nil {
+		f.Renderer.Error(err).Render()
+	}
+	return true
+}
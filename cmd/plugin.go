@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/spf13/cobra"
+)
+
+// pluginPrefix is the prefix of the plugin executables: miactl-<name> is
+// run by miactl <name>.
+const pluginPrefix = "miactl-"
+
+// pluginPathAnnotation marks the commands running a plugin, with the path
+// of the plugin executable.
+const pluginPathAnnotation = "miactl-plugin-path"
+
+// plugin is a miactl-<name> file found in PATH. The plugins with a warning
+// can't be run by miactl.
+type plugin struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// findPlugins returns the plugins found in the directories of path, in
+// order. A plugin is shadowed by a built-in command of rootCmd with the same
+// name and by a plugin with the same name found before it.
+func findPlugins(path string, rootCmd *cobra.Command) []plugin {
+	var plugins []plugin
+	found := map[string]string{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name, executable := pluginName(file)
+			if file.IsDir() || name == "" {
+				continue
+			}
+			p := plugin{Name: name, Path: filepath.Join(dir, file.Name())}
+			switch {
+			case !executable:
+				p.Warning = "not executable"
+			case isBuiltinCommand(rootCmd, name):
+				p.Warning = fmt.Sprintf("shadowed by the built-in command %s", name)
+			case found[name] != "":
+				p.Warning = fmt.Sprintf("shadowed by %s", found[name])
+			default:
+				found[name] = p.Path
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// pluginName returns the name of the plugin of file, empty if it is not a
+// plugin, and whether the file is executable.
+func pluginName(file os.FileInfo) (string, bool) {
+	fileName := file.Name()
+	if !strings.HasPrefix(fileName, pluginPrefix) {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := filepath.Ext(fileName)
+		return strings.TrimSuffix(strings.TrimPrefix(fileName, pluginPrefix), ext), strings.EqualFold(ext, ".exe")
+	}
+	return strings.TrimPrefix(fileName, pluginPrefix), file.Mode()&0111 != 0
+}
+
+func isBuiltinCommand(rootCmd *cobra.Command, name string) bool {
+	// the help command is added by cobra on execution
+	if name == "help" {
+		return true
+	}
+	for _, cmd := range rootCmd.Commands() {
+		if _, isPlugin := cmd.Annotations[pluginPathAnnotation]; isPlugin {
+			continue
+		}
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addPluginCmds adds to rootCmd a command running each plugin without
+// warnings.
+func addPluginCmds(rootCmd *cobra.Command, plugins []plugin) {
+	for _, p := range plugins {
+		if p.Warning == "" {
+			rootCmd.AddCommand(newPluginRunCmd(p))
+		}
+	}
+}
+
+func newPluginRunCmd(p plugin) *cobra.Command {
+	return &cobra.Command{
+		Use:         p.Name,
+		Short:       fmt.Sprintf("Run the %s plugin", filepath.Base(p.Path)),
+		Annotations: map[string]string{pluginPathAnnotation: p.Path},
+		// the flags and args are the plugin ones
+		DisableFlagParsing: true,
+		// the plugin prints its own errors
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		// overrides the root hook: the configuration is passed to the plugin
+		// in the environment.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := pluginEnv(cmd)
+			if err != nil {
+				return err
+			}
+			plugin := exec.CommandContext(cmd.Context(), p.Path, args...)
+			plugin.Env = env
+			plugin.Stdin = cmd.InOrStdin()
+			plugin.Stdout = cmd.OutOrStdout()
+			plugin.Stderr = cmd.ErrOrStderr()
+			return plugin.Run()
+		},
+	}
+}
+
+// pluginEnv returns the environment of miactl with the configuration
+// resolved from the environment, the project config file, the current
+// context and the config file set in the MIACTL_* variables.
+func pluginEnv(cmd *cobra.Command) ([]string, error) {
+	values, err := resolveConfig(cmd.InheritedFlags())
+	if err != nil {
+		return nil, err
+	}
+	envs := map[string]string{}
+	for _, key := range configKeys {
+		envs[key.key] = key.env
+	}
+
+	env := os.Environ()
+	for _, value := range values {
+		if value.Source == "default" {
+			continue
+		}
+		env = append(env, fmt.Sprintf("%s=%s", envs[value.Key], value.Value))
+	}
+	return env, nil
+}
+
+func newPluginCmd() *cobra.Command {
+	pluginCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Inspect the miactl plugins",
+		Long: `Inspect the miactl plugins.
+
+A plugin is an executable named miactl-<name> found in PATH, which is run by
+miactl <name>. The other args, flags included, are passed to the plugin as they
+are, while the configuration of miactl, like the api base url, the credentials
+and the project, is passed in the MIACTL_* environment variables.`,
+		// overrides the root hook: the plugins don't connect to a console.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	pluginCmd.AddCommand(newPluginListCmd())
+	return pluginCmd
+}
+
+func newPluginListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins found in PATH",
+		Long: `List the plugins found in PATH.
+
+A plugin can't be run if it is not executable, if a built-in command has its
+name or if a plugin with the same name comes before it in PATH.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkOutputFormat("table", "json", "yaml")
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugins := findPlugins(os.Getenv("PATH"), cmd.Root())
+
+			out := cmd.OutOrStdout()
+			switch outputFormat {
+			case "json":
+				return renderer.NewJSON(out, plugins)
+			case "yaml":
+				return renderer.NewYAML(out, plugins)
+			}
+			if len(plugins) == 0 {
+				fmt.Fprintf(out, "No %s* plugin found in PATH\n", pluginPrefix)
+				return nil
+			}
+			table := renderer.NewTable(out, []string{"Name", "Path", "Warning"})
+			for _, p := range plugins {
+				table.Append([]string{p.Name, p.Path, p.Warning})
+			}
+			table.Render()
+			return nil
+		},
+	}
+}
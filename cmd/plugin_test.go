@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPluginPath sets PATH to two directories with the plugin scripts of
+// first and second, by name.
+func setupPluginPath(t *testing.T, first, second map[string]string) ([]string, func()) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts are not executable on windows")
+	}
+	root, err := ioutil.TempDir("", "miactl-plugins")
+	require.NoError(t, err)
+
+	var dirs []string
+	for i, scripts := range []map[string]string{first, second} {
+		dir := filepath.Join(root, fmt.Sprintf("bin%d", i))
+		require.NoError(t, os.Mkdir(dir, 0755))
+		for name, script := range scripts {
+			mode := os.FileMode(0755)
+			if script == "" {
+				mode = 0644
+			}
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), mode))
+		}
+		dirs = append(dirs, dir)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+	return dirs, func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(root)
+	}
+}
+
+func TestFindPlugins(t *testing.T) {
+	dirs, cleanup := setupPluginPath(t, map[string]string{
+		"miactl-hello": "echo hello",
+		"miactl-get":   "echo get",
+		"miactl-notes": "",
+		"other-tool":   "echo other",
+	}, map[string]string{
+		"miactl-hello": "echo shadowed",
+		"miactl-world": "echo world",
+	})
+	defer cleanup()
+
+	plugins := findPlugins(os.Getenv("PATH"), NewRootCmd())
+	require.Equal(t, []plugin{
+		{Name: "get", Path: filepath.Join(dirs[0], "miactl-get"), Warning: "shadowed by the built-in command get"},
+		{Name: "hello", Path: filepath.Join(dirs[0], "miactl-hello")},
+		{Name: "notes", Path: filepath.Join(dirs[0], "miactl-notes"), Warning: "not executable"},
+		{Name: "hello", Path: filepath.Join(dirs[1], "miactl-hello"), Warning: fmt.Sprintf("shadowed by %s", filepath.Join(dirs[0], "miactl-hello"))},
+		{Name: "world", Path: filepath.Join(dirs[1], "miactl-world")},
+	}, plugins)
+}
+
+func TestPluginCommands(t *testing.T) {
+	dirs, cleanup := setupPluginPath(t, map[string]string{
+		"miactl-hello": `echo "args: $@"
+echo "project: $MIACTL_PROJECT"
+echo "base url: $MIACTL_API_BASE_URL"
+echo "api key: $MIACTL_API_KEY"
+echo "output: $MIACTL_OUTPUT"
+`,
+		"miactl-fail": "echo failed >&2\nexit 3",
+		"miactl-get":  "echo get",
+	}, nil)
+	defer cleanup()
+
+	t.Run("runs the plugin with the resolved configuration", func(t *testing.T) {
+		configPath, cleanupConfig := setupConfigFile(t, `current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    apiKey: context-key
+    project: context-project
+`)
+		defer cleanupConfig()
+
+		rootCmd := NewRootCmd()
+		cfgFile = configPath
+		out, err := executeCommand(rootCmd, "hello", "--name", "world", "-v")
+		require.NoError(t, err)
+		require.Contains(t, out, "args: --name world -v\nproject: context-project\nbase url: https://prod/\napi key: context-key\noutput: \n")
+	})
+
+	t.Run("returns the exit code of the plugin", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		stderr := new(strings.Builder)
+		rootCmd.SetErr(stderr)
+
+		require.Equal(t, 3, executeRoot(context.Background(), rootCmd, []string{"fail"}))
+		require.Equal(t, "failed\n", stderr.String())
+	})
+
+	t.Run("runs the built-in command shadowing a plugin", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "companies", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.NotContains(t, out, "get\n")
+	})
+
+	t.Run("lists the plugins", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "plugin", "list")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"NAME | PATH | WARNING",
+			fmt.Sprintf("fail | %s", filepath.Join(dirs[0], "miactl-fail")),
+			fmt.Sprintf("get | %s | shadowed by the built-in command get", filepath.Join(dirs[0], "miactl-get")),
+			fmt.Sprintf("hello | %s", filepath.Join(dirs[0], "miactl-hello")),
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("lists the plugins as json", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "plugin", "list", "-o", "json")
+		require.NoError(t, err)
+		var plugins []plugin
+		require.NoError(t, json.Unmarshal([]byte(out), &plugins))
+		require.Len(t, plugins, 3)
+	})
+
+	t.Run("prints a message without plugins", func(t *testing.T) {
+		os.Setenv("PATH", dirs[1])
+		defer os.Setenv("PATH", strings.Join(dirs, string(os.PathListSeparator)))
+
+		out, err := executeRootCommandWithContext(sdk.MockClientError{}, "plugin", "list")
+		require.NoError(t, err)
+		require.Equal(t, "No miactl-* plugin found in PATH\n", out)
+	})
+}
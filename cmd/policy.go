@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const policyKey = "policy"
+
+var (
+	errInvalidPolicy          = errors.New("Invalid deploy policy")
+	errDeployFrozen           = errors.New("Deploy frozen")
+	errOverrideReasonRequired = errors.New("A --reason is required to override the freeze")
+	errConfirmationMismatch   = errors.New("Confirmation phrase does not match")
+)
+
+// freezeOverrideLogFile is the file where the freeze overrides are
+// appended, the default is in the .miactl folder of the home directory.
+var freezeOverrideLogFile string
+
+// deployPolicy is the deploy policy of a context, consulted before each
+// deploy is triggered:
+//
+//	policy:
+//	  timezone: Europe/Rome
+//	  freezes:
+//	    - name: weekend
+//	      schedule: "* * * * 6,0"
+//	      environments: [production]
+//	    - name: holidays
+//	      from: 2020-12-23
+//	      to: 2021-01-06
+//	  protectedEnvironments:
+//	    - environment: production
+//	      confirmation: deploy to production
+type deployPolicy struct {
+	Timezone              string                 `mapstructure:"timezone"`
+	Freezes               []freezeWindow         `mapstructure:"freezes"`
+	ProtectedEnvironments []protectedEnvironment `mapstructure:"protectedEnvironments"`
+
+	location *time.Location
+}
+
+// freezeWindow is a period when the deploys of the environments, or of all
+// of them if empty, are not allowed: the minutes matching the cron schedule
+// or the dates from the start of from to the end of to.
+type freezeWindow struct {
+	Name         string   `mapstructure:"name"`
+	Environments []string `mapstructure:"environments"`
+	Schedule     string   `mapstructure:"schedule"`
+	From         string   `mapstructure:"from"`
+	To           string   `mapstructure:"to"`
+
+	schedule cronSchedule
+	from, to time.Time
+}
+
+// protectedEnvironment is an environment whose deploys must be confirmed
+// by typing the confirmation phrase.
+type protectedEnvironment struct {
+	Environment  string `mapstructure:"environment"`
+	Confirmation string `mapstructure:"confirmation"`
+}
+
+// contextPolicy returns the name of the current context and its deploy
+// policy, empty if no context is in use.
+func contextPolicy() (string, deployPolicy, error) {
+	var policy deployPolicy
+	name := viper.GetString(currentContextKey)
+	if name == "" {
+		return "", policy, nil
+	}
+	if err := viper.UnmarshalKey(fmt.Sprintf("%s.%s.%s", contextsKey, name, policyKey), &policy); err != nil {
+		return "", policy, fmt.Errorf("%w of context %s: %s", errInvalidPolicy, name, err)
+	}
+	if problems := policy.compile(); len(problems) > 0 {
+		return "", policy, fmt.Errorf("%w of context %s:\n  %s", errInvalidPolicy, name, strings.Join(problems, "\n  "))
+	}
+	return name, policy, nil
+}
+
+// compile validates the policy and parses its timezone, schedules and
+// dates.
+func (p *deployPolicy) compile() []string {
+	var problems []string
+	p.location = time.Local
+	if p.Timezone != "" {
+		location, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("timezone %q is unknown", p.Timezone))
+		} else {
+			p.location = location
+		}
+	}
+
+	for i := range p.Freezes {
+		freeze := &p.Freezes[i]
+		if freeze.Name == "" {
+			freeze.Name = fmt.Sprintf("freezes[%d]", i)
+		}
+		switch {
+		case freeze.Schedule != "" && (freeze.From != "" || freeze.To != ""):
+			problems = append(problems, fmt.Sprintf("%s: schedule can't be used with from and to", freeze.Name))
+		case freeze.Schedule != "":
+			schedule, err := parseCronSchedule(freeze.Schedule)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: schedule %q: %s", freeze.Name, freeze.Schedule, err))
+			}
+			freeze.schedule = schedule
+		case freeze.From != "" && freeze.To != "":
+			from, fromErr := parseFreezeTime(freeze.From, p.location, false)
+			to, toErr := parseFreezeTime(freeze.To, p.location, true)
+			switch {
+			case fromErr != nil:
+				problems = append(problems, fmt.Sprintf("%s: from %q is not a date", freeze.Name, freeze.From))
+			case toErr != nil:
+				problems = append(problems, fmt.Sprintf("%s: to %q is not a date", freeze.Name, freeze.To))
+			case !to.After(from):
+				problems = append(problems, fmt.Sprintf("%s: to must follow from", freeze.Name))
+			}
+			freeze.from, freeze.to = from, to
+		default:
+			problems = append(problems, fmt.Sprintf("%s: one of schedule or from and to is required", freeze.Name))
+		}
+	}
+
+	for i, protected := range p.ProtectedEnvironments {
+		if protected.Environment == "" || strings.TrimSpace(protected.Confirmation) == "" {
+			problems = append(problems, fmt.Sprintf("protectedEnvironments[%d]: environment and confirmation are required", i))
+		}
+	}
+	return problems
+}
+
+// parseFreezeTime parses an RFC 3339 time or a date, which is the start of
+// the day or, when end is set, the end of the day.
+func parseFreezeTime(value string, location *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// activeFreeze returns the first freeze of env active at now, or nil.
+func (p deployPolicy) activeFreeze(env string, now time.Time) *freezeWindow {
+	for i := range p.Freezes {
+		freeze := &p.Freezes[i]
+		if len(freeze.Environments) > 0 && !contains(freeze.Environments, env) {
+			continue
+		}
+		if freeze.Schedule != "" {
+			if freeze.schedule.matches(now.In(p.location)) {
+				return freeze
+			}
+			continue
+		}
+		if !now.Before(freeze.from) && now.Before(freeze.to) {
+			return freeze
+		}
+	}
+	return nil
+}
+
+// confirmation returns the phrase confirming the deploys of env, empty if
+// env is not protected.
+func (p deployPolicy) confirmation(env string) string {
+	for _, protected := range p.ProtectedEnvironments {
+		if protected.Environment == env {
+			return protected.Confirmation
+		}
+	}
+	return ""
+}
+
+// cronSchedule matches the minutes of a cron expression: minute, hour, day
+// of month, month and day of week.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// the days match if either the day of month or the day of week
+	// matches, when both are restricted
+	anyDay, anyWeekday bool
+}
+
+func parseCronSchedule(expression string) (cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return cronSchedule{}, errors.New("5 fields are required: minute hour day-of-month month day-of-week")
+	}
+
+	var schedule cronSchedule
+	var err error
+	for i, field := range []struct {
+		name     string
+		min, max int
+		bits     *uint64
+	}{
+		{"minute", 0, 59, &schedule.minutes},
+		{"hour", 0, 23, &schedule.hours},
+		{"day of month", 1, 31, &schedule.days},
+		{"month", 1, 12, &schedule.months},
+		{"day of week", 0, 7, &schedule.weekdays},
+	} {
+		if *field.bits, err = parseCronField(fields[i], field.min, field.max); err != nil {
+			return cronSchedule{}, fmt.Errorf("%s: %s", field.name, err)
+		}
+	}
+	// both 0 and 7 are sunday
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay, schedule.anyWeekday = fields[2] == "*", fields[4] == "*"
+	return schedule, nil
+}
+
+// parseCronField returns the bits of the values of a comma separated list
+// of *, values and ranges, each with an optional /step.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		start, end := min, max
+		switch bounds := strings.SplitN(rangePart, "-", 2); {
+		case rangePart == "*":
+		case len(bounds) == 2:
+			var startErr, endErr error
+			start, startErr = strconv.Atoi(bounds[0])
+			end, endErr = strconv.Atoi(bounds[1])
+			if startErr != nil || endErr != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			start = value
+			// a single value with a step runs to the maximum
+			if step == 1 {
+				end = value
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", rangePart, min, max)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (s cronSchedule) matches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDay || s.anyWeekday:
+		day = day && weekday
+	default:
+		day = day || weekday
+	}
+	return day &&
+		s.minutes&(1<<uint(t.Minute())) != 0 &&
+		s.hours&(1<<uint(t.Hour())) != 0 &&
+		s.months&(1<<uint(t.Month())) != 0
+}
+
+// policyOptions are the flags overriding the deploy policy.
+type policyOptions struct {
+	overrideFreeze bool
+	reason         string
+}
+
+// addPolicyFlags adds the flags overriding the deploy policy. The reason
+// flag is added unless the command has its own one, bound to options.reason.
+func addPolicyFlags(cmd *cobra.Command, options *policyOptions) {
+	cmd.Flags().BoolVar(&options.overrideFreeze, "override-freeze", false, "deploy even if a freeze window of the context policy is active, requires --reason")
+	if cmd.Flags().Lookup("reason") == nil {
+		cmd.Flags().StringVar(&options.reason, "reason", "", "reason of the freeze override, saved in the override log")
+	}
+}
+
+// deployGate checks the deploys of a command against the policy of the
+// current context. All the questions of the command must be asked by the
+// gate, which owns the prompter.
+type deployGate struct {
+	contextName string
+	policy      deployPolicy
+	options     policyOptions
+	prompter    *prompter
+	out         io.Writer
+	// confirmed holds the protected environments already confirmed
+	confirmed map[string]bool
+}
+
+func newDeployGate(cmd *cobra.Command, options policyOptions) (*deployGate, error) {
+	name, policy, err := contextPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return &deployGate{
+		contextName: name,
+		policy:      policy,
+		options:     options,
+		prompter:    newPrompter(cmd),
+		out:         cmd.OutOrStdout(),
+		confirmed:   map[string]bool{},
+	}, nil
+}
+
+// check returns an error if the deploy of ref on env of project is not
+// allowed: env is frozen and the freeze is not overridden, or env is
+// protected and the confirmation phrase typed does not match. The phrase
+// is asked once per environment.
+func (g *deployGate) check(project, env, ref string) error {
+	if freeze := g.policy.activeFreeze(env, timeNow()); freeze != nil {
+		if !g.options.overrideFreeze {
+			return fmt.Errorf("%w: %s of %s is in the freeze %s, use --override-freeze with a --reason to deploy anyway", errDeployFrozen, env, project, freeze.Name)
+		}
+		if strings.TrimSpace(g.options.reason) == "" {
+			return fmt.Errorf("%w: %s", errOverrideReasonRequired, freeze.Name)
+		}
+		err := logFreezeOverride(freezeOverride{
+			Time:        timeNow(),
+			Context:     g.contextName,
+			User:        currentUsername(),
+			Project:     project,
+			Environment: env,
+			Ref:         ref,
+			Freeze:      freeze.Name,
+			Reason:      g.options.reason,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(g.out, "Freeze %s of %s overridden: %s\n", freeze.Name, env, g.options.reason)
+	}
+
+	phrase := g.policy.confirmation(env)
+	if phrase == "" || g.confirmed[env] {
+		return nil
+	}
+	if answer := g.prompter.ask(fmt.Sprintf("%s is protected, type %q to deploy %s", env, phrase, ref), ""); answer != phrase {
+		return fmt.Errorf("%w: %s", errConfirmationMismatch, env)
+	}
+	g.confirmed[env] = true
+	return nil
+}
+
+// confirm asks a yes/no question with the prompter of the gate.
+func (g *deployGate) confirm(question string) bool {
+	return g.prompter.confirm(question)
+}
+
+// freezeOverride is a line of the freeze override log.
+type freezeOverride struct {
+	Time        time.Time `json:"time"`
+	Context     string    `json:"context,omitempty"`
+	User        string    `json:"user,omitempty"`
+	Project     string    `json:"project"`
+	Environment string    `json:"environment"`
+	Ref         string    `json:"ref"`
+	Freeze      string    `json:"freeze"`
+	Reason      string    `json:"reason"`
+}
+
+// logFreezeOverride appends the override to the freeze override log, one
+// json object per line.
+func logFreezeOverride(override freezeOverride) error {
+	path := freezeOverrideLogFile
+	if path == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, ".miactl", "freeze-overrides.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(override)
+}
+
+// currentUsername returns the name of the user running miactl, empty if
+// unknown.
+func currentUsername() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/mock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const policyTestConfig = `current-context: prod
+contexts:
+  prod:
+    apiBaseUrl: https://prod/
+    policy:
+      timezone: UTC
+      freezes:
+        - name: weekend
+          schedule: "* * * * 6,0"
+          environments: [production]
+        - name: holidays
+          from: 2020-12-23
+          to: 2021-01-06
+      protectedEnvironments:
+        - environment: staging
+          confirmation: deploy staging
+`
+
+func TestCronSchedule(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		date, err := time.Parse(time.RFC3339, value)
+		require.NoError(t, err)
+		return date
+	}
+
+	testCases := []struct {
+		schedule string
+		time     string
+		matches  bool
+	}{
+		{"* * * * *", "2020-04-25T12:00:00Z", true},
+		{"* 18-23 * * 5", "2020-04-24T19:30:00Z", true},
+		{"* 18-23 * * 5", "2020-04-24T17:59:00Z", false},
+		{"* 18-23 * * 5", "2020-04-25T19:30:00Z", false},
+		{"*/15 * * * *", "2020-04-25T12:30:00Z", true},
+		{"*/15 * * * *", "2020-04-25T12:31:00Z", false},
+		{"5/10 * * * *", "2020-04-25T12:25:00Z", true},
+		{"0,30 9 * * *", "2020-04-25T09:30:00Z", true},
+		{"* * * * 7", "2020-04-26T09:30:00Z", true},
+		{"* * 1 12 *", "2020-12-01T09:30:00Z", true},
+		{"* * 1 12 *", "2020-11-01T09:30:00Z", false},
+		// day of month or day of week, when both are restricted
+		{"* * 1 * 1", "2020-04-01T09:30:00Z", true},
+		{"* * 1 * 1", "2020-04-27T09:30:00Z", true},
+		{"* * 1 * 1", "2020-04-28T09:30:00Z", false},
+	}
+	for _, testCase := range testCases {
+		schedule, err := parseCronSchedule(testCase.schedule)
+		require.NoError(t, err)
+		require.Equal(t, testCase.matches, schedule.matches(at(testCase.time)), "%s at %s", testCase.schedule, testCase.time)
+	}
+
+	for _, expression := range []string{"* * * *", "60 * * * *", "* 5-2 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *"} {
+		_, err := parseCronSchedule(expression)
+		require.Error(t, err, expression)
+	}
+}
+
+func TestDeployPolicy(t *testing.T) {
+	t.Run("compiles and evaluates the policy", func(t *testing.T) {
+		policy := deployPolicy{
+			Timezone: "Europe/Rome",
+			Freezes: []freezeWindow{
+				{Schedule: "* 0-8 * * *", Environments: []string{"production"}},
+				{Name: "holidays", From: "2020-12-23", To: "2021-01-06"},
+			},
+			ProtectedEnvironments: []protectedEnvironment{{Environment: "production", Confirmation: "deploy production"}},
+		}
+		require.Empty(t, policy.compile())
+
+		// 07:00 in Rome
+		early := time.Date(2020, 4, 25, 5, 0, 0, 0, time.UTC)
+		require.Equal(t, "freezes[0]", policy.activeFreeze("production", early).Name)
+		require.Nil(t, policy.activeFreeze("staging", early))
+		require.Nil(t, policy.activeFreeze("production", early.Add(2*time.Hour)))
+
+		rome, err := time.LoadLocation("Europe/Rome")
+		require.NoError(t, err)
+		require.Equal(t, "holidays", policy.activeFreeze("staging", time.Date(2021, 1, 6, 23, 59, 0, 0, rome)).Name)
+		require.Nil(t, policy.activeFreeze("staging", time.Date(2021, 1, 7, 0, 0, 0, 0, rome)))
+
+		require.Equal(t, "deploy production", policy.confirmation("production"))
+		require.Empty(t, policy.confirmation("staging"))
+	})
+
+	t.Run("returns the problems of an invalid policy", func(t *testing.T) {
+		policy := deployPolicy{
+			Timezone: "Mars/Olympus",
+			Freezes: []freezeWindow{
+				{Name: "never"},
+				{Name: "both", Schedule: "* * * * *", From: "2020-01-01"},
+				{Name: "cron", Schedule: "* * *"},
+				{Name: "dates", From: "2020-01-02", To: "2020-01-01"},
+				{Name: "typo", From: "yesterday", To: "2020-01-01"},
+			},
+			ProtectedEnvironments: []protectedEnvironment{{Environment: "production"}},
+		}
+		require.Equal(t, []string{
+			`timezone "Mars/Olympus" is unknown`,
+			"never: one of schedule or from and to is required",
+			"both: schedule can't be used with from and to",
+			`cron: schedule "* * *": 5 fields are required: minute hour day-of-month month day-of-week`,
+			"dates: to must follow from",
+			`typo: from "yesterday" is not a date`,
+			"protectedEnvironments[0]: environment and confirmation are required",
+		}, policy.compile())
+	})
+
+	t.Run("reads the policy of the current context", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+		viper.SetConfigFile(configPath)
+		require.NoError(t, viper.ReadInConfig())
+
+		name, policy, err := contextPolicy()
+		require.NoError(t, err)
+		require.Equal(t, "prod", name)
+		require.Len(t, policy.Freezes, 2)
+		require.Equal(t, []string{"production"}, policy.Freezes[0].Environments)
+		require.Equal(t, "deploy staging", policy.confirmation("staging"))
+	})
+
+	t.Run("keeps the policy when the context is updated", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "context", "set", "prod", "--config", configPath, "--company=my-company")
+		require.NoError(t, err)
+
+		_, policy, err := contextPolicy()
+		require.NoError(t, err)
+		require.Len(t, policy.Freezes, 2)
+		require.Equal(t, "deploy staging", policy.confirmation("staging"))
+	})
+}
+
+func TestDeployPolicyGate(t *testing.T) {
+	deployStatusPollInterval = time.Millisecond
+	// a saturday
+	timeNow = func() time.Time { return time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC) }
+	defer func() { timeNow = time.Now }()
+
+	dir, err := ioutil.TempDir("", "miactl-policy")
+	require.NoError(t, err)
+	freezeOverrideLogFile = filepath.Join(dir, "freeze-overrides.log")
+	defer func() { freezeOverrideLogFile = "" }()
+
+	promoteArgs := func(configPath string, args ...string) []string {
+		return append([]string{"deploy", "promote", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--config", configPath, "--yes"}, args...)
+	}
+	newPromoteMock := func() *mock.Mock {
+		m := mock.New()
+		m.On(mock.NewMiaClient).Return(nil)
+		m.On(mock.ProjectsGet).Return(deployTestProjects, nil)
+		m.On(mock.DeployGetHistory).Return(deployTestHistory, nil)
+		m.On(mock.DeployTrigger).Return(sdk.DeployResponse{ID: 42}, nil)
+		return m
+	}
+
+	t.Run("blocks the deploys of a frozen environment", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		m := newPromoteMock()
+		_, err := executeRootCommandWithMock(m, promoteArgs(configPath, "--from=staging", "--to=production")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: production of project-id is in the freeze weekend, use --override-freeze with a --reason to deploy anyway", errDeployFrozen))
+		m.AssertNotCalled(t, mock.DeployTrigger)
+	})
+
+	t.Run("requires a reason to override the freeze", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		m := newPromoteMock()
+		_, err := executeRootCommandWithMock(m, promoteArgs(configPath, "--from=staging", "--to=production", "--override-freeze")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: weekend", errOverrideReasonRequired))
+		m.AssertNotCalled(t, mock.DeployTrigger)
+	})
+
+	t.Run("logs the freeze override", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		m := newPromoteMock()
+		out, err := executeRootCommandWithMock(m, promoteArgs(configPath, "--from=staging", "--to=production", "--override-freeze", "--reason", "checkout hotfix")...)
+		require.NoError(t, err)
+		require.Contains(t, out, "Freeze weekend of production overridden: checkout hotfix\n")
+		m.AssertCalledOnceWith(t, mock.DeployTrigger, "project-id", sdk.DeployConfig{Environment: "production", Revision: "v1.3.0"})
+
+		content, err := ioutil.ReadFile(freezeOverrideLogFile)
+		require.NoError(t, err)
+		var override freezeOverride
+		require.NoError(t, json.Unmarshal(content, &override))
+		override.User = ""
+		require.Equal(t, freezeOverride{
+			Time:        timeNow(),
+			Context:     "prod",
+			Project:     "project-id",
+			Environment: "production",
+			Ref:         "v1.3.0",
+			Freeze:      "weekend",
+			Reason:      "checkout hotfix",
+		}, override)
+	})
+
+	t.Run("uses the rollback reason to override the freeze", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		m := newPromoteMock()
+		args := []string{"deploy", "rollback", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--config", configPath, "--yes", "--env=production", "--to=2", "--override-freeze", "--reason", "broken login"}
+		out, err := executeRootCommandWithMock(m, args...)
+		require.NoError(t, err)
+		require.Contains(t, out, "  reason: broken login\nFreeze weekend of production overridden: broken login\n")
+		m.AssertCalledOnceWith(t, mock.DeployTrigger, "project-id", sdk.DeployConfig{Environment: "production", Revision: "v1.2.0"})
+	})
+
+	t.Run("requires the confirmation phrase of a protected environment", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:      deployTestProjects,
+			DeployHistory: deployTestHistory,
+		}, "deploy production\n", promoteArgs(configPath, "--from=development", "--to=staging")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: staging", errConfirmationMismatch))
+		require.Contains(t, out, `staging is protected, type "deploy staging" to deploy master: `)
+	})
+
+	t.Run("deploys a protected environment once confirmed", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:              deployTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+		}, "deploy staging\ny\n", "deploy", "promote", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--config", configPath, "--from=development", "--to=staging")
+		require.NoError(t, err)
+		require.Contains(t, out, "Do you want to continue? [y/N]: Deploy pipeline #42 of master triggered on staging")
+	})
+
+	t.Run("asks the phrase once per environment in batch deploys", func(t *testing.T) {
+		configPath, cleanup := setupConfigFile(t, policyTestConfig)
+		defer cleanup()
+
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			Projects:              batchTestProjects,
+			DeployHistory:         deployTestHistory,
+			DeployTriggerResponse: sdk.DeployResponse{ID: 42},
+		}, "deploy staging\n", "deploy", "batch", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--config", configPath, "--selector", "*", "--env", "staging,development", "--ref", "v1.2.0", "--workers", "1", "--yes")
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(out, `staging is protected, type "deploy staging" to deploy v1.2.0: `))
+		require.Contains(t, out, "Deploy of v1.2.0: 0 succeeded, 1 triggered, 0 failed, 5 skipped\n")
+	})
+}
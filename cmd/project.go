@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+const maxResourceIDLength = 63
+
+var (
+	errInvalidID       = errors.New("Invalid id")
+	errMissingValue    = errors.New("Missing required value")
+	resourceIDRegexp   = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+	nonSlugCharsRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// newProjectCmd creates the command grouping the project operations
+func newProjectCmd() *cobra.Command {
+	projectCmd := &cobra.Command{
+		Use:   "project",
+		Short: "Manage console projects",
+	}
+
+	projectCmd.AddCommand(newProjectCreateCmd())
+	return projectCmd
+}
+
+type projectCreateOptions struct {
+	name         string
+	id           string
+	template     string
+	description  string
+	environments []string
+	clusterHost  string
+	dryRun       bool
+}
+
+func newProjectCreateCmd() *cobra.Command {
+	var options projectCreateOptions
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a new project",
+		Long: `Create a new project in the company.
+
+The values of the missing flags are asked interactively, on the standard error.`,
+		Example: `  # create a project with two environments on the same cluster
+  miactl project create --name "My Project" --id my-project --company my-company \
+    --template template-id --environment development,production:Production --cluster-host cluster.example.com
+
+  # print the request without creating the project
+  miactl project create --name "My Project" --company my-company --dry-run`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the questions are asked on stderr, so that the output of a dry
+			// run is only the request payload
+			p := newPrompter(cmd)
+			p.out = cmd.ErrOrStderr()
+			if options.name == "" {
+				options.name = p.ask("Project name", "")
+			}
+			if options.id == "" {
+				options.id = p.ask("Project id", slugify(options.name))
+			}
+			if companyID == "" {
+				companyID = p.ask("Company id", "")
+			}
+			if !cmd.Flags().Changed("environment") {
+				options.environments = strings.Split(p.ask("Environments, as comma separated id[:label]", "development,production"), ",")
+			}
+			if options.clusterHost == "" {
+				options.clusterHost = p.ask("Cluster hostname", "")
+			}
+
+			request, err := buildCreateProjectRequest(options, companyID)
+			if err != nil {
+				return err
+			}
+
+			if options.dryRun {
+				return renderer.NewJSON(cmd.OutOrStdout(), request)
+			}
+
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			createProject(f, request)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.name, "name", "", "name of the project")
+	cmd.Flags().StringVar(&options.id, "id", "", "id of the project (default derived from the name)")
+	cmd.Flags().StringVar(&options.template, "template", "", "id of the template the project is created from")
+	cmd.Flags().StringVar(&options.description, "description", "", "description of the project")
+	cmd.Flags().StringSliceVar(&options.environments, "environment", nil, "environments of the project, as id[:label]")
+	cmd.Flags().StringVar(&options.clusterHost, "cluster-host", "", "hostname of the cluster where the environments are deployed")
+	cmd.Flags().BoolVar(&options.dryRun, "dry-run", false, "print the request payload without creating the project")
+
+	return cmd
+}
+
+func createProject(f *Factory, request sdk.CreateProjectRequest) {
+	project, err := f.MiaClient.Projects.Create(request)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+
+	table := f.Renderer.Table([]string{"Name", "Project id", "Company id", "Environments"})
+	envs := make([]string, 0, len(project.Environments))
+	for _, env := range project.Environments {
+		envs = append(envs, env.EnvID)
+	}
+	table.Append([]string{project.Name, project.ProjectID, project.TenantID, strings.Join(envs, ", ")})
+	table.Render()
+}
+
+// buildCreateProjectRequest validates the options and converts them to the
+// request sent to the console. The namespace of each environment is
+// <project id>-<environment id>.
+func buildCreateProjectRequest(options projectCreateOptions, company string) (sdk.CreateProjectRequest, error) {
+	if strings.TrimSpace(options.name) == "" {
+		return sdk.CreateProjectRequest{}, fmt.Errorf("%w: project name", errMissingValue)
+	}
+	if company == "" {
+		return sdk.CreateProjectRequest{}, fmt.Errorf("%w: company id", errMissingValue)
+	}
+	if err := validateResourceID("project", options.id); err != nil {
+		return sdk.CreateProjectRequest{}, err
+	}
+
+	environments := []sdk.Environment{}
+	seen := map[string]bool{}
+	for _, value := range options.environments {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		parts := strings.SplitN(value, ":", 2)
+		envID := parts[0]
+		label := capitalize(envID)
+		if len(parts) == 2 && parts[1] != "" {
+			label = parts[1]
+		}
+		if err := validateResourceID("environment", envID); err != nil {
+			return sdk.CreateProjectRequest{}, err
+		}
+		if seen[envID] {
+			return sdk.CreateProjectRequest{}, fmt.Errorf("%w: environment %s is repeated", errInvalidID, envID)
+		}
+		seen[envID] = true
+
+		environments = append(environments, sdk.Environment{
+			EnvID:       envID,
+			DisplayName: label,
+			Cluster: sdk.Cluster{
+				Hostname:  options.clusterHost,
+				Namespace: fmt.Sprintf("%s-%s", options.id, envID),
+			},
+		})
+	}
+	if len(environments) == 0 {
+		return sdk.CreateProjectRequest{}, fmt.Errorf("%w: environments", errMissingValue)
+	}
+	if strings.TrimSpace(options.clusterHost) == "" {
+		return sdk.CreateProjectRequest{}, fmt.Errorf("%w: cluster hostname", errMissingValue)
+	}
+
+	return sdk.CreateProjectRequest{
+		Name:         options.name,
+		ProjectID:    options.id,
+		TenantID:     company,
+		TemplateID:   options.template,
+		Description:  options.description,
+		Environments: environments,
+	}, nil
+}
+
+// validateResourceID checks that id can be used as a kubernetes resource
+// name: lowercase alphanumeric characters and dashes, starting with a letter.
+func validateResourceID(kind, id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: %s id", errMissingValue, kind)
+	}
+	if len(id) > maxResourceIDLength || !resourceIDRegexp.MatchString(id) {
+		return fmt.Errorf("%w: %s id %q must contain at most %d lowercase letters, digits or dashes and start with a letter", errInvalidID, kind, id, maxResourceIDLength)
+	}
+	return nil
+}
+
+func slugify(name string) string {
+	return strings.Trim(nonSlugCharsRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
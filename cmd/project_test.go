@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectCreate(t *testing.T) {
+	expectedRequest := sdk.CreateProjectRequest{
+		Name:       "My Project",
+		ProjectID:  "my-project",
+		TenantID:   "my-company",
+		TemplateID: "template-1",
+		Environments: []sdk.Environment{
+			{
+				EnvID:       "development",
+				DisplayName: "Development",
+				Cluster:     sdk.Cluster{Hostname: "cluster.local", Namespace: "my-project-development"},
+			},
+			{
+				EnvID:       "production",
+				DisplayName: "Prod",
+				Cluster:     sdk.Cluster{Hostname: "cluster.local", Namespace: "my-project-production"},
+			},
+		},
+	}
+	flags := []string{
+		"project", "create", apiKeyFlag, apiBaseURLFlag, apiCookieFlag,
+		"--name=My Project", "--id=my-project", "--company=my-company", "--template=template-1",
+		"--environment=development,production:Prod", "--cluster-host=cluster.local",
+	}
+
+	t.Run("creates project from flags", func(t *testing.T) {
+		var created bool
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ProjectCreateAssertFn: func(request sdk.CreateProjectRequest) {
+				created = true
+				require.Equal(t, expectedRequest, request)
+			},
+		}, flags...)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, []string{
+			"NAME | PROJECT ID | COMPANY ID | ENVIRONMENTS",
+			"My Project | my-project | my-company | development, production",
+		}, renderer.CleanTableRows(out))
+	})
+
+	t.Run("prints request on dry run", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ProjectCreateAssertFn: func(request sdk.CreateProjectRequest) {
+				t.Fatal("project should not be created")
+			},
+		}, append(flags, "--dry-run")...)
+		require.NoError(t, err)
+
+		var request sdk.CreateProjectRequest
+		require.NoError(t, json.Unmarshal([]byte(out), &request))
+		require.Equal(t, expectedRequest, request)
+	})
+
+	t.Run("asks missing values", func(t *testing.T) {
+		out, err := executeRootCommandWithInput(sdk.MockClientError{
+			ProjectCreateAssertFn: func(request sdk.CreateProjectRequest) {
+				require.Equal(t, sdk.CreateProjectRequest{
+					Name:      "My Project",
+					ProjectID: "my-project",
+					TenantID:  "my-company",
+					Environments: []sdk.Environment{
+						{
+							EnvID:       "development",
+							DisplayName: "Development",
+							Cluster:     sdk.Cluster{Hostname: "cluster.local", Namespace: "my-project-development"},
+						},
+						{
+							EnvID:       "production",
+							DisplayName: "Production",
+							Cluster:     sdk.Cluster{Hostname: "cluster.local", Namespace: "my-project-production"},
+						},
+					},
+				}, request)
+			},
+		}, "My Project\n\nmy-company\n\ncluster.local\n", "project", "create", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.NoError(t, err)
+		require.Contains(t, out, "Project name: Project id [my-project]: Company id: Environments, as comma separated id[:label] [development,production]: Cluster hostname: ")
+	})
+
+	t.Run("asks missing values on stderr on dry run", func(t *testing.T) {
+		rootCmd := NewRootCmd()
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		rootCmd.SetOut(stdout)
+		rootCmd.SetErr(stderr)
+		rootCmd.SetIn(strings.NewReader(""))
+		rootCmd.SetArgs([]string{"project", "create", "--name=My Project", "--company=my-company", "--cluster-host=cluster.local", "--dry-run"})
+
+		require.NoError(t, rootCmd.Execute())
+		require.Equal(t, "Project id [my-project]: Environments, as comma separated id[:label] [development,production]: ", stderr.String())
+		var request sdk.CreateProjectRequest
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &request))
+		require.Equal(t, "my-project", request.ProjectID)
+	})
+
+	t.Run("renders error on sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ProjectsError: fmt.Errorf("Some error"),
+		}, flags...)
+		require.NoError(t, err)
+		require.Equal(t, "Some error\n", out)
+	})
+
+	t.Run("returns error on invalid id", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, append(flags, "--id=My_Project")...)
+		require.EqualError(t, err, fmt.Sprintf(`%s: project id "My_Project" must contain at most 63 lowercase letters, digits or dashes and start with a letter`, errInvalidID))
+	})
+}
+
+func TestBuildCreateProjectRequest(t *testing.T) {
+	options := projectCreateOptions{
+		name:         "Project",
+		id:           "project",
+		environments: []string{"development"},
+		clusterHost:  "cluster.local",
+	}
+
+	t.Run("returns error without name", func(t *testing.T) {
+		o := options
+		o.name = " "
+		_, err := buildCreateProjectRequest(o, "company")
+		require.EqualError(t, err, fmt.Sprintf("%s: project name", errMissingValue))
+	})
+
+	t.Run("returns error without company", func(t *testing.T) {
+		_, err := buildCreateProjectRequest(options, "")
+		require.EqualError(t, err, fmt.Sprintf("%s: company id", errMissingValue))
+	})
+
+	t.Run("returns error without environments", func(t *testing.T) {
+		o := options
+		o.environments = []string{"", " "}
+		_, err := buildCreateProjectRequest(o, "company")
+		require.EqualError(t, err, fmt.Sprintf("%s: environments", errMissingValue))
+	})
+
+	t.Run("returns error without cluster hostname", func(t *testing.T) {
+		o := options
+		o.clusterHost = ""
+		_, err := buildCreateProjectRequest(o, "company")
+		require.EqualError(t, err, fmt.Sprintf("%s: cluster hostname", errMissingValue))
+	})
+
+	t.Run("returns error on repeated environment", func(t *testing.T) {
+		o := options
+		o.environments = []string{"development", "development:Dev"}
+		_, err := buildCreateProjectRequest(o, "company")
+		require.EqualError(t, err, fmt.Sprintf("%s: environment development is repeated", errInvalidID))
+	})
+
+	t.Run("returns error on invalid environment id", func(t *testing.T) {
+		o := options
+		o.environments = []string{"1st"}
+		_, err := buildCreateProjectRequest(o, "company")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `environment id "1st"`)
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	require.Equal(t, "my-new-project-2", slugify("  My New_Project (2) "))
+	require.Equal(t, "", slugify("!!"))
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// prompter asks questions on the command output and reads the answers from
+// the command input. The same prompter must be used for all the questions
+// of a command, since the input is buffered.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(cmd *cobra.Command) *prompter {
+	return &prompter{
+		in:  bufio.NewReader(cmd.InOrStdin()),
+		out: cmd.OutOrStdout(),
+	}
+}
+
+// ask returns the trimmed answer to question, or def if the answer is empty.
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	answer, _ := p.in.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// confirm asks a yes/no question. Only an explicit yes is accepted.
+func (p *prompter) confirm(question string) bool {
+	fmt.Fprintf(p.out, "%s [y/N]: ", question)
+
+	answer, _ := p.in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// confirm asks the user a yes/no question on the command output and reads
+// the answer from the command input. Only an explicit yes is accepted.
+func confirm(cmd *cobra.Command, question string) bool {
+	return newPrompter(cmd).confirm(question)
+}
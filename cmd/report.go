@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// reportHistoryPageSize is the number of deploys requested for each page
+// of the deploy history while building reports.
+const reportHistoryPageSize = 100
+
+var errInvalidOutputFormat = errors.New("Unsupported output format")
+
+// timeNow returns the current time, it is replaced in tests.
+var timeNow = time.Now
+
+// newReportCmd creates the command grouping the available reports
+func newReportCmd() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate reports about projects",
+	}
+
+	reportCmd.AddCommand(newReportDeploymentsCmd())
+	return reportCmd
+}
+
+func newReportDeploymentsCmd() *cobra.Command {
+	var since string
+
+	cmd := &cobra.Command{
+		Use:   "deployments",
+		Short: "Report deploy frequency, change failure rate, duration and time to restore",
+		Example: `  # report the last 90 days of deploys as csv
+  miactl report deployments --project my-project --since 90d -o csv`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.MarkFlagRequired("project")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutputFormat("table", "json", "yaml", "csv"); err != nil {
+				return err
+			}
+			now := timeNow()
+			sinceTime, err := parseSince(since, now)
+			if err != nil {
+				return err
+			}
+
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			return reportDeployments(f, sinceTime, now, outputFormat)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "30d", "start of the report, as a duration (e.g. 90d, 2w, 12h) or a date (YYYY-MM-DD)")
+
+	return cmd
+}
+
+// deployReport holds the deploy metrics of a project in a time window.
+type deployReport struct {
+	ProjectID    string                    `json:"projectId"`
+	Since        time.Time                 `json:"since"`
+	Until        time.Time                 `json:"until"`
+	Environments []environmentDeployReport `json:"environments"`
+}
+
+// environmentDeployReport holds the deploy metrics of a single environment.
+// Durations are expressed in seconds.
+type environmentDeployReport struct {
+	Environment       string  `json:"environment"`
+	Deploys           int     `json:"deploys"`
+	SuccessfulDeploys int     `json:"successfulDeploys"`
+	FailedDeploys     int     `json:"failedDeploys"`
+	DeploysPerDay     float64 `json:"deploysPerDay"`
+	ChangeFailureRate float64 `json:"changeFailureRate"`
+	MeanDuration      float64 `json:"meanDuration"`
+	MeanLeadTime      float64 `json:"meanLeadTime"`
+	MeanTimeToRestore float64 `json:"meanTimeToRestore"`
+}
+
+// reportDeployments renders the deploy report, returning the errors
+// writing the csv output.
+func reportDeployments(f *Factory, since, until time.Time, output string) error {
+	history, err := getHistorySince(f, projectID, since)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return nil
+	}
+
+	report := deployReport{
+		ProjectID:    projectID,
+		Since:        since,
+		Until:        until,
+		Environments: computeDeployReport(history, since, until),
+	}
+
+	switch output {
+	case "json", "yaml":
+		renderStructured(f, report)
+	case "csv":
+		w := f.Renderer.CSV()
+		w.Write([]string{"environment", "deploys", "successful_deploys", "failed_deploys", "deploys_per_day", "change_failure_rate", "mean_duration_seconds", "mean_lead_time_seconds", "mean_time_to_restore_seconds"})
+		for _, env := range report.Environments {
+			w.Write([]string{
+				env.Environment,
+				strconv.Itoa(env.Deploys),
+				strconv.Itoa(env.SuccessfulDeploys),
+				strconv.Itoa(env.FailedDeploys),
+				strconv.FormatFloat(env.DeploysPerDay, 'f', 2, 64),
+				strconv.FormatFloat(env.ChangeFailureRate, 'f', 4, 64),
+				strconv.FormatFloat(env.MeanDuration, 'f', 0, 64),
+				strconv.FormatFloat(env.MeanLeadTime, 'f', 0, 64),
+				strconv.FormatFloat(env.MeanTimeToRestore, 'f', 0, 64),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		headers := []string{"Environment", "Deploys", "Failed", "Deploys/Day", "Change Failure Rate", "Mean Duration", "Mean Lead Time", "Mean Time To Restore"}
+		table := f.Renderer.Table(headers)
+		for _, env := range report.Environments {
+			table.Append([]string{
+				env.Environment,
+				strconv.Itoa(env.Deploys),
+				strconv.Itoa(env.FailedDeploys),
+				strconv.FormatFloat(env.DeploysPerDay, 'f', 2, 64),
+				fmt.Sprintf("%.1f%%", env.ChangeFailureRate*100),
+				formatSeconds(env.MeanDuration),
+				formatSeconds(env.MeanLeadTime),
+				formatSeconds(env.MeanTimeToRestore),
+			})
+		}
+		table.Render()
+	}
+	return nil
+}
+
+// getHistorySince pages through the deploy history, sorted from the newest
+// deploy, until a deploy finished before since is found. The deploys not
+// finished yet are skipped.
+func getHistorySince(f *Factory, projectID string, since time.Time) ([]sdk.DeployItem, error) {
+	var history []sdk.DeployItem
+	for page := 1; ; page++ {
+		items, err := f.MiaClient.Deploy.GetHistory(sdk.DeployHistoryQuery{
+			ProjectID: projectID,
+			Page:      page,
+			PerPage:   reportHistoryPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			if item.FinishedAt.IsZero() {
+				continue
+			}
+			if item.FinishedAt.Before(since) {
+				return history, nil
+			}
+			history = append(history, item)
+		}
+		if len(items) < reportHistoryPageSize {
+			return history, nil
+		}
+	}
+}
+
+// computeDeployReport computes the metrics of each environment found in
+// history. Only successful and failed deploys are taken into account.
+func computeDeployReport(history []sdk.DeployItem, since, until time.Time) []environmentDeployReport {
+	byEnvironment := map[string][]sdk.DeployItem{}
+	for _, deploy := range history {
+		if deploy.Status != sdk.DeployStatusSuccess && deploy.Status != sdk.DeployStatusFailed {
+			continue
+		}
+		byEnvironment[deploy.Environment] = append(byEnvironment[deploy.Environment], deploy)
+	}
+
+	days := until.Sub(since).Hours() / 24
+	reports := []environmentDeployReport{}
+	for env, deploys := range byEnvironment {
+		// walk the deploys in chronological order to measure restore times
+		sort.SliceStable(deploys, func(i, j int) bool {
+			return deploys[i].FinishedAt.Before(deploys[j].FinishedAt)
+		})
+
+		report := environmentDeployReport{Environment: env, Deploys: len(deploys)}
+		var duration, leadTime, restoreTime time.Duration
+		var leadTimes, restores int
+		var failedAt *time.Time
+		for i, deploy := range deploys {
+			duration += time.Duration(deploy.Duration * float64(time.Second))
+
+			if deploy.Status == sdk.DeployStatusFailed {
+				report.FailedDeploys++
+				if failedAt == nil {
+					failedAt = &deploys[i].FinishedAt
+				}
+				continue
+			}
+
+			report.SuccessfulDeploys++
+			if !deploy.Commit.CommitDate.IsZero() {
+				leadTime += deploy.FinishedAt.Sub(deploy.Commit.CommitDate)
+				leadTimes++
+			}
+			if failedAt != nil {
+				restoreTime += deploy.FinishedAt.Sub(*failedAt)
+				restores++
+				failedAt = nil
+			}
+		}
+
+		if days > 0 {
+			report.DeploysPerDay = float64(report.SuccessfulDeploys) / days
+		}
+		report.ChangeFailureRate = float64(report.FailedDeploys) / float64(report.Deploys)
+		report.MeanDuration = duration.Seconds() / float64(report.Deploys)
+		if leadTimes > 0 {
+			report.MeanLeadTime = leadTime.Seconds() / float64(leadTimes)
+		}
+		if restores > 0 {
+			report.MeanTimeToRestore = restoreTime.Seconds() / float64(restores)
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Environment < reports[j].Environment
+	})
+	return reports
+}
+
+// parseSince converts since into the start time of a report. It accepts
+// durations, with the additional d (days) and w (weeks) units, or dates.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", since); err == nil {
+		return date, nil
+	}
+
+	for unit, multiplier := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if !strings.HasSuffix(since, unit) {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSuffix(since, unit))
+		if err != nil || value < 0 {
+			return time.Time{}, fmt.Errorf("invalid since value: %s", since)
+		}
+		return now.Add(-time.Duration(value) * multiplier), nil
+	}
+
+	duration, err := time.ParseDuration(since)
+	if err != nil || duration < 0 {
+		return time.Time{}, fmt.Errorf("invalid since value: %s", since)
+	}
+	return now.Add(-duration), nil
+}
+
+// formatSeconds renders a number of seconds as a duration, or - if zero.
+func formatSeconds(seconds float64) string {
+	if seconds == 0 {
+		return "-"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+var reportTestNow = time.Date(2020, 05, 01, 00, 00, 00, 00, time.UTC)
+
+var reportTestHistory = []sdk.DeployItem{
+	{
+		ID:          6,
+		Status:      sdk.DeployStatusRunning,
+		Environment: "production",
+	},
+	{
+		ID:          5,
+		Status:      sdk.DeployStatusSuccess,
+		Environment: "production",
+		Duration:    40,
+		Commit:      sdk.CommitInfo{CommitDate: time.Date(2020, 04, 29, 10, 00, 00, 00, time.UTC)},
+		FinishedAt:  time.Date(2020, 04, 29, 12, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          4,
+		Status:      sdk.DeployStatusFailed,
+		Environment: "production",
+		Duration:    20,
+		FinishedAt:  time.Date(2020, 04, 29, 11, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          3,
+		Status:      sdk.DeployStatusSuccess,
+		Environment: "development",
+		Duration:    30,
+		Commit:      sdk.CommitInfo{CommitDate: time.Date(2020, 04, 20, 9, 00, 00, 00, time.UTC)},
+		FinishedAt:  time.Date(2020, 04, 20, 10, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          2,
+		Status:      sdk.DeployStatusSuccess,
+		Environment: "production",
+		Duration:    60,
+		Commit:      sdk.CommitInfo{CommitDate: time.Date(2020, 04, 10, 8, 00, 00, 00, time.UTC)},
+		FinishedAt:  time.Date(2020, 04, 10, 10, 00, 00, 00, time.UTC),
+	},
+	{
+		ID:          1,
+		Status:      sdk.DeployStatusSuccess,
+		Environment: "production",
+		Duration:    60,
+		FinishedAt:  time.Date(2020, 03, 01, 10, 00, 00, 00, time.UTC),
+	},
+}
+
+func TestReportDeployments(t *testing.T) {
+	timeNow = func() time.Time { return reportTestNow }
+	defer func() { timeNow = time.Now }()
+	projectIDFlag := fmt.Sprintf("--project=%s", "project-id")
+	baseArgs := []string{"report", "deployments", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, projectIDFlag, "--since=30d"}
+
+	t.Run("returns error on invalid output", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, append(baseArgs, "-o=xml")...)
+		require.EqualError(t, err, fmt.Sprintf("%s: xml", errInvalidOutputFormat))
+	})
+
+	t.Run("returns error on invalid since", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, append(baseArgs, "--since=yesterday")...)
+		require.EqualError(t, err, "invalid since value: yesterday")
+	})
+
+	t.Run("renders error on sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployError: fmt.Errorf("Some error"),
+		}, baseArgs...)
+		require.NoError(t, err)
+		require.Equal(t, "Some error\n", out)
+	})
+
+	t.Run("renders table", func(t *testing.T) {
+		var pages []int
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: reportTestHistory,
+			DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+				pages = append(pages, query.Page)
+			},
+		}, baseArgs...)
+		require.NoError(t, err)
+		require.Equal(t, []int{1}, pages)
+
+		rows := renderer.CleanTableRows(out)
+		require.Equal(t, []string{
+			"ENVIRONMENT | DEPLOYS | FAILED | DEPLOYS/DAY | CHANGE FAILURE RATE | MEAN DURATION | MEAN LEAD TIME | MEAN TIME TO RESTORE",
+			"development | 1 | 0 | 0.03 | 0.0% | 30s | 1h0m0s | -",
+			"production | 3 | 1 | 0.07 | 33.3% | 40s | 2h0m0s | 1h0m0s",
+		}, rows)
+	})
+
+	t.Run("renders csv", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: reportTestHistory,
+		}, append(baseArgs, "-o=csv")...)
+		require.NoError(t, err)
+		require.Equal(t, `environment,deploys,successful_deploys,failed_deploys,deploys_per_day,change_failure_rate,mean_duration_seconds,mean_lead_time_seconds,mean_time_to_restore_seconds
+development,1,1,0,0.03,0.0000,30,3600,0
+production,3,2,1,0.07,0.3333,40,7200,3600
+`, out)
+	})
+
+	t.Run("returns the csv write error", func(t *testing.T) {
+		f := &Factory{
+			Renderer:         renderer.New(errorWriter{}),
+			miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{DeployHistory: reportTestHistory}),
+		}
+		require.NoError(t, f.addMiaClientToFactory(sdk.Options{}))
+		err := reportDeployments(f, reportTestNow.AddDate(0, 0, -30), reportTestNow, "csv")
+		require.EqualError(t, err, "disk full")
+	})
+
+	t.Run("renders json", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			DeployHistory: reportTestHistory,
+		}, append(baseArgs, "--output=json")...)
+		require.NoError(t, err)
+
+		var report deployReport
+		require.NoError(t, json.Unmarshal([]byte(out), &report))
+		require.Equal(t, "project-id", report.ProjectID)
+		require.Equal(t, reportTestNow.AddDate(0, 0, -30), report.Since)
+		require.Len(t, report.Environments, 2)
+		require.Equal(t, environmentDeployReport{
+			Environment:       "production",
+			Deploys:           3,
+			SuccessfulDeploys: 2,
+			FailedDeploys:     1,
+			DeploysPerDay:     2.0 / 30,
+			ChangeFailureRate: 1.0 / 3,
+			MeanDuration:      40,
+			MeanLeadTime:      7200,
+			MeanTimeToRestore: 3600,
+		}, report.Environments[1])
+	})
+}
+
+// errorWriter fails every write.
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestGetHistorySince(t *testing.T) {
+	running := sdk.DeployItem{ID: -1, Status: sdk.DeployStatusRunning}
+	history := make([]sdk.DeployItem, 0, 2*reportHistoryPageSize+1)
+	for i := 0; i < 2*reportHistoryPageSize+1; i++ {
+		history = append(history, sdk.DeployItem{
+			ID:         i,
+			FinishedAt: reportTestNow.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	f := &Factory{
+		miaClientCreator: sdk.WrapperMockMiaClient(sdk.MockClientError{
+			DeployHistory: append([]sdk.DeployItem{running}, history...),
+		}),
+	}
+	require.NoError(t, f.addMiaClientToFactory(sdk.Options{}))
+
+	t.Run("pages through the whole history", func(t *testing.T) {
+		items, err := getHistorySince(f, "project-id", time.Time{})
+		require.NoError(t, err)
+		require.Equal(t, history, items)
+	})
+
+	t.Run("stops at the first deploy before since, skipping the running ones", func(t *testing.T) {
+		items, err := getHistorySince(f, "project-id", reportTestNow.Add(-150*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, history[:151], items)
+	})
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		since    string
+		expected time.Time
+	}{
+		{since: "90d", expected: reportTestNow.AddDate(0, 0, -90)},
+		{since: "2w", expected: reportTestNow.AddDate(0, 0, -14)},
+		{since: "12h", expected: reportTestNow.Add(-12 * time.Hour)},
+		{since: "2020-01-31", expected: time.Date(2020, 01, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		since, err := parseSince(test.since, reportTestNow)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, since, test.since)
+	}
+
+	for _, invalid := range []string{"", "-1d", "d", "tomorrow"} {
+		_, err := parseSince(invalid, reportTestNow)
+		require.EqualError(t, err, fmt.Sprintf("invalid since value: %s", invalid))
+	}
+}
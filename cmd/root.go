@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 
 	"github.com/mia-platform/miactl/sdk"
 
@@ -13,22 +15,51 @@ import (
 )
 
 var (
-	cfgFile   string
-	projectID string
-	opts      = sdk.Options{}
+	cfgFile      string
+	projectID    string
+	companyID    string
+	outputFormat string
+	cassetteFile string
+	opts         = sdk.Options{}
 )
 
 // NewRootCmd creates a new root command
 func NewRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use: "miactl",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			startAudit(cmd.Context(), cmd)
+			return applyFlagSources(cmd)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			finishAudit(cmd.Context(), cmd, nil)
+		},
 	}
 	setRootPersistentFlag(rootCmd)
 
 	// add sub command to root command
 	rootCmd.AddCommand(newGetCmd())
+	rootCmd.AddCommand(newDeployCmd())
+	rootCmd.AddCommand(newApplyCmd())
+	rootCmd.AddCommand(newReportCmd())
+	rootCmd.AddCommand(newContextCmd())
+	rootCmd.AddCommand(newAliasCmd())
+	rootCmd.AddCommand(newProjectCmd())
+	rootCmd.AddCommand(newServiceCmd())
+	rootCmd.AddCommand(newMembersCmd())
+	rootCmd.AddCommand(newAPIKeysCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newUICmd())
+	rootCmd.AddCommand(newDevCmd())
+
+	rootCmd.AddCommand(newPluginCmd())
 
 	rootCmd.AddCommand(newCompletionCmd(rootCmd))
+	addPluginCmds(rootCmd, findPlugins(os.Getenv("PATH"), rootCmd))
+	registerCompletions(rootCmd)
+	markAuditedCommands(rootCmd)
 	return rootCmd
 }
 
@@ -37,10 +68,35 @@ func NewRootCmd() *cobra.Command {
 func Execute() {
 	rootCmd := NewRootCmd()
 	ctx := WithFactoryValue(context.Background(), rootCmd.OutOrStdout())
+	if code := executeRoot(ctx, rootCmd, os.Args[1:]); code != 0 {
+		os.Exit(code)
+	}
+}
+
+// executeRoot executes the root command with args, after replacing the
+// alias, and returns the exit code, which is the plugin one when a plugin
+// fails. The failed runs of the audited commands, which skip the post run
+// hook, are audited here.
+func executeRoot(ctx context.Context, rootCmd *cobra.Command, args []string) int {
+	args, err := expandAliases(rootCmd, args)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	rootCmd.SetArgs(args)
+	ctx = withAuditRun(ctx)
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		if run := auditRunFromContext(ctx); run.cmd != nil {
+			finishAudit(ctx, run.cmd, err)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
 		fmt.Println(err)
-		os.Exit(1)
+		return 1
 	}
+	return 0
 }
 
 func init() {
@@ -53,6 +109,16 @@ func setRootPersistentFlag(rootCmd *cobra.Command) {
 	rootCmd.PersistentFlags().StringVar(&opts.APICookie, "apiCookie", "", "api cookie sid")
 	rootCmd.PersistentFlags().StringVar(&opts.APIBaseURL, "apiBaseUrl", "", "api base url")
 	rootCmd.PersistentFlags().StringVarP(&projectID, "project", "p", "", "specify desired project ID")
+	rootCmd.PersistentFlags().StringVar(&companyID, "company", "", "specify desired company ID")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "output format, one of table, json, yaml or csv")
+	rootCmd.PersistentFlags().DurationVar(&opts.Timeout, "timeout", 0, "timeout of each request to the console, 0 means no timeout")
+	rootCmd.PersistentFlags().StringVar(&opts.ClientID, "client-id", "", "client id of the service account, used instead of the api cookie")
+	rootCmd.PersistentFlags().StringVar(&opts.ClientSecret, "client-secret", "", "client secret of the service account")
+	rootCmd.PersistentFlags().StringVar(&opts.PrivateKeyFile, "private-key-file", "", "RSA private key signing the service account JWT assertion, used instead of the client secret")
+	rootCmd.PersistentFlags().StringVar(&opts.KeyID, "key-id", "", "id of the service account private key")
+
+	rootCmd.PersistentFlags().StringVar(&cassetteFile, "record-cassette", "", "record the sanitized console requests and responses in a cassette file")
+	rootCmd.PersistentFlags().MarkHidden("record-cassette")
 
 	rootCmd.MarkFlagRequired("apiKey")
 	rootCmd.MarkFlagRequired("apiCookie")
@@ -77,10 +143,17 @@ func initConfig() {
 		viper.SetConfigName(".miaplatformctl")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	for _, key := range configKeys {
+		viper.BindEnv(key.key, key.env)
+	}
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
+
+	var err error
+	if projectConfig, err = readProjectConfig(); err != nil {
+		fmt.Println("Invalid project config file:", err)
+	}
 }
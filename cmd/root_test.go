@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"context"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mia-platform/miactl/renderer"
 	"github.com/mia-platform/miactl/sdk"
+	"github.com/mia-platform/miactl/sdk/cassette"
+	"github.com/mia-platform/miactl/sdk/mock"
 
 	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
 )
 
 func executeCommand(root *cobra.Command, args ...string) (output string, err error) {
@@ -30,11 +35,16 @@ func executeCommandWithContext(ctx context.Context, root *cobra.Command, args ..
 }
 
 func executeRootCommandWithContext(mockError sdk.MockClientError, args ...string) (output string, err error) {
+	return executeRootCommandWithInput(mockError, "", args...)
+}
+
+func executeRootCommandWithInput(mockError sdk.MockClientError, input string, args ...string) (output string, err error) {
 	rootCmd := NewRootCmd()
 
 	buf := new(bytes.Buffer)
 	rootCmd.SetOut(buf)
 	rootCmd.SetErr(buf)
+	rootCmd.SetIn(strings.NewReader(input))
 	rootCmd.SetArgs(args)
 
 	ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
@@ -47,6 +57,26 @@ func executeRootCommandWithContext(mockError sdk.MockClientError, args ...string
 	return buf.String(), err
 }
 
+// executeRootCommandWithMock executes the root command with the sdk client
+// of the mock.
+func executeRootCommandWithMock(m *mock.Mock, args ...string) (output string, err error) {
+	rootCmd := NewRootCmd()
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs(args)
+
+	ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+		Renderer:         renderer.New(rootCmd.OutOrStderr()),
+		miaClientCreator: m.Creator(),
+	})
+
+	err = rootCmd.ExecuteContext(ctx)
+
+	return buf.String(), err
+}
+
 func executeCommandC(root *cobra.Command, args ...string) (c *cobra.Command, output string, err error) {
 	buf := new(bytes.Buffer)
 	root.SetOut(buf)
@@ -66,3 +96,51 @@ func helperLoadBytes(t *testing.T, name string) []byte {
 	}
 	return bytes
 }
+
+// executeCapturingOptions executes the root command and returns the options
+// the sdk client is created with.
+func executeCapturingOptions(t *testing.T, args ...string) sdk.Options {
+	t.Helper()
+	var options sdk.Options
+	rootCmd := NewRootCmd()
+	ctx := context.WithValue(context.Background(), FactoryContextKey{}, Factory{
+		Renderer: renderer.New(rootCmd.OutOrStdout()),
+		miaClientCreator: func(opts sdk.Options) (*sdk.MiaClient, error) {
+			options = opts
+			return sdk.WrapperMockMiaClient(sdk.MockClientError{})(opts)
+		},
+	})
+	_, err := executeCommandWithContext(ctx, rootCmd, args...)
+	require.NoError(t, err)
+	return options
+}
+
+func TestClientCredentialsFlags(t *testing.T) {
+	t.Run("reads service account from environment", func(t *testing.T) {
+		os.Setenv("MIACTL_CLIENT_ID", "env-client")
+		os.Setenv("MIACTL_CLIENT_SECRET", "env-secret")
+		defer os.Unsetenv("MIACTL_CLIENT_ID")
+		defer os.Unsetenv("MIACTL_CLIENT_SECRET")
+
+		options := executeCapturingOptions(t, "get", "projects", apiKeyFlag, apiBaseURLFlag)
+		require.Equal(t, "env-client", options.ClientID)
+		require.Equal(t, "env-secret", options.ClientSecret)
+		require.True(t, strings.HasSuffix(options.TokenCacheFile, filepath.Join(".miactl", "token-cache.json")))
+
+		options = executeCapturingOptions(t, "get", "projects", apiKeyFlag, apiBaseURLFlag, "--client-id=flag-client")
+		require.Equal(t, "flag-client", options.ClientID)
+		require.Equal(t, "env-secret", options.ClientSecret)
+	})
+}
+
+func TestRecordCassetteFlag(t *testing.T) {
+	t.Run("records the requests in the cassette", func(t *testing.T) {
+		options := executeCapturingOptions(t, "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--record-cassette=cassette.json")
+		require.IsType(t, &cassette.Recorder{}, options.Transport)
+	})
+
+	t.Run("uses the default transport without cassette", func(t *testing.T) {
+		options := executeCapturingOptions(t, "get", "projects", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.Nil(t, options.Transport)
+	})
+}
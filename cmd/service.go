@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+)
+
+// newServiceCmd creates the command grouping the service operations
+func newServiceCmd() *cobra.Command {
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage the services of a project",
+	}
+
+	serviceCmd.AddCommand(newServiceCreateCmd())
+	return serviceCmd
+}
+
+func newServiceCreateCmd() *cobra.Command {
+	var request sdk.CreateServiceRequest
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a service from a marketplace item",
+		Long: `Create a service in the project configuration from a marketplace template or plugin.
+
+Use "miactl get marketplace" to list the available items.`,
+		Example: `  # create a service from the Node.js template
+  miactl service create --project my-project --from-template node-template --name my-service`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cmd.MarkFlagRequired("project")
+			cmd.MarkFlagRequired("from-template")
+			cmd.MarkFlagRequired("name")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateResourceID("service", request.Name); err != nil {
+				return err
+			}
+
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			createService(cmd.OutOrStdout(), f, projectID, request)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&request.TemplateID, "from-template", "", "id of the marketplace template or plugin the service is created from")
+	cmd.Flags().StringVar(&request.Name, "name", "", "name of the service")
+	cmd.Flags().StringVar(&request.Description, "description", "", "description of the service")
+
+	return cmd
+}
+
+func createService(out io.Writer, f *Factory, projectID string, request sdk.CreateServiceRequest) {
+	service, err := f.MiaClient.Services.Create(projectID, request)
+	if err != nil {
+		f.Renderer.Error(err).Render()
+		return
+	}
+
+	fmt.Fprintf(out, "Service %s created in project %s\n", service.Name, projectID)
+	if service.RepositoryURL != "" {
+		fmt.Fprintf(out, "Repository: %s\n", service.RepositoryURL)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceCreate(t *testing.T) {
+	flags := []string{
+		"service", "create", apiKeyFlag, apiBaseURLFlag, apiCookieFlag,
+		"--project=project-id", "--from-template=node-template", "--name=my-service",
+	}
+
+	t.Run("creates service and reports the repository", func(t *testing.T) {
+		var created bool
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ServiceCreateAssertFn: func(projectID string, request sdk.CreateServiceRequest) {
+				created = true
+				require.Equal(t, "project-id", projectID)
+				require.Equal(t, sdk.CreateServiceRequest{Name: "my-service", TemplateID: "node-template"}, request)
+			},
+			ServiceCreateResponse: &sdk.CreateServiceResponse{
+				ID:            "service-id",
+				Name:          "my-service",
+				RepositoryURL: "https://git.example.com/my-service",
+			},
+		}, flags...)
+		require.NoError(t, err)
+		require.True(t, created)
+		require.Equal(t, "Service my-service created in project project-id\nRepository: https://git.example.com/my-service\n", out)
+	})
+
+	t.Run("renders sdk error", func(t *testing.T) {
+		out, err := executeRootCommandWithContext(sdk.MockClientError{
+			ServicesError: sdk.ErrHTTP,
+		}, flags...)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%s\n", sdk.ErrHTTP), out)
+	})
+
+	t.Run("returns error on invalid service name", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{
+			ServiceCreateAssertFn: func(string, sdk.CreateServiceRequest) {
+				t.Fatal("service should not be created")
+			},
+		}, append(flags, "--name=My Service")...)
+		require.EqualError(t, err, fmt.Sprintf(`%s: service id "My Service" must contain at most 63 lowercase letters, digits or dashes and start with a letter`, errInvalidID))
+	})
+
+	t.Run("returns error without template", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{},
+			"service", "create", apiKeyFlag, apiBaseURLFlag, apiCookieFlag, "--project=project-id", "--name=my-service")
+		require.EqualError(t, err, `required flag(s) "from-template" not set`)
+	})
+}
@@ -0,0 +1,534 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errUINotSupported = errors.New("miactl ui requires an interactive terminal")
+
+// uiView is a screen of the ui, from the projects list to the detail of a
+// single deploy.
+type uiView int
+
+const (
+	uiViewProjects uiView = iota
+	uiViewEnvironments
+	uiViewDeploys
+	uiViewDeployDetail
+)
+
+// ui keys, as returned by parseKeys. The other keys are returned as the
+// typed character.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyEnter     = "enter"
+	keyEsc       = "esc"
+	keyBackspace = "backspace"
+	keyCtrlC     = "ctrl-c"
+)
+
+var uiHelp = map[uiView]string{
+	uiViewProjects:     "↑/↓ move  enter open  r refresh  q quit",
+	uiViewEnvironments: "↑/↓ move  enter deploys  t trigger deploy  esc back  r refresh  q quit",
+	uiViewDeploys:      "↑/↓ move  enter inspect  t trigger deploy  esc back  r refresh  q quit",
+	uiViewDeployDetail: "t trigger deploy  esc back  r refresh  q quit",
+}
+
+func newUICmd() *cobra.Command {
+	var refresh time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "ui",
+		Short: "Browse projects, environments and deploys in a full screen terminal interface",
+		Long: `Browse projects, environments and deploys in a full screen terminal interface.
+
+The deploys are refreshed automatically and a new deploy of an environment can be
+triggered with the t key. The deploys of the environments frozen or protected by
+the deploy policy of the current context can't be triggered from the ui.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := GetFactoryFromContext(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+			_, policy, err := contextPolicy()
+			if err != nil {
+				return err
+			}
+			return runUI(os.Stdin, cmd.OutOrStdout(), f, policy, refresh)
+		},
+	}
+
+	cmd.Flags().DurationVar(&refresh, "refresh", 5*time.Second, "time between two automatic refreshes")
+	return cmd
+}
+
+// runUI draws the ui on out until the user quits, reading the keys from in,
+// which must be a terminal.
+func runUI(in *os.File, out io.Writer, f *Factory, policy deployPolicy, refresh time.Duration) error {
+	fd := int(in.Fd())
+	restore, err := makeTerminalRaw(fd)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errUINotSupported, err)
+	}
+	defer restore()
+
+	// alternate screen and hidden cursor, restored on exit
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan string)
+	go readKeys(in, keys)
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+
+	m := newUIModel()
+	m.policy = policy
+	m.refresh(f)
+	for !m.quit {
+		width, height, err := terminalSize(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		drawUI(out, m.render(width, height), width)
+
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			m.handleKey(f, key)
+		case <-ticker.C:
+			m.refresh(f)
+		}
+	}
+	return nil
+}
+
+func readKeys(in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// parseKeys splits the bytes read from a raw terminal in keys.
+func parseKeys(data []byte) []string {
+	keys := []string{}
+	for len(data) > 0 {
+		switch {
+		case bytes.HasPrefix(data, []byte("\x1bOA")):
+			keys, data = append(keys, keyUp), data[3:]
+		case bytes.HasPrefix(data, []byte("\x1bOB")):
+			keys, data = append(keys, keyDown), data[3:]
+		case bytes.HasPrefix(data, []byte("\x1b[")):
+			var final byte
+			final, data = splitCSI(data)
+			switch final {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			}
+			// the other control sequences are ignored
+		case data[0] == 0x1b:
+			keys, data = append(keys, keyEsc), data[1:]
+		case data[0] == '\r', data[0] == '\n':
+			keys, data = append(keys, keyEnter), data[1:]
+		case data[0] == 0x7f, data[0] == 0x08:
+			keys, data = append(keys, keyBackspace), data[1:]
+		case data[0] == 0x03:
+			keys, data = append(keys, keyCtrlC), data[1:]
+		default:
+			r := bytes.Runes(data)[0]
+			keys, data = append(keys, string(r)), data[len(string(r)):]
+		}
+	}
+	return keys
+}
+
+// splitCSI returns the final byte of the control sequence at the start of
+// data, which begins with ESC [, and the data following it. A sequence
+// truncated by the read has no final byte and consumes all data.
+func splitCSI(data []byte) (byte, []byte) {
+	for i := 2; i < len(data); i++ {
+		if data[i] >= 0x40 && data[i] <= 0x7e {
+			return data[i], data[i+1:]
+		}
+	}
+	return 0, nil
+}
+
+// drawUI clears the screen and writes the lines, cut to width. The selected
+// row, starting with the cursor marker, is highlighted.
+func drawUI(out io.Writer, lines []string, width int) {
+	var buf bytes.Buffer
+	buf.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if runes := []rune(line); len(runes) > width {
+			line = string(runes[:width])
+		}
+		if strings.HasPrefix(line, "> ") {
+			line = fmt.Sprintf("\x1b[7m%s\x1b[0m", line)
+		}
+		buf.WriteString(line)
+		if i < len(lines)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	out.Write(buf.Bytes())
+}
+
+// uiModel is the state of the ui. It is updated by the keys and the
+// refreshes, and rendered as lines of text.
+type uiModel struct {
+	view        uiView
+	cursors     map[uiView]int
+	projects    sdk.Projects
+	project     sdk.Project
+	history     []sdk.DeployItem
+	environment string
+	deploy      sdk.DeployItem
+	policy      deployPolicy
+
+	// inputActive is set while the revision to deploy is typed
+	inputActive bool
+	input       string
+	status      string
+	quit        bool
+}
+
+func newUIModel() *uiModel {
+	return &uiModel{cursors: map[uiView]int{}}
+}
+
+// refresh fetches the data of the current view, keeping the selection.
+func (m *uiModel) refresh(f *Factory) {
+	if m.view == uiViewProjects {
+		projects, err := f.MiaClient.Projects.Get()
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		m.projects = filterProjectsByCompany(projects, companyID)
+	} else {
+		history, err := f.MiaClient.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: m.project.ProjectID})
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		m.history = history
+		for _, deploy := range history {
+			if deploy.ID == m.deploy.ID {
+				m.deploy = deploy
+			}
+		}
+	}
+	m.clampCursor()
+}
+
+func (m *uiModel) handleKey(f *Factory, key string) {
+	if key == keyCtrlC {
+		m.quit = true
+		return
+	}
+	if m.inputActive {
+		m.handleInputKey(f, key)
+		return
+	}
+
+	switch key {
+	case "q":
+		m.quit = true
+	case keyUp, "k":
+		m.cursors[m.view]--
+		m.clampCursor()
+	case keyDown, "j":
+		m.cursors[m.view]++
+		m.clampCursor()
+	case keyEnter:
+		m.open(f)
+	case keyEsc, keyBackspace:
+		if m.view > uiViewProjects {
+			m.view--
+			m.status = ""
+		}
+	case "r":
+		m.refresh(f)
+	case "t":
+		if m.view != uiViewProjects && m.targetEnvironment() != "" {
+			m.inputActive = true
+			m.input = ""
+		}
+	}
+}
+
+func (m *uiModel) handleInputKey(f *Factory, key string) {
+	switch key {
+	case keyEsc:
+		m.inputActive = false
+	case keyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case keyEnter:
+		m.inputActive = false
+		if m.input != "" {
+			m.trigger(f, strings.TrimSpace(m.input))
+		}
+	case keyUp, keyDown:
+	default:
+		m.input += key
+	}
+}
+
+// open drills into the selected row.
+func (m *uiModel) open(f *Factory) {
+	cursor := m.cursors[m.view]
+	switch m.view {
+	case uiViewProjects:
+		if cursor >= len(m.projects) {
+			return
+		}
+		m.project = m.projects[cursor]
+		m.history = nil
+		m.view = uiViewEnvironments
+		m.cursors[uiViewEnvironments] = 0
+		m.status = ""
+		m.refresh(f)
+	case uiViewEnvironments:
+		if cursor >= len(m.project.Environments) {
+			return
+		}
+		m.environment = m.project.Environments[cursor].EnvID
+		m.view = uiViewDeploys
+		m.cursors[uiViewDeploys] = 0
+	case uiViewDeploys:
+		deploys := m.environmentDeploys()
+		if cursor >= len(deploys) {
+			return
+		}
+		m.deploy = deploys[cursor]
+		m.view = uiViewDeployDetail
+	}
+}
+
+func (m *uiModel) trigger(f *Factory, revision string) {
+	env := m.targetEnvironment()
+	// the freeze override and the confirmation phrase need the command line
+	if freeze := m.policy.activeFreeze(env, timeNow()); freeze != nil {
+		m.status = fmt.Sprintf("%s: %s is in the freeze %s", errDeployFrozen, env, freeze.Name)
+		return
+	}
+	if m.policy.confirmation(env) != "" {
+		m.status = fmt.Sprintf("%s is protected, deploy it with the miactl commands", env)
+		return
+	}
+	var deployType string
+	for _, deploy := range m.history {
+		if deploy.Environment == env {
+			deployType = deploy.DeployType
+			break
+		}
+	}
+
+	response, err := f.MiaClient.Deploy.Trigger(m.project.ProjectID, sdk.DeployConfig{
+		Environment: env,
+		Revision:    revision,
+		DeployType:  deployType,
+	})
+	problems := auditUITrigger(m.project.ProjectID, env, revision, err)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.status = fmt.Sprintf("Deploy pipeline #%d of %s triggered on %s: %s", response.ID, revision, env, response.URL)
+	if len(problems) > 0 {
+		m.status += fmt.Sprintf(" (%s)", strings.Join(problems, ", "))
+	}
+	m.refresh(f)
+}
+
+// auditUITrigger writes the audit entry of a deploy triggered from the ui,
+// as the ui deploy command, returning the description of the failures.
+func auditUITrigger(project, env, revision string, err error) []string {
+	entry := auditEntry{
+		Time:    timeNow(),
+		User:    currentUsername(),
+		Context: viper.GetString(currentContextKey),
+		Project: project,
+		Command: "ui deploy",
+		Args:    []string{fmt.Sprintf("--env=%s", env), fmt.Sprintf("--revision=%s", revision)},
+		Result:  auditResultSuccess,
+	}
+	if err != nil {
+		entry.Result, entry.Error = auditResultError, err.Error()
+	}
+	return writeAuditEntry(entry)
+}
+
+// targetEnvironment is the environment where a deploy is triggered: the
+// selected one in the environments view, the open one otherwise.
+func (m *uiModel) targetEnvironment() string {
+	if m.view == uiViewEnvironments {
+		cursor := m.cursors[uiViewEnvironments]
+		if cursor < len(m.project.Environments) {
+			return m.project.Environments[cursor].EnvID
+		}
+		return ""
+	}
+	return m.environment
+}
+
+func (m *uiModel) environmentDeploys() []sdk.DeployItem {
+	deploys := []sdk.DeployItem{}
+	for _, deploy := range m.history {
+		if deploy.Environment == m.environment {
+			deploys = append(deploys, deploy)
+		}
+	}
+	return deploys
+}
+
+func (m *uiModel) clampCursor() {
+	_, rows := m.table()
+	cursor := m.cursors[m.view]
+	if cursor >= len(rows) {
+		cursor = len(rows) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.cursors[m.view] = cursor
+}
+
+// table returns the headers and the rows of the current view.
+func (m *uiModel) table() ([]string, [][]string) {
+	rows := [][]string{}
+	switch m.view {
+	case uiViewProjects:
+		for _, project := range m.projects {
+			rows = append(rows, []string{project.Name, project.ProjectID, project.TenantID})
+		}
+		return []string{"Name", "Project id", "Company id"}, rows
+	case uiViewEnvironments:
+		for _, env := range m.project.Environments {
+			row := []string{env.EnvID, env.DisplayName, "", ""}
+			for _, deploy := range m.history {
+				if deploy.Environment == env.EnvID {
+					row[2], row[3] = deploy.Ref, deploy.Status
+					break
+				}
+			}
+			rows = append(rows, row)
+		}
+		return []string{"Environment", "Name", "Last Deploy", "Status"}, rows
+	case uiViewDeploys:
+		for _, deploy := range m.environmentDeploys() {
+			rows = append(rows, deployRow(deploy))
+		}
+		return deploymentsHeaders, rows
+	default:
+		deploy := m.deploy
+		rows = [][]string{
+			{"Deploy", strconv.Itoa(deploy.ID)},
+			{"Status", deploy.Status},
+			{"Environment", deploy.Environment},
+			{"Branch/Tag", deploy.Ref},
+			{"Commit", deploy.Commit.Hash},
+			{"Deploy Type", deploy.DeployType},
+			{"Made By", deploy.User.Name},
+			{"Duration", (time.Duration(deploy.Duration) * time.Second).String()},
+			{"Finished At", renderer.FormatDate(deploy.FinishedAt)},
+			{"View Log", deploy.WebURL},
+		}
+		return nil, rows
+	}
+}
+
+func (m *uiModel) breadcrumb() string {
+	parts := []string{"Projects"}
+	if m.view >= uiViewEnvironments {
+		parts = append(parts, m.project.Name)
+	}
+	if m.view >= uiViewDeploys {
+		parts = append(parts, m.environment)
+	}
+	if m.view == uiViewDeployDetail {
+		parts = append(parts, fmt.Sprintf("#%d", m.deploy.ID))
+	}
+	return strings.Join(parts, " > ")
+}
+
+// render returns the height lines of the screen: the breadcrumb, the table
+// of the current view scrolled to the selected row, the status and the
+// keys help.
+func (m *uiModel) render(width, height int) []string {
+	headers, rows := m.table()
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	if headers != nil {
+		fmt.Fprintf(w, "  %s\n", strings.ToUpper(strings.Join(headers, "\t")))
+	}
+	cursor := m.cursors[m.view]
+	for i, row := range rows {
+		marker := "  "
+		if i == cursor && m.view != uiViewDeployDetail {
+			marker = "> "
+		}
+		fmt.Fprintf(w, "%s%s\n", marker, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	tableLines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+
+	var header []string
+	if headers != nil {
+		header, tableLines = tableLines[:1], tableLines[1:]
+	}
+	visible := height - 4 - len(header)
+	if visible < 1 {
+		visible = 1
+	}
+	if len(tableLines) > visible {
+		start := 0
+		if cursor >= visible {
+			start = cursor - visible + 1
+		}
+		tableLines = tableLines[start : start+visible]
+	}
+
+	lines := append([]string{m.breadcrumb(), ""}, header...)
+	lines = append(lines, tableLines...)
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	status := m.status
+	if m.inputActive {
+		status = fmt.Sprintf("Revision to deploy on %s: %s", m.targetEnvironment(), m.input)
+	}
+	return append(lines, status, uiHelp[m.view])
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package cmd
+
+import "errors"
+
+func makeTerminalRaw(fd int) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func terminalSize(fd int) (int, int, error) {
+	return 0, 0, errors.New("terminal size is not supported on this platform")
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		keys []string
+	}{
+		{"keys", "\x1b[A\x1bOB\r\x1b\x7f\x03qè", []string{keyUp, keyDown, keyEnter, keyEsc, keyBackspace, keyCtrlC, "q", "è"}},
+		{"ignored sequence", "\x1b[Cj", []string{"j"}},
+		{"sequence with parameters", "\x1b[3~j", []string{"j"}},
+		{"arrow with modifiers", "\x1b[1;2Aj", []string{keyUp, "j"}},
+		{"truncated sequence", "\x1b[", []string{}},
+		{"truncated sequence with parameters", "j\x1b[1;", []string{"j"}},
+		{"truncated ss3 sequence", "\x1bO", []string{keyEsc, "O"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.keys, parseKeys([]byte(testCase.data)))
+		})
+	}
+}
+
+func TestDrawUI(t *testing.T) {
+	buf := &bytes.Buffer{}
+	drawUI(buf, []string{"Projects", "> selected row", "  other row"}, 10)
+	require.Equal(t, "\x1b[H\x1b[2JProjects\r\n\x1b[7m> selected\x1b[0m\r\n  other ro", buf.String())
+}
+
+func TestUIModel(t *testing.T) {
+	projects := sdk.Projects{
+		{
+			Name:      "Project 1",
+			ProjectID: "project-1",
+			TenantID:  "company-1",
+			Environments: []sdk.Environment{
+				{EnvID: "development", DisplayName: "Development"},
+				{EnvID: "production", DisplayName: "Production"},
+			},
+		},
+		{Name: "Project 2", ProjectID: "project-2", TenantID: "company-1"},
+	}
+	finishedAt := time.Date(2020, 4, 30, 10, 0, 0, 0, time.UTC)
+	history := []sdk.DeployItem{
+		{ID: 3, Status: sdk.DeployStatusRunning, Ref: "v1.1.0", Environment: "production", DeployType: "smart_deploy", FinishedAt: finishedAt},
+		{ID: 2, Status: sdk.DeployStatusSuccess, Ref: "v1.0.0", Environment: "production", DeployType: "smart_deploy", Duration: 90, FinishedAt: finishedAt, Commit: sdk.CommitInfo{Hash: "abc123"}, WebURL: "https://web.url/2"},
+		{ID: 1, Status: sdk.DeployStatusSuccess, Ref: "main", Environment: "development", FinishedAt: finishedAt},
+	}
+
+	var triggered []sdk.DeployConfig
+	client, err := sdk.WrapperMockMiaClient(sdk.MockClientError{
+		Projects:      projects,
+		DeployHistory: history,
+		DeployAssertFn: func(query sdk.DeployHistoryQuery) {
+			require.Equal(t, "project-1", query.ProjectID)
+		},
+		DeployTriggerAssertFn: func(projectID string, cfg sdk.DeployConfig) {
+			require.Equal(t, "project-1", projectID)
+			triggered = append(triggered, cfg)
+		},
+		DeployTriggerResponse: sdk.DeployResponse{ID: 4, URL: "https://pipeline.url/4"},
+	})(sdk.Options{})
+	require.NoError(t, err)
+	f := &Factory{MiaClient: client}
+
+	m := newUIModel()
+	m.refresh(f)
+	require.Equal(t, []string{
+		"Projects",
+		"",
+		"  NAME       PROJECT ID  COMPANY ID",
+		"> Project 1  project-1   company-1",
+		"  Project 2  project-2   company-1",
+		"",
+		"",
+		uiHelp[uiViewProjects],
+	}, m.render(80, 8))
+
+	t.Run("drills into environments and deploys", func(t *testing.T) {
+		m.handleKey(f, keyDown)
+		m.handleKey(f, keyDown)
+		require.Equal(t, 1, m.cursors[uiViewProjects])
+		m.handleKey(f, keyUp)
+		m.handleKey(f, keyEnter)
+
+		require.Equal(t, []string{
+			"Projects > Project 1",
+			"",
+			"  ENVIRONMENT  NAME         LAST DEPLOY  STATUS",
+			"> development  Development  main         success",
+			"  production   Production   v1.1.0       running",
+			"",
+			uiHelp[uiViewEnvironments],
+		}, m.render(80, 7))
+
+		m.handleKey(f, "j")
+		m.handleKey(f, keyEnter)
+		require.Equal(t, uiViewDeploys, m.view)
+		require.Equal(t, []string{">", "3", "running", "smart_deploy", "production", "v1.1.0", "0s", "30", "Apr", "2020", "10:00", "UTC"}, strings.Fields(m.render(200, 6)[3]))
+
+		m.handleKey(f, keyDown)
+		m.handleKey(f, keyEnter)
+		require.Equal(t, uiViewDeployDetail, m.view)
+		lines := m.render(80, 14)
+		require.Equal(t, "Projects > Project 1 > production > #2", lines[0])
+		require.Contains(t, lines, "  Commit       abc123")
+		require.Contains(t, lines, "  Duration     1m30s")
+		require.Contains(t, lines, "  View Log     https://web.url/2")
+
+		m.handleKey(f, keyEsc)
+		require.Equal(t, uiViewDeploys, m.view)
+		require.Equal(t, 1, m.cursors[uiViewDeploys])
+	})
+
+	t.Run("triggers a deploy of the typed revision", func(t *testing.T) {
+		timeNow = func() time.Time { return time.Date(2020, 04, 25, 12, 00, 00, 00, time.UTC) }
+		defer func() { timeNow = time.Now }()
+		readEntries, cleanup := useAuditLog(t)
+		defer cleanup()
+
+		m.handleKey(f, "t")
+		for _, key := range []string{"v", "2", "x", keyBackspace, ".", "0"} {
+			m.handleKey(f, key)
+		}
+		lines := m.render(80, 6)
+		require.Equal(t, "Revision to deploy on production: v2.0", lines[4])
+
+		m.handleKey(f, keyEnter)
+		require.Equal(t, []sdk.DeployConfig{{Environment: "production", Revision: "v2.0", DeployType: "smart_deploy"}}, triggered)
+		require.Equal(t, "Deploy pipeline #4 of v2.0 triggered on production: https://pipeline.url/4", m.render(80, 6)[4])
+		require.Equal(t, []auditEntry{{
+			Time:    timeNow(),
+			Project: "project-1",
+			Command: "ui deploy",
+			Args:    []string{"--env=production", "--revision=v2.0"},
+			Result:  auditResultSuccess,
+		}}, readEntries())
+
+		m.handleKey(f, "t")
+		m.handleKey(f, "x")
+		m.handleKey(f, keyEsc)
+		require.False(t, m.inputActive)
+		require.Len(t, triggered, 1)
+	})
+
+	t.Run("does not trigger a deploy of a frozen or protected environment", func(t *testing.T) {
+		m.policy = deployPolicy{Freezes: []freezeWindow{{Name: "always", Schedule: "* * * * *"}}}
+		require.Empty(t, m.policy.compile())
+		m.handleKey(f, "t")
+		m.handleKey(f, "v")
+		m.handleKey(f, keyEnter)
+		require.Len(t, triggered, 1)
+		require.Equal(t, fmt.Sprintf("%s: production is in the freeze always", errDeployFrozen), m.render(80, 6)[4])
+
+		m.policy = deployPolicy{ProtectedEnvironments: []protectedEnvironment{{Environment: "production", Confirmation: "deploy"}}}
+		m.handleKey(f, "t")
+		m.handleKey(f, "v")
+		m.handleKey(f, keyEnter)
+		require.Len(t, triggered, 1)
+		require.Equal(t, "production is protected, deploy it with the miactl commands", m.render(80, 6)[4])
+		m.policy = deployPolicy{}
+	})
+
+	t.Run("scrolls to the selected row", func(t *testing.T) {
+		m.view = uiViewProjects
+		m.cursors[uiViewProjects] = 1
+		lines := m.render(80, 6)
+		require.Equal(t, []string{"Projects", "", "  NAME       PROJECT ID  COMPANY ID", "> Project 2  project-2   company-1"}, lines[:4])
+	})
+
+	t.Run("quits", func(t *testing.T) {
+		m.handleKey(f, "q")
+		require.True(t, m.quit)
+	})
+}
+
+func TestUIModelErrors(t *testing.T) {
+	client, err := sdk.WrapperMockMiaClient(sdk.MockClientError{
+		ProjectsError: sdk.ErrHTTP,
+	})(sdk.Options{})
+	require.NoError(t, err)
+
+	m := newUIModel()
+	m.refresh(&Factory{MiaClient: client})
+	lines := m.render(80, 5)
+	require.Equal(t, sdk.ErrHTTP.Error(), lines[3])
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// watchOptions controls the polling of list commands.
+type watchOptions struct {
+	enabled  bool
+	interval time.Duration
+	events   bool
+}
+
+func addWatchFlags(cmd *cobra.Command, watch *watchOptions) {
+	cmd.Flags().BoolVarP(&watch.enabled, "watch", "w", false, "after listing, keep polling and print the rows which change")
+	cmd.Flags().DurationVar(&watch.interval, "watch-interval", 5*time.Second, "time between two polls in watch mode")
+	cmd.Flags().BoolVar(&watch.events, "watch-events", false, "in watch mode, print a json line for each status transition instead of table rows")
+}
+
+// watchRow is a single row of a watched list, identified by ID.
+type watchRow struct {
+	ID     string
+	Status string
+	Cells  []string
+	// Values are the cells compared between polls, Cells when nil. The
+	// cells depending on the position of the row, like its index, are left
+	// out, so that inserting a row does not change the following ones.
+	Values []string
+}
+
+func (r watchRow) values() []string {
+	if r.Values != nil {
+		return r.Values
+	}
+	return r.Cells
+}
+
+// rowChange is a row which is new, when Previous is nil, or differs from the
+// previous poll.
+type rowChange struct {
+	Previous *watchRow
+	Current  watchRow
+}
+
+// watchResource calls fetch every interval until ctx is done and passes to
+// onPoll the rows changed since the previous poll. On the first poll every
+// row is reported as new. Fetch errors are passed to onError and do not stop
+// the watch.
+func watchResource(ctx context.Context, interval time.Duration, fetch func() ([]watchRow, error), onPoll func(changes []rowChange, first bool), onError func(error)) {
+	var previous map[string]watchRow
+	for {
+		rows, err := fetch()
+		if err != nil {
+			onError(err)
+		} else {
+			current := make(map[string]watchRow, len(rows))
+			changes := []rowChange{}
+			for _, row := range rows {
+				current[row.ID] = row
+				old, found := previous[row.ID]
+				switch {
+				case !found:
+					changes = append(changes, rowChange{Current: row})
+				case !equalCells(old.values(), row.values()):
+					old := old
+					changes = append(changes, rowChange{Previous: &old, Current: row})
+				}
+			}
+			onPoll(changes, previous == nil)
+			previous = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func equalCells(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mia-platform/miactl/renderer"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+// historySequenceMock returns a different history on each GetHistory call
+// and cancels the watch once all of them have been returned.
+type historySequenceMock struct {
+	sdk.DeployMock
+	histories [][]sdk.DeployItem
+	calls     int
+	cancel    context.CancelFunc
+}
+
+func (m *historySequenceMock) GetHistory(query sdk.DeployHistoryQuery) ([]sdk.DeployItem, error) {
+	history := m.histories[m.calls]
+	m.calls++
+	if m.calls == len(m.histories) {
+		m.cancel()
+	}
+	if history == nil {
+		return nil, errors.New("history error")
+	}
+	return history, nil
+}
+
+func TestWatchResource(t *testing.T) {
+	t.Run("reports new and changed rows", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		polls := [][]watchRow{
+			{{ID: "1", Cells: []string{"1", "running"}}, {ID: "2", Cells: []string{"2", "success"}}},
+			nil,
+			{{ID: "1", Cells: []string{"1", "success"}}, {ID: "2", Cells: []string{"2", "success"}}},
+			{{ID: "3", Cells: []string{"3", "pending"}}, {ID: "1", Cells: []string{"1", "success"}}},
+		}
+		var calls int
+		fetch := func() ([]watchRow, error) {
+			rows := polls[calls]
+			calls++
+			if calls == len(polls) {
+				cancel()
+			}
+			if rows == nil {
+				return nil, errors.New("fetch error")
+			}
+			return rows, nil
+		}
+
+		var changes [][]rowChange
+		var firsts []bool
+		var errs []error
+		watchResource(ctx, time.Millisecond, fetch, func(c []rowChange, first bool) {
+			changes = append(changes, c)
+			firsts = append(firsts, first)
+		}, func(err error) {
+			errs = append(errs, err)
+		})
+
+		require.Equal(t, 4, calls)
+		require.Equal(t, []error{errors.New("fetch error")}, errs)
+		require.Equal(t, []bool{true, false, false}, firsts)
+		require.Equal(t, [][]rowChange{
+			{{Current: polls[0][0]}, {Current: polls[0][1]}},
+			{{Previous: &polls[0][0], Current: polls[2][0]}},
+			{{Current: polls[3][0]}},
+		}, changes)
+	})
+}
+
+// projectsSequenceMock returns different projects on each Get call and
+// cancels the watch once all of them have been returned.
+type projectsSequenceMock struct {
+	sdk.ProjectsMock
+	projects []sdk.Projects
+	calls    int
+	cancel   context.CancelFunc
+}
+
+func (m *projectsSequenceMock) Get() (sdk.Projects, error) {
+	projects := m.projects[m.calls]
+	m.calls++
+	if m.calls == len(m.projects) {
+		m.cancel()
+	}
+	return projects, nil
+}
+
+func TestWatchProjects(t *testing.T) {
+	companyID = ""
+	first := sdk.Project{Name: "First", ProjectID: "first", ConfigurationGitPath: "/first"}
+	second := sdk.Project{Name: "Second", ProjectID: "second", ConfigurationGitPath: "/second"}
+	inserted := sdk.Project{Name: "Inserted", ProjectID: "inserted", ConfigurationGitPath: "/inserted"}
+
+	buf := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := &Factory{
+		Renderer: renderer.New(buf),
+		MiaClient: &sdk.MiaClient{
+			Projects: &projectsSequenceMock{
+				projects: []sdk.Projects{{first, second}, {inserted, first, second}},
+				cancel:   cancel,
+			},
+		},
+	}
+	watchProjects(ctx, f, watchOptions{enabled: true, interval: time.Millisecond})
+
+	require.Equal(t, []string{
+		"# | NAME | CONFIGURATION GIT PATH | PROJECT ID",
+		"1 | First | /first | first",
+		"2 | Second | /second | second",
+		"1 | Inserted | /inserted | inserted",
+	}, renderer.CleanTableRows(buf.String()))
+}
+
+func TestWatchDeployments(t *testing.T) {
+	timeNow = func() time.Time { return reportTestNow }
+	defer func() { timeNow = time.Now }()
+	projectID = "project-id"
+
+	running := sdk.DeployItem{ID: 1, Status: sdk.DeployStatusRunning, Ref: "v1.0.0", Environment: "production", WebURL: "https://web.url/"}
+	succeeded := running
+	succeeded.Status = sdk.DeployStatusSuccess
+	pending := sdk.DeployItem{ID: 2, Status: sdk.DeployStatusPending, Ref: "v1.1.0", Environment: "development"}
+
+	setup := func() (context.Context, *Factory, *bytes.Buffer) {
+		buf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+
+		f := &Factory{
+			Renderer: renderer.New(buf),
+			MiaClient: &sdk.MiaClient{
+				Deploy: &historySequenceMock{
+					histories: [][]sdk.DeployItem{{running}, nil, {running}, {pending, succeeded}},
+					cancel:    cancel,
+				},
+			},
+		}
+		return ctx, f, buf
+	}
+
+	t.Run("renders changed rows", func(t *testing.T) {
+		watch := watchOptions{enabled: true, interval: time.Millisecond}
+		ctx, f, buf := setup()
+		watchDeploysForProject(ctx, buf, f, watch)
+
+		rows := renderer.CleanTableRows(buf.String())
+		require.Equal(t, []string{
+			"# | STATUS | DEPLOY TYPE | ENVIRONMENT | DEPLOY BRANCH/TAG | MADE BY | DURATION | FINISHED AT | VIEW LOG",
+			"1 | running |  | production | v1.0.0 |  | 0s | 01 Jan 0001 00:00 UTC | https://web.url/",
+			"history error",
+			"2 | pending |  | development | v1.1.0 |  | 0s | 01 Jan 0001 00:00 UTC",
+			"1 | success |  | production | v1.0.0 |  | 0s | 01 Jan 0001 00:00 UTC | https://web.url/",
+		}, rows)
+	})
+
+	t.Run("emits status transitions as json lines", func(t *testing.T) {
+		watch := watchOptions{enabled: true, interval: time.Millisecond, events: true}
+		ctx, f, buf := setup()
+		watchDeploysForProject(ctx, buf, f, watch)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Equal(t, []string{
+			"history error",
+			`{"type":"deploy.status","projectId":"project-id","deployId":2,"environment":"development","ref":"v1.1.0","from":"","to":"pending","webUrl":"","time":"2020-05-01T00:00:00Z"}`,
+			`{"type":"deploy.status","projectId":"project-id","deployId":1,"environment":"production","ref":"v1.0.0","from":"running","to":"success","webUrl":"https://web.url/","time":"2020-05-01T00:00:00Z"}`,
+		}, lines)
+	})
+}
+
+func TestGetWatchFlags(t *testing.T) {
+	t.Run("returns error if watch events are requested without watch", func(t *testing.T) {
+		_, err := executeRootCommandWithContext(sdk.MockClientError{}, "get", "projects", "--watch-events", apiKeyFlag, apiBaseURLFlag, apiCookieFlag)
+		require.EqualError(t, err, "--watch-events requires --watch")
+	})
+}
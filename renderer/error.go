@@ -39,7 +39,7 @@ func NewError(writer io.Writer, err error) IError {
 	switch true {
 	case errors.As(err, &httpErr):
 		return &writeError{
-			Message: httpErrorMessage(httpErr),
+			Message: httpErrorMessage(err, httpErr),
 			writer:  writer,
 		}
 	case errors.Is(err, sdk.ErrCreateClient):
@@ -54,11 +54,13 @@ func NewError(writer io.Writer, err error) IError {
 	}
 }
 
-func httpErrorMessage(httpErr *jsonclient.HTTPError) string {
+// httpErrorMessage returns the message of err, wrapping httpErr: the sdk
+// errors wrap the jsonclient one to add the response body.
+func httpErrorMessage(err error, httpErr *jsonclient.HTTPError) string {
 	switch httpErr.StatusCode {
 	case 401:
 		return "Unauthorized access, returns 401. Please check your credentials."
 	default:
-		return httpErr.Error()
+		return err.Error()
 	}
 }
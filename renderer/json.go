@@ -0,0 +1,13 @@
+package renderer
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// NewJSON writes v to writer as indented json
+func NewJSON(writer io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
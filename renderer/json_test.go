@@ -0,0 +1,32 @@
+package renderer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJSON(t *testing.T) {
+	t.Run("render indented json", func(t *testing.T) {
+		var b bytes.Buffer
+		err := NewJSON(&b, map[string]interface{}{"key": "value", "list": []int{1, 2}})
+		require.NoError(t, err)
+
+		expected := `{
+  "key": "value",
+  "list": [
+    1,
+    2
+  ]
+}
+`
+		require.Equal(t, expected, b.String())
+	})
+
+	t.Run("returns error if value cannot be encoded", func(t *testing.T) {
+		var b bytes.Buffer
+		err := NewJSON(&b, make(chan int))
+		require.Error(t, err)
+	})
+}
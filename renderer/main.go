@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"encoding/csv"
 	"io"
 
 	"github.com/olekukonko/tablewriter"
@@ -10,6 +11,9 @@ import (
 type IRenderer interface {
 	Error(err error) IError
 	Table(headersString []string) *tablewriter.Table
+	JSON(v interface{}) error
+	YAML(v interface{}) error
+	CSV() *csv.Writer
 }
 
 // Renderer implementation of IRenderer interface
@@ -27,6 +31,21 @@ func (r *Renderer) Table(headersString []string) *tablewriter.Table {
 	return NewTable(r.writer, headersString)
 }
 
+// JSON method writes v as indented json
+func (r *Renderer) JSON(v interface{}) error {
+	return NewJSON(r.writer, v)
+}
+
+// YAML method writes v as yaml
+func (r *Renderer) YAML(v interface{}) error {
+	return NewYAML(r.writer, v)
+}
+
+// CSV method create a new csv writer
+func (r *Renderer) CSV() *csv.Writer {
+	return csv.NewWriter(r.writer)
+}
+
 // New create the renderer implementation
 func New(writer io.Writer) IRenderer {
 	return &Renderer{
@@ -36,6 +36,25 @@ func TestNew(t *testing.T) {
 		require.Equal(t, expected.String(), buf.String())
 	})
 
+	t.Run("JSON method writes json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		r := New(buf)
+		require.NoError(t, r.JSON([]string{"v1"}))
+
+		require.Equal(t, "[\n  \"v1\"\n]\n", buf.String())
+	})
+
+	t.Run("CSV method returns csv writer", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		r := New(buf)
+		w := r.CSV()
+		w.Write([]string{"h1", "h2"})
+		w.Write([]string{"v1", "v,2"})
+		w.Flush()
+
+		require.Equal(t, "h1,h2\nv1,\"v,2\"\n", buf.String())
+	})
+
 	t.Run("render table with correct headers", func(t *testing.T) {
 		var b bytes.Buffer
 		headers := []string{"h1", "h2", "h3"}
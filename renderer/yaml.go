@@ -0,0 +1,27 @@
+package renderer
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NewYAML writes v to writer as yaml. The value is converted through json,
+// so that the yaml keys are the same of the json output.
+func NewYAML(writer io.Writer, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return err
+	}
+	content, err = yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
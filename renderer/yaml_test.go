@@ -0,0 +1,40 @@
+package renderer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewYAML(t *testing.T) {
+	t.Run("render yaml with the json keys", func(t *testing.T) {
+		var b bytes.Buffer
+		value := []struct {
+			Name      string    `json:"name"`
+			ProjectID string    `json:"projectId"`
+			Tags      []string  `json:"tags"`
+			CreatedAt time.Time `json:"createdAt"`
+		}{
+			{Name: "Project", ProjectID: "project-id", Tags: []string{"a", "b"}, CreatedAt: time.Date(2020, 4, 24, 21, 0, 0, 0, time.UTC)},
+		}
+		err := NewYAML(&b, value)
+		require.NoError(t, err)
+
+		expected := `- createdAt: "2020-04-24T21:00:00Z"
+  name: Project
+  projectId: project-id
+  tags:
+  - a
+  - b
+`
+		require.Equal(t, expected, b.String())
+	})
+
+	t.Run("returns error if value cannot be encoded", func(t *testing.T) {
+		var b bytes.Buffer
+		err := NewYAML(&b, make(chan int))
+		require.Error(t, err)
+	})
+}
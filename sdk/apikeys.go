@@ -0,0 +1,95 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// APIKey is a project api key, used as client-key by the console clients.
+// Key is returned only when the api key is created or rotated.
+type APIKey struct {
+	ID        string    `json:"_id"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"createdAt"`
+	Key       string    `json:"key,omitempty"`
+}
+
+// APIKeys is a list of api key
+type APIKeys []APIKey
+
+// CreateAPIKeyRequest holds the settings of an api key to be created
+type CreateAPIKeyRequest struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+// APIKeysClient is the console implementations of the IAPIKeys interface
+type APIKeysClient struct {
+	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
+	projects   *projectCache
+}
+
+// List method to fetch the api keys of the project
+func (a APIKeysClient) List(projectID string) (APIKeys, error) {
+	path, err := a.apiKeysPath(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := APIKeys{}
+	if err := doRequest(a.HTTPClient, a.JSONClient, http.MethodGet, path, nil, &apiKeys); err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// Create method creates a new api key in the project
+func (a APIKeysClient) Create(projectID string, request CreateAPIKeyRequest) (*APIKey, error) {
+	path, err := a.apiKeysPath(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &APIKey{}
+	if err := doRequest(a.HTTPClient, a.JSONClient, http.MethodPost, path, request, apiKey); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+// Delete method deletes the api key from the project
+func (a APIKeysClient) Delete(projectID, apiKeyID string) error {
+	path, err := a.apiKeysPath(projectID)
+	if err != nil {
+		return err
+	}
+	return doRequest(a.HTTPClient, a.JSONClient, http.MethodDelete, fmt.Sprintf("%s%s/", path, url.PathEscape(apiKeyID)), nil, nil)
+}
+
+// Rotate method replaces the key of the api key, keeping its name and roles
+func (a APIKeysClient) Rotate(projectID, apiKeyID string) (*APIKey, error) {
+	path, err := a.apiKeysPath(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &APIKey{}
+	if err := doRequest(a.HTTPClient, a.JSONClient, http.MethodPost, fmt.Sprintf("%s%s/rotate/", path, url.PathEscape(apiKeyID)), nil, apiKey); err != nil {
+		return nil, err
+	}
+	return apiKey, nil
+}
+
+func (a APIKeysClient) apiKeysPath(projectID string) (string, error) {
+	project, err := a.projects.get(a.HTTPClient, a.JSONClient, projectID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("api/backend/projects/%s/api-keys/", url.PathEscape(project.ID)), nil
+}
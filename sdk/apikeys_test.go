@@ -0,0 +1,153 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	projectsListResponseBody := readTestData(t, "projects.json")
+	projectRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.True(t, strings.HasSuffix(req.URL.Path, "/projects/"))
+		require.Equal(t, http.MethodGet, req.Method)
+	}
+	apiKeysRequestAssertions := func(method, path string) func(t *testing.T, req *http.Request) {
+		return func(t *testing.T, req *http.Request) {
+			t.Helper()
+			require.Equal(t, path, req.URL.Path)
+			require.Equal(t, method, req.Method)
+			cookieSid, err := req.Cookie("sid")
+			require.NoError(t, err)
+			require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+		}
+	}
+	testServer := func(t *testing.T, assertions assertionFn, body string, status int) string {
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: assertions, body: body, status: status},
+		})
+		t.Cleanup(s.Close)
+		return fmt.Sprintf("%s/", s.URL)
+	}
+
+	t.Run("correctly returns api keys", func(t *testing.T) {
+		url := testServer(t, apiKeysRequestAssertions(http.MethodGet, "/api/backend/projects/mongo-id-2/api-keys/"), readTestData(t, "api-keys.json"), 200)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKeys, err := client.List("project-2")
+		require.NoError(t, err)
+		require.Equal(t, APIKeys{
+			{ID: "key-1", Name: "ci", Roles: []string{RoleDeveloper}, CreatedAt: time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)},
+			{ID: "key-2", Name: "monitoring", Roles: []string{RoleReporter, RoleGuest}, CreatedAt: time.Date(2020, 4, 15, 8, 30, 0, 0, time.UTC)},
+		}, apiKeys)
+	})
+
+	t.Run("Error occurs when projectId does not exist", func(t *testing.T) {
+		s := testCreateResponseServer(t, projectRequestAssertions, projectsListResponseBody, 200)
+		defer s.Close()
+		client := testCreateAPIKeysClient(t, fmt.Sprintf("%s/", s.URL))
+
+		apiKeys, err := client.List("project-NaN")
+		require.Nil(t, apiKeys)
+		require.EqualError(t, err, fmt.Sprintf("%s: project-NaN", ErrProjectNotFound))
+	})
+
+	t.Run("list throws if response body is not as expected", func(t *testing.T) {
+		url := testServer(t, nil, `{"_id":"not-a-list"}`, 200)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKeys, err := client.List("project-2")
+		require.Nil(t, apiKeys)
+		require.True(t, errors.Is(err, ErrGeneric))
+	})
+
+	t.Run("creates api key", func(t *testing.T) {
+		assertions := func(t *testing.T, req *http.Request) {
+			apiKeysRequestAssertions(http.MethodPost, "/api/backend/projects/mongo-id-2/api-keys/")(t, req)
+			var body CreateAPIKeyRequest
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+			require.Equal(t, CreateAPIKeyRequest{Name: "ci", Roles: []string{RoleDeveloper}}, body)
+		}
+		url := testServer(t, assertions, `{"_id":"key-3","name":"ci","roles":["developer"],"key":"the-secret"}`, 200)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKey, err := client.Create("project-2", CreateAPIKeyRequest{Name: "ci", Roles: []string{RoleDeveloper}})
+		require.NoError(t, err)
+		require.Equal(t, &APIKey{ID: "key-3", Name: "ci", Roles: []string{RoleDeveloper}, Key: "the-secret"}, apiKey)
+	})
+
+	t.Run("create throws when server respond with 403", func(t *testing.T) {
+		responseBody := `{"statusCode":403,"error":"Forbidden","message":"Forbidden"}`
+		url := testServer(t, nil, responseBody, 403)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKey, err := client.Create("project-2", CreateAPIKeyRequest{Name: "ci"})
+		require.Nil(t, apiKey)
+		require.EqualError(t, err, fmt.Sprintf("POST %sapi/backend/projects/mongo-id-2/api-keys/: 403 - %s", url, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("deletes api key", func(t *testing.T) {
+		url := testServer(t, apiKeysRequestAssertions(http.MethodDelete, "/api/backend/projects/mongo-id-2/api-keys/key-1/"), "", 204)
+		client := testCreateAPIKeysClient(t, url)
+
+		require.NoError(t, client.Delete("project-2", "key-1"))
+	})
+
+	t.Run("rotates api key", func(t *testing.T) {
+		url := testServer(t, apiKeysRequestAssertions(http.MethodPost, "/api/backend/projects/mongo-id-2/api-keys/key-1/rotate/"), `{"_id":"key-1","name":"ci","roles":["developer"],"key":"new-secret"}`, 200)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKey, err := client.Rotate("project-2", "key-1")
+		require.NoError(t, err)
+		require.Equal(t, &APIKey{ID: "key-1", Name: "ci", Roles: []string{RoleDeveloper}, Key: "new-secret"}, apiKey)
+	})
+
+	t.Run("escapes the api key id", func(t *testing.T) {
+		assertions := func(t *testing.T, req *http.Request) {
+			require.Equal(t, "/api/backend/projects/mongo-id-2/api-keys/key%2F1%3F/", req.URL.EscapedPath())
+		}
+		url := testServer(t, assertions, "", 204)
+		client := testCreateAPIKeysClient(t, url)
+
+		require.NoError(t, client.Delete("project-2", "key/1?"))
+	})
+
+	t.Run("resolves the project once", func(t *testing.T) {
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: apiKeysRequestAssertions(http.MethodDelete, "/api/backend/projects/mongo-id-2/api-keys/key-1/"), status: 204},
+			{assertions: apiKeysRequestAssertions(http.MethodDelete, "/api/backend/projects/mongo-id-2/api-keys/key-2/"), status: 204},
+		})
+		defer s.Close()
+		client := testCreateAPIKeysClient(t, fmt.Sprintf("%s/", s.URL))
+
+		require.NoError(t, client.Delete("project-2", "key-1"))
+		require.NoError(t, client.Delete("project-2", "key-2"))
+	})
+
+	t.Run("rotate throws when server respond with 404", func(t *testing.T) {
+		url := testServer(t, nil, `{"statusCode":404,"error":"Not Found","message":"api key not found"}`, 404)
+		client := testCreateAPIKeysClient(t, url)
+
+		apiKey, err := client.Rotate("project-2", "key-1")
+		require.Nil(t, apiKey)
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+}
+
+func testCreateAPIKeysClient(t *testing.T, url string) IAPIKeys {
+	t.Helper()
+	return APIKeysClient{
+		JSONClient: testCreateClient(t, url),
+		projects:   newProjectCache(),
+	}
+}
@@ -0,0 +1,217 @@
+package sdk
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	tokenPath           = "api/m2m/oauth/token"
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	// assertionLifetime is the validity of the signed JWT assertion
+	assertionLifetime = 5 * time.Minute
+	// tokenExpiryMargin avoids to use a cached token which expires during
+	// the command execution
+	tokenExpiryMargin = time.Minute
+)
+
+// ErrAuthentication is the error returned when the client credentials can
+// not be exchanged for an access token
+var ErrAuthentication = errors.New("Authentication failed")
+
+// timeNow is overridden in tests
+var timeNow = time.Now
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// cachedToken is an access token saved in the token cache file
+type cachedToken struct {
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// getAccessToken returns the access token for the client credentials of opts,
+// from the token cache if still valid or exchanging the credentials at
+// tokenURL with httpClient. Exchanged tokens are saved in the cache.
+func getAccessToken(httpClient *http.Client, opts Options, tokenURL string) (string, error) {
+	cacheKey := fmt.Sprintf("%s@%s", opts.ClientID, opts.APIBaseURL)
+	cache := readTokenCache(opts.TokenCacheFile)
+	if token, ok := cache[cacheKey]; ok && timeNow().Add(tokenExpiryMargin).Before(token.ExpiresAt) {
+		return token.AccessToken, nil
+	}
+
+	token, err := exchangeClientCredentials(httpClient, opts, tokenURL)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.TokenCacheFile != "" {
+		cache[cacheKey] = token
+		// a cache which can't be written only costs a new exchange next time
+		writeTokenCache(opts.TokenCacheFile, cache)
+	}
+	return token.AccessToken, nil
+}
+
+func exchangeClientCredentials(httpClient *http.Client, opts Options, tokenURL string) (cachedToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if opts.PrivateKeyFile != "" {
+		assertion, err := signClientAssertion(opts, tokenURL)
+		if err != nil {
+			return cachedToken{}, fmt.Errorf("%w: %s", ErrAuthentication, err)
+		}
+		form.Set("client_id", opts.ClientID)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if opts.PrivateKeyFile == "" {
+		req.SetBasicAuth(opts.ClientID, opts.ClientSecret)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("%w: %s", ErrAuthentication, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return cachedToken{}, fmt.Errorf("%w: token request responded with status %d", ErrAuthentication, resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("%w: invalid token response", ErrAuthentication)
+	}
+	return cachedToken{
+		AccessToken: token.AccessToken,
+		ExpiresAt:   timeNow().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// signClientAssertion creates the JWT used to authenticate the client,
+// signed with RS256 by the private key.
+func signClientAssertion(opts Options, audience string) (string, error) {
+	key, err := readPrivateKey(opts.PrivateKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+	now := timeNow()
+	header := map[string]string{"alg": "RS256", "typ": "JWT"}
+	if opts.KeyID != "" {
+		header["kid"] = opts.KeyID
+	}
+	claims := map[string]interface{}{
+		"iss": opts.ClientID,
+		"sub": opts.ClientID,
+		"aud": audience,
+		"jti": hex.EncodeToString(jti),
+		"iat": now.Unix(),
+		"exp": now.Add(assertionLifetime).Unix(),
+	}
+
+	encodedHeader, err := encodeJWTPart(header)
+	if err != nil {
+		return "", err
+	}
+	encodedClaims, err := encodeJWTPart(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + encodedClaims
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func encodeJWTPart(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// readPrivateKey reads a PEM encoded RSA private key, in PKCS#1 or PKCS#8
+// format.
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %s", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key in %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+func readTokenCache(path string) map[string]cachedToken {
+	cache := map[string]cachedToken{}
+	if path == "" {
+		return cache
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return map[string]cachedToken{}
+	}
+	return cache
+}
+
+func writeTokenCache(path string, cache map[string]cachedToken) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
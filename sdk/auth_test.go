@@ -0,0 +1,192 @@
+package sdk
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCredentials(t *testing.T) {
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	dir, err := ioutil.TempDir("", "miactl-auth")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	secretAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/m2m/oauth/token", req.URL.Path)
+		require.Equal(t, http.MethodPost, req.Method)
+		clientID, secret, ok := req.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "client-id", clientID)
+		require.Equal(t, "client-secret", secret)
+		require.NoError(t, req.ParseForm())
+		require.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+	}
+	tokenResponseBody := `{"access_token":"the-token","token_type":"Bearer","expires_in":3600}`
+	secretOptions := func(url, cacheFile string) Options {
+		return Options{
+			APIBaseURL:     url,
+			APIKey:         "api-key",
+			ClientID:       "client-id",
+			ClientSecret:   "client-secret",
+			TokenCacheFile: cacheFile,
+		}
+	}
+
+	t.Run("throws without secret or private key", func(t *testing.T) {
+		client, err := New(Options{APIBaseURL: "http://my-url/", APIKey: "api-key", ClientID: "client-id"})
+		require.Nil(t, client)
+		require.EqualError(t, err, fmt.Sprintf("%s: client secret or private key file is required with client id", ErrCreateClient))
+	})
+
+	t.Run("exchanges client secret for an access token", func(t *testing.T) {
+		s := testCreateResponseServer(t, secretAssertions, tokenResponseBody, 200)
+		defer s.Close()
+
+		client, err := New(secretOptions(fmt.Sprintf("%s/", s.URL), ""))
+		require.NoError(t, err)
+		jsonClient := client.Projects.(*ProjectsClient).JSONClient
+		require.Equal(t, "Bearer the-token", jsonClient.DefaultHeaders["Authorization"])
+		require.Equal(t, "api-key", jsonClient.DefaultHeaders["client-key"])
+		require.NotContains(t, jsonClient.DefaultHeaders, "cookie")
+	})
+
+	t.Run("exchanges the token with the transport of the options", func(t *testing.T) {
+		s := testCreateResponseServer(t, secretAssertions, tokenResponseBody, 200)
+		defer s.Close()
+
+		var sent []string
+		options := secretOptions(fmt.Sprintf("%s/", s.URL), "")
+		options.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = append(sent, req.URL.Path)
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		_, err := New(options)
+		require.NoError(t, err)
+		require.Equal(t, []string{"/" + tokenPath}, sent)
+	})
+
+	t.Run("reuses cached token until expiry", func(t *testing.T) {
+		cacheFile := filepath.Join(dir, "cache", "tokens.json")
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: secretAssertions, body: tokenResponseBody, status: 200},
+			{assertions: secretAssertions, body: `{"access_token":"new-token","expires_in":3600}`, status: 200},
+		})
+		defer s.Close()
+		options := secretOptions(fmt.Sprintf("%s/", s.URL), cacheFile)
+
+		for i := 0; i < 2; i++ {
+			token, err := getAccessToken(http.DefaultClient, options, fmt.Sprintf("%s/%s", s.URL, tokenPath))
+			require.NoError(t, err)
+			require.Equal(t, "the-token", token)
+		}
+
+		now = now.Add(time.Hour)
+		token, err := getAccessToken(http.DefaultClient, options, fmt.Sprintf("%s/%s", s.URL, tokenPath))
+		require.NoError(t, err)
+		require.Equal(t, "new-token", token)
+
+		cache := readTokenCache(cacheFile)
+		require.Equal(t, map[string]cachedToken{
+			fmt.Sprintf("client-id@%s/", s.URL): {AccessToken: "new-token", ExpiresAt: now.Add(time.Hour)},
+		}, cache)
+	})
+
+	t.Run("throws when token request is rejected", func(t *testing.T) {
+		s := testCreateResponseServer(t, secretAssertions, `{"error":"invalid_client"}`, 401)
+		defer s.Close()
+
+		client, err := New(secretOptions(fmt.Sprintf("%s/", s.URL), ""))
+		require.Nil(t, client)
+		require.EqualError(t, err, fmt.Sprintf("%s: token request responded with status 401", ErrAuthentication))
+		require.True(t, errors.Is(err, ErrAuthentication))
+	})
+
+	t.Run("authenticates with a signed JWT assertion", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		keyFile := filepath.Join(dir, "key.pem")
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		require.NoError(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+		var serverURL string
+		assertions := func(t *testing.T, req *http.Request) {
+			_, _, ok := req.BasicAuth()
+			require.False(t, ok)
+			require.NoError(t, req.ParseForm())
+			require.Equal(t, "client_credentials", req.PostForm.Get("grant_type"))
+			require.Equal(t, "client-id", req.PostForm.Get("client_id"))
+			require.Equal(t, clientAssertionType, req.PostForm.Get("client_assertion_type"))
+
+			parts := strings.Split(req.PostForm.Get("client_assertion"), ".")
+			require.Len(t, parts, 3)
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			require.NoError(t, err)
+			hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature))
+
+			var header, claims map[string]interface{}
+			decodeJWTPart(t, parts[0], &header)
+			decodeJWTPart(t, parts[1], &claims)
+			require.Equal(t, map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": "key-1"}, header)
+			require.Equal(t, "client-id", claims["iss"])
+			require.Equal(t, "client-id", claims["sub"])
+			require.Equal(t, fmt.Sprintf("%s/api/m2m/oauth/token", serverURL), claims["aud"])
+			require.Equal(t, float64(now.Add(5*time.Minute).Unix()), claims["exp"])
+		}
+		s := testCreateResponseServer(t, assertions, tokenResponseBody, 200)
+		defer s.Close()
+		serverURL = s.URL
+
+		client, err := New(Options{
+			APIBaseURL:     fmt.Sprintf("%s/", s.URL),
+			APIKey:         "api-key",
+			ClientID:       "client-id",
+			PrivateKeyFile: keyFile,
+			KeyID:          "key-1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Bearer the-token", client.Deploy.(*DeployClient).JSONClient.DefaultHeaders["Authorization"])
+	})
+
+	t.Run("throws with invalid private key file", func(t *testing.T) {
+		keyFile := filepath.Join(dir, "invalid.pem")
+		require.NoError(t, ioutil.WriteFile(keyFile, []byte("not a key"), 0600))
+
+		client, err := New(Options{
+			APIBaseURL:     "http://my-url/",
+			APIKey:         "api-key",
+			ClientID:       "client-id",
+			PrivateKeyFile: keyFile,
+		})
+		require.Nil(t, client)
+		require.EqualError(t, err, fmt.Sprintf("%s: no PEM data found in %s", ErrAuthentication, keyFile))
+	})
+}
+
+func decodeJWTPart(t *testing.T, part string, v interface{}) {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
@@ -0,0 +1,412 @@
+// Package cassette records the http interactions with the console into
+// sanitized cassette files and replays them, so that the sdk can be tested
+// with real world payloads without a console.
+//
+// Both Recorder and Replayer are http.RoundTripper, to be set as
+// sdk.Options.Transport.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the sensitive values in the cassettes.
+const RedactedValue = "REDACTED"
+
+// ErrInteractionNotFound is returned replaying a request missing from the
+// cassette.
+var ErrInteractionNotFound = errors.New("Interaction not found in cassette")
+
+// Cassette holds the recorded interactions, in the order they happened.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request sent to the console with its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. The URL holds path and query only, so
+// that the cassette can be replayed against any host.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Payload
+}
+
+// Payload is a recorded body, kept as JSON when valid to make the
+// cassettes readable and editable.
+type Payload struct {
+	JSON json.RawMessage `json:"json,omitempty"`
+	Body string          `json:"body,omitempty"`
+}
+
+func newPayload(body []byte) Payload {
+	if len(body) == 0 {
+		return Payload{}
+	}
+	if json.Valid(body) {
+		return Payload{JSON: json.RawMessage(body)}
+	}
+	return Payload{Body: string(body)}
+}
+
+func (p Payload) bytes() []byte {
+	if len(p.JSON) > 0 {
+		return p.JSON
+	}
+	return []byte(p.Body)
+}
+
+// Read reads a cassette file.
+func Read(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(content, cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Write saves the cassette in path.
+func (c *Cassette) Write(path string) error {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(c); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, content.Bytes(), 0644)
+}
+
+// Sanitizer lists the values redacted before recording an interaction.
+// Names are case insensitive.
+type Sanitizer struct {
+	// Headers whose values are redacted
+	Headers []string
+	// Fields whose values are redacted in JSON and form bodies, at any depth
+	Fields []string
+}
+
+// DefaultSanitizer redacts the credentials used with the console and the
+// secrets it returns.
+var DefaultSanitizer = Sanitizer{
+	Headers: []string{"Authorization", "Cookie", "Set-Cookie", "Client-Key"},
+	Fields:  []string{"access_token", "refresh_token", "client_secret", "client_assertion", "password", "key"},
+}
+
+func (s Sanitizer) headers(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := map[string]string{}
+	for name, values := range header {
+		// the length changes with the sanitized body, so it is not kept
+		if strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		value := strings.Join(values, ", ")
+		if contains(s.Headers, name) {
+			value = RedactedValue
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+func (s Sanitizer) body(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	if json.Valid(body) {
+		// numbers are kept as they are, to not lose precision of large ids
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return body
+		}
+		sanitized, err := json.Marshal(s.jsonValue(value))
+		if err != nil {
+			return body
+		}
+		return sanitized
+	}
+	if form, err := url.ParseQuery(string(body)); err == nil && strings.Contains(string(body), "=") {
+		for name := range form {
+			if contains(s.Fields, name) {
+				form.Set(name, RedactedValue)
+			}
+		}
+		return []byte(form.Encode())
+	}
+	return body
+}
+
+func (s Sanitizer) jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, field := range v {
+			if contains(s.Fields, name) {
+				v[name] = RedactedValue
+			} else {
+				v[name] = s.jsonValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.jsonValue(item)
+		}
+	}
+	return value
+}
+
+// Recorder is a transport saving each interaction in the cassette file,
+// sanitized, as soon as the response is received.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	sanitizer Sanitizer
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a recorder writing to path the interactions sent
+// with the default transport.
+func NewRecorder(path string) *Recorder {
+	return &Recorder{
+		path:      path,
+		transport: http.DefaultTransport,
+		sanitizer: DefaultSanitizer,
+	}
+}
+
+// WithTransport sets the transport sending the recorded requests.
+func (r *Recorder) WithTransport(transport http.RoundTripper) *Recorder {
+	r.transport = transport
+	return r
+}
+
+// WithSanitizer sets the values redacted from the cassette.
+func (r *Recorder) WithSanitizer(sanitizer Sanitizer) *Recorder {
+	r.sanitizer = sanitizer
+	return r
+}
+
+// RoundTrip sends the request and records it with its response. The
+// request is not modified: its body is read from a copy.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, sent, err := copyRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.transport.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := readBody(&res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.RequestURI(),
+			Headers: r.sanitizer.headers(req.Header),
+			Payload: newPayload(r.sanitizer.body(requestBody)),
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Headers:    r.sanitizer.headers(res.Header),
+			Payload:    newPayload(r.sanitizer.body(responseBody)),
+		},
+	})
+	if err := r.cassette.Write(r.path); err != nil {
+		return nil, fmt.Errorf("error recording cassette: %w", err)
+	}
+	return res, nil
+}
+
+// Replayer is a transport answering the requests with the responses of a
+// cassette. Each interaction is replayed once, in the recorded order when
+// the same request is repeated.
+type Replayer struct {
+	cassette  *Cassette
+	sanitizer Sanitizer
+
+	mu       sync.Mutex
+	replayed []bool
+}
+
+// NewReplayer returns a replayer of the interactions of cassette. The
+// requests are sanitized as in recording, before being matched.
+func NewReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		cassette:  cassette,
+		sanitizer: DefaultSanitizer,
+		replayed:  make([]bool, len(cassette.Interactions)),
+	}
+}
+
+// Load returns a replayer of the cassette file in path.
+func Load(path string) (*Replayer, error) {
+	cassette, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(cassette), nil
+}
+
+// WithSanitizer sets the values redacted from the cassette when recorded.
+func (r *Replayer) WithSanitizer(sanitizer Sanitizer) *Replayer {
+	r.sanitizer = sanitizer
+	return r
+}
+
+// RoundTrip returns the response of the first interaction not yet replayed
+// with the same method, url and body of req.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		content, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = content
+	}
+	body = r.sanitizer.body(body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.replayed[i] || recorded.Method != req.Method || recorded.URL != req.URL.RequestURI() || !sameBody(recorded.bytes(), body) {
+			continue
+		}
+		r.replayed[i] = true
+
+		header := http.Header{}
+		for name, value := range interaction.Response.Headers {
+			header.Set(name, value)
+		}
+		responseBody := interaction.Response.bytes()
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(responseBody)),
+			ContentLength: int64(len(responseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL.RequestURI())
+}
+
+// Unused returns the interactions not replayed yet, to check that all the
+// recorded requests have been sent.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.replayed[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// copyRequestBody returns the body of req and the request to send in its
+// place. The body is read from GetBody when available, leaving req to be
+// sent as is, otherwise req is cloned with a copy of the body it consumes.
+func copyRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer body.Close()
+		content, err := ioutil.ReadAll(body)
+		return content, req, err
+	}
+
+	content, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	sent := req.Clone(req.Context())
+	sent.Body = ioutil.NopCloser(bytes.NewReader(content))
+	sent.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+	return content, sent, nil
+}
+
+// readBody reads the body and replaces it with a copy to be read again.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil {
+		return nil, nil
+	}
+	content, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(content))
+	return content, nil
+}
+
+// sameBody compares the bodies as JSON values when both are valid JSON, so
+// that the formatting of the cassette doesn't matter.
+func sameBody(recorded, sent []byte) bool {
+	var recordedValue, sentValue interface{}
+	if json.Unmarshal(recorded, &recordedValue) == nil && json.Unmarshal(sent, &sentValue) == nil {
+		return reflect.DeepEqual(recordedValue, sentValue)
+	}
+	return bytes.Equal(recorded, sent)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupCassetteDir(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "miactl-cassette")
+	require.NoError(t, err)
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func testServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "sid=server-secret")
+		switch req.URL.Path {
+		case "/token":
+			fmt.Fprint(w, `{"access_token":"secret-token","expires_in":3600}`)
+		case "/keys":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"name":"my-key","key":"secret-key","received":%s}`, body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"not found"}`)
+		}
+	}))
+}
+
+func sendRequest(t *testing.T, transport http.RoundTripper, method, url, contentType, body string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cookie", "sid=my-secret-sid")
+	req.Header.Set("Client-Key", "my-secret-key")
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	responseBody, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res, string(responseBody)
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("records sanitized interactions", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		server := testServer()
+		defer server.Close()
+		path := filepath.Join(dir, "cassette.json")
+		recorder := NewRecorder(path)
+
+		res, body := sendRequest(t, recorder, http.MethodPost, server.URL+"/token", "application/x-www-form-urlencoded", "grant_type=client_credentials&client_secret=my-secret")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, `{"access_token":"secret-token","expires_in":3600}`, body, "the response is not sanitized for the client")
+
+		res, _ = sendRequest(t, recorder, http.MethodPost, server.URL+"/keys?project=p", "application/json", `{"name":"my-key","roles":["developer"]}`)
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+
+		cassette, err := Read(path)
+		require.NoError(t, err)
+		require.Len(t, cassette.Interactions, 2)
+
+		token := cassette.Interactions[0]
+		require.Equal(t, http.MethodPost, token.Request.Method)
+		require.Equal(t, "/token", token.Request.URL)
+		require.Equal(t, RedactedValue, token.Request.Headers["Cookie"])
+		require.Equal(t, RedactedValue, token.Request.Headers["Client-Key"])
+		require.Equal(t, "client_secret=REDACTED&grant_type=client_credentials", token.Request.Body)
+		require.Equal(t, RedactedValue, token.Response.Headers["Set-Cookie"])
+		require.NotContains(t, token.Response.Headers, "Content-Length")
+		require.JSONEq(t, `{"access_token":"REDACTED","expires_in":3600}`, string(token.Response.JSON))
+
+		keys := cassette.Interactions[1]
+		require.Equal(t, "/keys?project=p", keys.Request.URL)
+		require.JSONEq(t, `{"name":"my-key","roles":["developer"]}`, string(keys.Request.JSON))
+		require.Equal(t, http.StatusCreated, keys.Response.StatusCode)
+		require.JSONEq(t, `{"name":"my-key","key":"REDACTED","received":{"name":"my-key","roles":["developer"]}}`, string(keys.Response.JSON))
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		for _, secret := range []string{"my-secret", "secret-token", "secret-key", "server-secret"} {
+			require.NotContains(t, string(content), secret)
+		}
+	})
+
+	t.Run("redacts the values of a custom sanitizer", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		server := testServer()
+		defer server.Close()
+		path := filepath.Join(dir, "cassette.json")
+		recorder := NewRecorder(path).WithSanitizer(Sanitizer{Fields: []string{"NAME"}})
+
+		sendRequest(t, recorder, http.MethodPost, server.URL+"/keys", "application/json", `{"name":"my-key"}`)
+
+		cassette, err := Read(path)
+		require.NoError(t, err)
+		interaction := cassette.Interactions[0]
+		require.Equal(t, "sid=my-secret-sid", interaction.Request.Headers["Cookie"])
+		require.JSONEq(t, `{"name":"REDACTED"}`, string(interaction.Request.JSON))
+		require.JSONEq(t, `{"name":"REDACTED","key":"secret-key","received":{"name":"REDACTED"}}`, string(interaction.Response.JSON))
+	})
+
+	t.Run("keeps large numbers", func(t *testing.T) {
+		sanitized := DefaultSanitizer.body([]byte(`{"id":9007199254740993}`))
+		require.Equal(t, `{"id":9007199254740993}`, string(sanitized))
+	})
+
+	t.Run("does not modify the request", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		var sent []string
+		recorder := NewRecorder(filepath.Join(dir, "cassette.json")).WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			sent = append(sent, string(body))
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("{}"))}, nil
+		}))
+
+		withGetBody, err := http.NewRequest(http.MethodPost, "http://console.example.com/keys", strings.NewReader(`{"name":"a"}`))
+		require.NoError(t, err)
+		withoutGetBody, err := http.NewRequest(http.MethodPost, "http://console.example.com/keys", ioutil.NopCloser(strings.NewReader(`{"name":"b"}`)))
+		require.NoError(t, err)
+		require.Nil(t, withoutGetBody.GetBody)
+
+		for _, req := range []*http.Request{withGetBody, withoutGetBody} {
+			body := req.Body
+			_, err = recorder.RoundTrip(req)
+			require.NoError(t, err)
+			require.True(t, body == req.Body, "the request body is replaced")
+		}
+		require.Nil(t, withoutGetBody.GetBody)
+		require.Equal(t, []string{`{"name":"a"}`, `{"name":"b"}`}, sent)
+	})
+
+	t.Run("returns transport errors without recording", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		path := filepath.Join(dir, "cassette.json")
+		recorder := NewRecorder(path).WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}))
+
+		req, err := http.NewRequest(http.MethodGet, "http://console.example.com/", nil)
+		require.NoError(t, err)
+		_, err = recorder.RoundTrip(req)
+		require.EqualError(t, err, "connection refused")
+		_, err = os.Stat(path)
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestReplayer(t *testing.T) {
+	cassette := &Cassette{
+		Interactions: []Interaction{
+			{
+				Request:  Request{Method: http.MethodGet, URL: "/status"},
+				Response: Response{StatusCode: http.StatusOK, Payload: Payload{JSON: json.RawMessage(`{"status":"running"}`)}},
+			},
+			{
+				Request:  Request{Method: http.MethodGet, URL: "/status"},
+				Response: Response{StatusCode: http.StatusOK, Payload: Payload{JSON: json.RawMessage(`{"status":"success"}`)}},
+			},
+			{
+				Request: Request{Method: http.MethodPost, URL: "/token", Payload: Payload{Body: "client_secret=REDACTED&grant_type=client_credentials"}},
+				Response: Response{
+					StatusCode: http.StatusOK,
+					Headers:    map[string]string{"Content-Type": "application/json"},
+					Payload:    Payload{JSON: json.RawMessage(`{"access_token":"REDACTED"}`)},
+				},
+			},
+			{
+				Request:  Request{Method: http.MethodPost, URL: "/keys", Payload: Payload{JSON: json.RawMessage("{\n  \"name\": \"my-key\"\n}")}},
+				Response: Response{StatusCode: http.StatusConflict, Payload: Payload{Body: "already exists"}},
+			},
+		},
+	}
+
+	t.Run("replays the interactions in order", func(t *testing.T) {
+		replayer := NewReplayer(cassette)
+
+		_, body := sendRequest(t, replayer, http.MethodGet, "http://any-host/status", "", "")
+		require.Equal(t, `{"status":"running"}`, body)
+		_, body = sendRequest(t, replayer, http.MethodGet, "http://other-host/status", "", "")
+		require.Equal(t, `{"status":"success"}`, body)
+
+		req, err := http.NewRequest(http.MethodGet, "http://any-host/status", nil)
+		require.NoError(t, err)
+		_, err = replayer.RoundTrip(req)
+		require.True(t, errors.Is(err, ErrInteractionNotFound))
+		require.EqualError(t, err, fmt.Sprintf("%s: GET /status", ErrInteractionNotFound))
+		require.Len(t, replayer.Unused(), 2)
+	})
+
+	t.Run("matches the sanitized body", func(t *testing.T) {
+		replayer := NewReplayer(cassette)
+
+		res, body := sendRequest(t, replayer, http.MethodPost, "http://any-host/token", "application/x-www-form-urlencoded", "grant_type=client_credentials&client_secret=another-secret")
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		require.Equal(t, `{"access_token":"REDACTED"}`, body)
+
+		res, body = sendRequest(t, replayer, http.MethodPost, "http://any-host/keys", "application/json", `{"name":"my-key"}`)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.Equal(t, "already exists", body)
+
+		req, err := http.NewRequest(http.MethodPost, "http://any-host/keys", bytes.NewBufferString(`{"name":"other-key"}`))
+		require.NoError(t, err)
+		_, err = replayer.RoundTrip(req)
+		require.True(t, errors.Is(err, ErrInteractionNotFound))
+	})
+
+	t.Run("does not modify the request", func(t *testing.T) {
+		replayer := NewReplayer(cassette)
+		req, err := http.NewRequest(http.MethodPost, "http://any-host/keys", strings.NewReader(`{"name":"my-key"}`))
+		require.NoError(t, err)
+		body := req.Body
+
+		res, err := replayer.RoundTrip(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+		require.True(t, body == req.Body, "the request body is replaced")
+	})
+
+	t.Run("replays a recorded cassette", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		server := testServer()
+		defer server.Close()
+		path := filepath.Join(dir, "cassette.json")
+		_, recorded := sendRequest(t, NewRecorder(path), http.MethodGet, server.URL+"/unknown", "", "")
+
+		replayer, err := Load(path)
+		require.NoError(t, err)
+		res, body := sendRequest(t, replayer, http.MethodGet, server.URL+"/unknown", "", "")
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+		require.JSONEq(t, recorded, body)
+		require.Empty(t, replayer.Unused())
+	})
+
+	t.Run("returns error loading invalid cassette", func(t *testing.T) {
+		dir, cleanup := setupCassetteDir(t)
+		defer cleanup()
+		path := filepath.Join(dir, "cassette.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0644))
+
+		_, err := Load(path)
+		require.Contains(t, err.Error(), "invalid cassette")
+
+		_, err = Load(filepath.Join(dir, "not-exists.json"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1,8 +1,13 @@
 package sdk
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
 
 	"github.com/davidebianchi/go-jsonclient"
 )
@@ -12,28 +17,89 @@ type Options struct {
 	APIKey     string
 	APICookie  string
 	APIBaseURL string
+
+	// ClientID enables the client credentials authentication, used instead
+	// of the cookie by machine to machine clients. The client is
+	// authenticated by ClientSecret or, if set, by a JWT assertion signed
+	// with the RSA key in PrivateKeyFile.
+	ClientID       string
+	ClientSecret   string
+	PrivateKeyFile string
+	// KeyID is the kid header of the JWT assertion
+	KeyID string
+	// TokenCacheFile, if set, keeps the access tokens until they expire
+	TokenCacheFile string
+
+	// Timeout limits the duration of each request, if greater than zero
+	Timeout time.Duration
+	// Transport, if set, sends the requests in place of the default one,
+	// e.g. to record or replay them with the cassette package
+	Transport http.RoundTripper
 }
 
 // IProjects expose the projects client interface
 type IProjects interface {
 	Get() (Projects, error)
+	Create(CreateProjectRequest) (*Project, error)
+}
+
+// ICompanies expose the companies client interface
+type ICompanies interface {
+	Get() (Companies, error)
+}
+
+// IMarketplace expose the marketplace client interface
+type IMarketplace interface {
+	Get(MarketplaceQuery) (MarketplaceItems, error)
+}
+
+// IServices expose the services client interface
+type IServices interface {
+	Create(projectID string, request CreateServiceRequest) (*CreateServiceResponse, error)
+}
+
+// IMembers expose the members client interface
+type IMembers interface {
+	List(scope MembersScope) (Members, error)
+	Add(scope MembersScope, request AddMemberRequest) (*Member, error)
+	Remove(scope MembersScope, memberID string) error
+	SetRole(scope MembersScope, memberID, role string) error
+}
+
+// IAPIKeys expose the api keys client interface
+type IAPIKeys interface {
+	List(projectID string) (APIKeys, error)
+	Create(projectID string, request CreateAPIKeyRequest) (*APIKey, error)
+	Delete(projectID, apiKeyID string) error
+	Rotate(projectID, apiKeyID string) (*APIKey, error)
 }
 
 // DeployHistoryQuery wraps query filters for project deployments.
+// Page starts from 1; when Page or PerPage are not set the first page
+// of 25 items is requested.
 type DeployHistoryQuery struct {
 	ProjectID string
+	Page      int
+	PerPage   int
 }
 
 // IDeploy is a client interface used to interact with deployment pipelines.
 type IDeploy interface {
 	GetHistory(DeployHistoryQuery) ([]DeployItem, error)
+	Trigger(projectID string, cfg DeployConfig) (DeployResponse, error)
+	GetStatus(projectID string, pipelineID int, environment string) (PipelineStatus, error)
 }
 
 // MiaClient is the client of the sdk to be used to communicate with Mia
 // Platform Console api
 type MiaClient struct {
-	Projects IProjects
-	Deploy   IDeploy
+	Projects    IProjects
+	Deploy      IDeploy
+	Companies   ICompanies
+	Marketplace IMarketplace
+	Services    IServices
+	Members     IMembers
+	APIKeys     IAPIKeys
 }
 
 var (
@@ -51,22 +117,103 @@ var (
 // New returns the MiaSdkClient to be used to communicate to Mia Platform
 // Console api.
 func New(opts Options) (*MiaClient, error) {
-	if opts.APIKey == "" || opts.APIBaseURL == "" || opts.APICookie == "" {
+	if opts.APIKey == "" || opts.APIBaseURL == "" || (opts.APICookie == "" && opts.ClientID == "") {
 		return nil, fmt.Errorf("%w: client options are not correct", ErrCreateClient)
 	}
+	if opts.ClientID != "" && opts.ClientSecret == "" && opts.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("%w: client secret or private key file is required with client id", ErrCreateClient)
+	}
+	// the json client only builds the requests: they are sent by doRequest
+	// with the http client of the options, leaving http.DefaultClient as is
+	httpClient := &http.Client{Timeout: opts.Timeout, Transport: opts.Transport}
+	headers := map[string]string{
+		"client-key": opts.APIKey,
+	}
+	if opts.APICookie != "" {
+		headers["cookie"] = opts.APICookie
+	}
 	JSONClient, err := jsonclient.New(jsonclient.Options{
 		BaseURL: opts.APIBaseURL,
-		Headers: map[string]string{
-			"cookie":     opts.APICookie,
-			"client-key": opts.APIKey,
-		},
+		Headers: headers,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrCreateClient, err)
 	}
 
+	if opts.ClientID != "" {
+		tokenURL, err := JSONClient.BaseURL.Parse(tokenPath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrCreateClient, err)
+		}
+		token, err := getAccessToken(httpClient, opts, tokenURL.String())
+		if err != nil {
+			return nil, err
+		}
+		JSONClient.DefaultHeaders["Authorization"] = fmt.Sprintf("Bearer %s", token)
+	}
+
+	// the members and the api keys are managed in bulk, one request per
+	// row, so their projects are resolved once
+	projects := newProjectCache()
 	return &MiaClient{
-		Projects: &ProjectsClient{JSONClient: JSONClient},
-		Deploy:   &DeployClient{JSONClient: JSONClient},
+		Projects:    &ProjectsClient{JSONClient: JSONClient, HTTPClient: httpClient},
+		Deploy:      &DeployClient{JSONClient: JSONClient, HTTPClient: httpClient},
+		Companies:   &CompaniesClient{JSONClient: JSONClient, HTTPClient: httpClient},
+		Marketplace: &MarketplaceClient{JSONClient: JSONClient, HTTPClient: httpClient},
+		Services:    &ServicesClient{JSONClient: JSONClient, HTTPClient: httpClient},
+		Members:     &MembersClient{JSONClient: JSONClient, HTTPClient: httpClient, projects: projects},
+		APIKeys:     &APIKeysClient{JSONClient: JSONClient, HTTPClient: httpClient, projects: projects},
 	}, nil
 }
+
+// doRequest sends a request with the json body to path, with httpClient or
+// http.DefaultClient if nil, and decodes the json response in v, if not nil.
+// HTTP errors are returned as they are, the others are wrapped in
+// ErrGeneric.
+func doRequest(httpClient *http.Client, client *jsonclient.Client, method, path string, body, v interface{}) error {
+	req, err := client.NewRequest(method, path, body)
+	if err != nil {
+		return err
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrGeneric, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return &httpError{
+			HTTPError: &jsonclient.HTTPError{Response: resp, StatusCode: resp.StatusCode, Err: ErrHTTP},
+			body:      string(body),
+		}
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil && err != io.EOF {
+			return fmt.Errorf("%w: %s", ErrGeneric, err)
+		}
+	}
+	return nil
+}
+
+// httpError is the jsonclient.HTTPError of a response with a status code
+// not 2xx, with the response body, which only the json client can set in it.
+type httpError struct {
+	*jsonclient.HTTPError
+	body string
+}
+
+func (e *httpError) Error() string {
+	message := fmt.Sprintf("%v %v: %d", e.Response.Request.Method, e.Response.Request.URL, e.StatusCode)
+	if e.body != "" {
+		message = fmt.Sprintf("%s - %s", message, e.body)
+	}
+	return message
+}
+
+func (e *httpError) Unwrap() error {
+	return e.HTTPError
+}
@@ -1,8 +1,11 @@
 package sdk
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/davidebianchi/go-jsonclient"
 	"github.com/stretchr/testify/require"
@@ -28,13 +31,32 @@ func TestNew(t *testing.T) {
 	t.Run("throws with wrong base url", func(t *testing.T) {
 		client, err := New(Options{
 			APIBaseURL: "wrong	",
-			APIKey:    "apiKey",
-			APICookie: "sid=asd",
+			APIKey:     "apiKey",
+			APICookie:  "sid=asd",
 		})
 		require.Error(t, err)
 		require.Nil(t, client)
 	})
 
+	t.Run("sets the requests timeout and transport of the client", func(t *testing.T) {
+		transport := &http.Transport{}
+		client, err := New(Options{
+			APIBaseURL: "http://my-url/path/",
+			APIKey:     "my apiKey",
+			APICookie:  "sid=asd",
+			Timeout:    10 * time.Second,
+			Transport:  transport,
+		})
+		require.NoError(t, err)
+		httpClient := client.Projects.(*ProjectsClient).HTTPClient
+		require.Equal(t, 10*time.Second, httpClient.Timeout)
+		require.Same(t, transport, httpClient.Transport)
+		require.Same(t, httpClient, client.Deploy.(*DeployClient).HTTPClient)
+
+		require.Zero(t, http.DefaultClient.Timeout)
+		require.Nil(t, http.DefaultClient.Transport)
+	})
+
 	t.Run("correctly returns mia client", func(t *testing.T) {
 		opts := Options{
 			APIBaseURL: "http://my-url/path/",
@@ -52,13 +74,75 @@ func TestNew(t *testing.T) {
 		})
 
 		require.NoError(t, err, "new client error")
+		httpClient := &http.Client{}
+		projects := client.APIKeys.(*APIKeysClient).projects
+		require.NotNil(t, projects)
 		require.Exactly(t, &MiaClient{
 			Projects: &ProjectsClient{
 				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
 			},
 			Deploy: &DeployClient{
 				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+			},
+			Companies: &CompaniesClient{
+				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+			},
+			Marketplace: &MarketplaceClient{
+				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+			},
+			Services: &ServicesClient{
+				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+			},
+			Members: &MembersClient{
+				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+				projects:   projects,
+			},
+			APIKeys: &APIKeysClient{
+				JSONClient: expectedJSONClient,
+				HTTPClient: httpClient,
+				projects:   projects,
 			},
 		}, client)
 	})
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDoRequest(t *testing.T) {
+	responseBody := `{"message":"Not found"}`
+	s := testCreateMultiResponseServer(t, responses{
+		{body: responseBody, status: 404},
+		{body: responseBody, status: 404},
+	})
+	defer s.Close()
+	client := testCreateClient(t, fmt.Sprintf("%s/", s.URL))
+
+	t.Run("sends the request with the http client", func(t *testing.T) {
+		var sent int
+		httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return http.DefaultTransport.RoundTrip(req)
+		})}
+		require.Error(t, doRequest(httpClient, client, http.MethodGet, "path/", nil, nil))
+		require.Equal(t, 1, sent)
+	})
+
+	t.Run("returns the http errors with the response body", func(t *testing.T) {
+		err := doRequest(nil, client, http.MethodGet, "path/", nil, nil)
+		require.EqualError(t, err, fmt.Sprintf("GET %s/path/: 404 - %s", s.URL, responseBody))
+		var httpErr *jsonclient.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		require.Equal(t, 404, httpErr.StatusCode)
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+}
@@ -0,0 +1,33 @@
+package sdk
+
+import (
+	"net/http"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// Company define the mia-platform console company (tenant)
+type Company struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"`
+	// TenantID is the identifier of the company referenced by its projects
+	TenantID string `json:"tenantId"`
+}
+
+// Companies is a list of company
+type Companies []Company
+
+// CompaniesClient is the console implementations of the ICompanies interface
+type CompaniesClient struct {
+	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
+}
+
+// Get method to fetch the console companies the user can access
+func (c CompaniesClient) Get() (Companies, error) {
+	companies := Companies{}
+	if err := doRequest(c.HTTPClient, c.JSONClient, http.MethodGet, "api/backend/tenants/", nil, &companies); err != nil {
+		return nil, err
+	}
+	return companies, nil
+}
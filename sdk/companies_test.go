@@ -0,0 +1,64 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompaniesGet(t *testing.T) {
+	companiesListResponseBody := readTestData(t, "companies.json")
+	requestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/backend/tenants/", req.URL.Path)
+		require.Equal(t, http.MethodGet, req.Method)
+		cookieSid, err := req.Cookie("sid")
+		require.NoError(t, err)
+		require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+	}
+
+	t.Run("correctly returns companies", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions, companiesListResponseBody, 200)
+		defer s.Close()
+		client := testCreateCompaniesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		companies, err := client.Get()
+		require.NoError(t, err)
+		require.Equal(t, Companies{
+			{ID: "mongo-id-1", Name: "Company 1", TenantID: "company-1"},
+			{ID: "mongo-id-2", Name: "Company 2", TenantID: "company-2"},
+		}, companies)
+	})
+
+	t.Run("throws when server respond with 401", func(t *testing.T) {
+		responseBody := `{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`
+		s := testCreateResponseServer(t, requestAssertions, responseBody, 401)
+		defer s.Close()
+		client := testCreateCompaniesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		companies, err := client.Get()
+		require.Nil(t, companies)
+		require.EqualError(t, err, fmt.Sprintf("GET %s/api/backend/tenants/: 401 - %s", s.URL, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("throws if response body is not as expected", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions, `{"_id":"not-a-list"}`, 200)
+		defer s.Close()
+		client := testCreateCompaniesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		companies, err := client.Get()
+		require.Nil(t, companies)
+		require.True(t, errors.Is(err, ErrGeneric))
+	})
+}
+
+func testCreateCompaniesClient(t *testing.T, url string) ICompanies {
+	t.Helper()
+	return CompaniesClient{
+		JSONClient: testCreateClient(t, url),
+	}
+}
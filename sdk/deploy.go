@@ -1,14 +1,25 @@
 package sdk
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/davidebianchi/go-jsonclient"
 )
 
+// Deploy statuses as reported by the deploy history API.
+const (
+	DeployStatusSuccess  = "success"
+	DeployStatusFailed   = "failed"
+	DeployStatusRunning  = "running"
+	DeployStatusPending  = "pending"
+	DeployStatusCanceled = "canceled"
+)
+
+const defaultHistoryPageSize = 25
+
 // DeployItem represents a single item of the deploy history.
 type DeployItem struct {
 	ID          int        `json:"id"`
@@ -41,29 +52,81 @@ type DeployUser struct {
 // DeployClient implements IDeploy interface to interact with Mia Platform deploy API.
 type DeployClient struct {
 	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
 }
 
 // GetHistory interacts with Mia Platform APIs to retrieve a list of the lastest deploy.
 func (d DeployClient) GetHistory(query DeployHistoryQuery) ([]DeployItem, error) {
-	project, err := getProjectByID(d.JSONClient, query.ProjectID)
+	project, err := getProjectByID(d.HTTPClient, d.JSONClient, query.ProjectID)
 	if err != nil {
 		return nil, err
 	}
 
-	path := fmt.Sprintf("api/deploy/projects/%s/deployment/?page=1&per_page=25&sort=desc", project.ID)
-
-	historyReq, err := d.JSONClient.NewRequest(http.MethodGet, path, nil)
-	if err != nil {
-		return nil, err
+	page, perPage := query.Page, query.PerPage
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = defaultHistoryPageSize
 	}
 
+	path := fmt.Sprintf("api/deploy/projects/%s/deployment/?page=%d&per_page=%d&sort=desc", project.ID, page, perPage)
+
 	var history []DeployItem
-	if _, err := d.JSONClient.Do(historyReq, &history); err != nil {
-		var httpErr *jsonclient.HTTPError
-		if errors.As(err, &httpErr) {
-			return nil, httpErr
-		}
-		return nil, fmt.Errorf("%w: %s", ErrGeneric, err)
+	if err := doRequest(d.HTTPClient, d.JSONClient, http.MethodGet, path, nil, &history); err != nil {
+		return nil, err
 	}
 	return history, nil
 }
+
+// DeployConfig holds the parameters of a deploy to be triggered.
+type DeployConfig struct {
+	Environment             string `json:"environment"`
+	Revision                string `json:"revision"`
+	DeployType              string `json:"deployType"`
+	ForceDeployWhenNoSemver bool   `json:"forceDeployWhenNoSemver"`
+}
+
+// DeployResponse identifies the pipeline started by a deploy trigger.
+type DeployResponse struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+}
+
+// PipelineStatus represents the current status of a deploy pipeline.
+type PipelineStatus struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// Trigger interacts with Mia Platform APIs to start the deploy pipeline of
+// the environment specified in cfg.
+func (d DeployClient) Trigger(projectID string, cfg DeployConfig) (DeployResponse, error) {
+	project, err := getProjectByID(d.HTTPClient, d.JSONClient, projectID)
+	if err != nil {
+		return DeployResponse{}, err
+	}
+
+	path := fmt.Sprintf("api/deploy/projects/%s/trigger/pipeline/", project.ID)
+	var response DeployResponse
+	if err := doRequest(d.HTTPClient, d.JSONClient, http.MethodPost, path, cfg, &response); err != nil {
+		return DeployResponse{}, err
+	}
+	return response, nil
+}
+
+// GetStatus interacts with Mia Platform APIs to retrieve the status of the
+// pipeline started by a deploy trigger.
+func (d DeployClient) GetStatus(projectID string, pipelineID int, environment string) (PipelineStatus, error) {
+	project, err := getProjectByID(d.HTTPClient, d.JSONClient, projectID)
+	if err != nil {
+		return PipelineStatus{}, err
+	}
+
+	path := fmt.Sprintf("api/deploy/projects/%s/pipelines/%d/status/?environment=%s", project.ID, pipelineID, url.QueryEscape(environment))
+	var status PipelineStatus
+	if err := doRequest(d.HTTPClient, d.JSONClient, http.MethodGet, path, nil, &status); err != nil {
+		return PipelineStatus{}, err
+	}
+	return status, nil
+}
@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/davidebianchi/go-jsonclient"
+	"github.com/mia-platform/miactl/sdk/cassette"
 	"github.com/stretchr/testify/require"
 )
 
@@ -80,6 +82,43 @@ func TestDeployGetHistory(t *testing.T) {
 		require.True(t, errors.Is(err, ErrGeneric))
 	})
 
+	t.Run("History is requested with default pagination", func(t *testing.T) {
+		responses := []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: func(t *testing.T, req *http.Request) {
+				historyRequestAssertions(t, req)
+				require.Equal(t, "1", req.URL.Query().Get("page"))
+				require.Equal(t, "25", req.URL.Query().Get("per_page"))
+			}, body: "[]", status: 200},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		history, err := client.GetHistory(DeployHistoryQuery{ProjectID: "project-2"})
+		require.NoError(t, err)
+		require.Empty(t, history)
+	})
+
+	t.Run("History is requested with the given page", func(t *testing.T) {
+		responses := []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: func(t *testing.T, req *http.Request) {
+				historyRequestAssertions(t, req)
+				require.Equal(t, "3", req.URL.Query().Get("page"))
+				require.Equal(t, "100", req.URL.Query().Get("per_page"))
+				require.Equal(t, "desc", req.URL.Query().Get("sort"))
+			}, body: "[]", status: 200},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		history, err := client.GetHistory(DeployHistoryQuery{ProjectID: "project-2", Page: 3, PerPage: 100})
+		require.NoError(t, err)
+		require.Empty(t, history)
+	})
+
 	t.Run("History download goes fine", func(t *testing.T) {
 		historyResponseBody := readTestData(t, "deploy-history.json")
 		responses := []response{
@@ -187,3 +226,147 @@ func testCreateDeployClient(t *testing.T, url string) IDeploy {
 		JSONClient: client,
 	}
 }
+
+func TestDeployTrigger(t *testing.T) {
+	projectsListResponseBody := readTestData(t, "projects.json")
+	projectRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.True(t, strings.HasSuffix(req.URL.Path, "/projects/"))
+		require.Equal(t, http.MethodGet, req.Method)
+	}
+	triggerRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/deploy/projects/mongo-id-2/trigger/pipeline/", req.URL.Path)
+		require.Equal(t, http.MethodPost, req.Method)
+		cookieSid, err := req.Cookie("sid")
+		require.NoError(t, err)
+		require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+
+		var body DeployConfig
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		require.Equal(t, DeployConfig{
+			Environment: "production",
+			Revision:    "v1.4.2",
+			DeployType:  "smart_deploy",
+		}, body)
+	}
+	cfg := DeployConfig{
+		Environment: "production",
+		Revision:    "v1.4.2",
+		DeployType:  "smart_deploy",
+	}
+
+	t.Run("Error occurs when projectId does not exist", func(t *testing.T) {
+		s := testCreateResponseServer(t, projectRequestAssertions, projectsListResponseBody, 200)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		response, err := client.Trigger("project-NaN", cfg)
+		require.Equal(t, DeployResponse{}, response)
+		require.EqualError(t, err, fmt.Sprintf("%s: project-NaN", ErrProjectNotFound))
+	})
+
+	t.Run("HTTP error occurs when triggering deploy", func(t *testing.T) {
+		responses := []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: triggerRequestAssertions, body: `{"statusCode":400,"error":"Bad Request","message":"invalid revision"}`, status: 400},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		response, err := client.Trigger("project-2", cfg)
+		require.Equal(t, DeployResponse{}, response)
+		require.True(t, errors.Is(err, jsonclient.ErrHTTP))
+	})
+
+	t.Run("Deploy is triggered", func(t *testing.T) {
+		responses := []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: triggerRequestAssertions, body: `{"id":42,"url":"https://the-repo/pipelines/42"}`, status: 200},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		response, err := client.Trigger("project-2", cfg)
+		require.NoError(t, err)
+		require.Equal(t, DeployResponse{ID: 42, URL: "https://the-repo/pipelines/42"}, response)
+	})
+}
+
+func TestDeployGetStatus(t *testing.T) {
+	projectsListResponseBody := readTestData(t, "projects.json")
+	statusRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/deploy/projects/mongo-id-2/pipelines/42/status/", req.URL.Path)
+		require.Equal(t, "production", req.URL.Query().Get("environment"))
+		require.Equal(t, http.MethodGet, req.Method)
+	}
+
+	t.Run("Error on malformed status", func(t *testing.T) {
+		responses := []response{
+			{body: projectsListResponseBody, status: 200},
+			{assertions: statusRequestAssertions, body: `{"id":"42"}`, status: 200},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		status, err := client.GetStatus("project-2", 42, "production")
+		require.Equal(t, PipelineStatus{}, status)
+		require.True(t, errors.Is(err, ErrGeneric))
+	})
+
+	t.Run("Returns pipeline status", func(t *testing.T) {
+		responses := []response{
+			{body: projectsListResponseBody, status: 200},
+			{assertions: statusRequestAssertions, body: `{"id":42,"status":"running"}`, status: 200},
+		}
+		s := testCreateMultiResponseServer(t, responses)
+		defer s.Close()
+		client := testCreateDeployClient(t, fmt.Sprintf("%s/", s.URL))
+
+		status, err := client.GetStatus("project-2", 42, "production")
+		require.NoError(t, err)
+		require.Equal(t, PipelineStatus{ID: 42, Status: DeployStatusRunning}, status)
+	})
+}
+
+func TestDeployCassettes(t *testing.T) {
+	t.Run("History of a recorded cassette", func(t *testing.T) {
+		client, replayer := testReplayCassette(t, "deploy-history.json")
+
+		history, err := client.Deploy.GetHistory(DeployHistoryQuery{ProjectID: "project-2", PerPage: 2})
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, 1234, history[0].ID)
+		require.Equal(t, "v1.4.2", history[0].Ref)
+		require.Equal(t, "production", history[0].Environment)
+		require.Empty(t, replayer.Unused())
+	})
+
+	t.Run("History with a different page is not recorded", func(t *testing.T) {
+		client, _ := testReplayCassette(t, "deploy-history.json")
+
+		history, err := client.Deploy.GetHistory(DeployHistoryQuery{ProjectID: "project-2"})
+		require.Nil(t, history)
+		require.True(t, errors.Is(err, ErrGeneric))
+		require.Contains(t, err.Error(), cassette.ErrInteractionNotFound.Error())
+	})
+
+	t.Run("Trigger and status of a recorded cassette", func(t *testing.T) {
+		client, replayer := testReplayCassette(t, "deploy-trigger.json")
+
+		response, err := client.Deploy.Trigger("project-2", DeployConfig{Environment: "production", Revision: "v1.5.0", DeployType: "smart_deploy"})
+		require.NoError(t, err)
+		require.Equal(t, 2415, response.ID)
+
+		for _, expected := range []string{DeployStatusRunning, DeployStatusSuccess} {
+			status, err := client.Deploy.GetStatus("project-2", response.ID, "production")
+			require.NoError(t, err)
+			require.Equal(t, PipelineStatus{ID: 2415, Status: expected}, status)
+		}
+		require.Empty(t, replayer.Unused())
+	})
+}
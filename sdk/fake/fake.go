@@ -0,0 +1,387 @@
+// Package fake provides a fake Mia Platform Console, serving the projects,
+// deploy history and deploy trigger endpoints from fixtures. It can be
+// embedded in Go tests with NewServer or run with miactl dev fake-server to
+// test scripts without a real Console.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mia-platform/miactl/sdk"
+)
+
+// AnyEnvironment is the PipelineStatuses key of the statuses used for the
+// environments without their own statuses.
+const AnyEnvironment = "*"
+
+// DefaultPipelineStatuses are the statuses of the triggered pipelines when
+// no statuses are set in the fixtures.
+var DefaultPipelineStatuses = []string{sdk.DeployStatusRunning, sdk.DeployStatusSuccess}
+
+// Fixtures holds the initial state of the fake console.
+type Fixtures struct {
+	Projects sdk.Projects `json:"projects"`
+	// DeployHistory holds the deploys of each project, by project id,
+	// sorted from the newest to the oldest one.
+	DeployHistory map[string][]sdk.DeployItem `json:"deployHistory"`
+	// PipelineStatuses lists, by environment id, the statuses returned by
+	// the subsequent status requests of a triggered pipeline. The last
+	// status is kept once reached.
+	PipelineStatuses map[string][]string `json:"pipelineStatuses"`
+}
+
+// ReadFixtures reads the fixtures from a json file.
+func ReadFixtures(path string) (Fixtures, error) {
+	var fixtures Fixtures
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fixtures, err
+	}
+	if err := json.Unmarshal(content, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("invalid fixtures file %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// Trigger records a deploy triggered on the fake console.
+type Trigger struct {
+	ProjectID  string           `json:"projectId"`
+	PipelineID int              `json:"pipelineId"`
+	Config     sdk.DeployConfig `json:"config"`
+}
+
+type pipeline struct {
+	projectID string
+	config    sdk.DeployConfig
+	statuses  []string
+	requests  int
+	finished  bool
+}
+
+// Console is the http.Handler of the fake console. Its state is changed by
+// the deploy triggers: each triggered pipeline goes through the statuses set
+// for its environment and, once finished, it is added to the deploy history.
+//
+// Besides the console api, it serves the endpoints used to script the state
+// from the outside:
+//
+//	GET /fake/triggers lists the triggered deploys
+//	PUT /fake/pipelines/{id}/status sets the status of a pipeline, with a {"status": "failed"} body
+type Console struct {
+	mu             sync.Mutex
+	projects       sdk.Projects
+	history        map[string][]sdk.DeployItem
+	statuses       map[string][]string
+	pipelines      map[int]*pipeline
+	triggers       []Trigger
+	nextPipelineID int
+}
+
+// NewConsole returns a fake console with the state of fixtures.
+func NewConsole(fixtures Fixtures) *Console {
+	c := &Console{
+		projects:       fixtures.Projects,
+		history:        map[string][]sdk.DeployItem{},
+		statuses:       map[string][]string{},
+		pipelines:      map[int]*pipeline{},
+		nextPipelineID: 1,
+	}
+	if c.projects == nil {
+		c.projects = sdk.Projects{}
+	}
+	for projectID, history := range fixtures.DeployHistory {
+		c.history[projectID] = append([]sdk.DeployItem{}, history...)
+		for _, deploy := range history {
+			if deploy.ID >= c.nextPipelineID {
+				c.nextPipelineID = deploy.ID + 1
+			}
+		}
+	}
+	for env, statuses := range fixtures.PipelineStatuses {
+		c.SetPipelineStatuses(env, statuses...)
+	}
+	return c
+}
+
+// SetPipelineStatuses sets the statuses of the pipelines triggered from now
+// on in env, which can be AnyEnvironment.
+func (c *Console) SetPipelineStatuses(env string, statuses ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statuses[env] = append([]string{}, statuses...)
+}
+
+// SetPipelineStatus sets the status returned by the next status requests of
+// a triggered pipeline.
+func (c *Console) SetPipelineStatus(pipelineID int, status string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.setPipelineStatus(pipelineID, status) {
+		return fmt.Errorf("pipeline %d not found", pipelineID)
+	}
+	return nil
+}
+
+func (c *Console) setPipelineStatus(pipelineID int, status string) bool {
+	p, ok := c.pipelines[pipelineID]
+	if !ok {
+		return false
+	}
+	p.statuses = []string{status}
+	p.requests = 0
+	return true
+}
+
+// Triggers returns the deploys triggered so far, from the oldest one.
+func (c *Console) Triggers() []Trigger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Trigger{}, c.triggers...)
+}
+
+// History returns the deploy history of a project.
+func (c *Console) History(projectID string) []sdk.DeployItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]sdk.DeployItem{}, c.history[projectID]...)
+}
+
+func (c *Console) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case matchPath(parts, "api", "backend", "projects"):
+		if checkMethod(w, req, http.MethodGet) {
+			writeJSON(w, http.StatusOK, c.projects)
+		}
+	case matchPath(parts, "api", "deploy", "projects", "*", "deployment"):
+		if project, ok := c.project(w, parts[3]); ok && checkMethod(w, req, http.MethodGet) {
+			c.serveHistory(w, req, project)
+		}
+	case matchPath(parts, "api", "deploy", "projects", "*", "trigger", "pipeline"):
+		if project, ok := c.project(w, parts[3]); ok && checkMethod(w, req, http.MethodPost) {
+			c.serveTrigger(w, req, project)
+		}
+	case matchPath(parts, "api", "deploy", "projects", "*", "pipelines", "*", "status"):
+		if project, ok := c.project(w, parts[3]); ok && checkMethod(w, req, http.MethodGet) {
+			c.serveStatus(w, project, parts[5])
+		}
+	case matchPath(parts, "fake", "triggers"):
+		if checkMethod(w, req, http.MethodGet) {
+			writeJSON(w, http.StatusOK, c.triggers)
+		}
+	case matchPath(parts, "fake", "pipelines", "*", "status"):
+		if checkMethod(w, req, http.MethodPut) {
+			c.serveSetStatus(w, req, parts[2])
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("route %s %s not found", req.Method, req.URL.Path))
+	}
+}
+
+func (c *Console) serveHistory(w http.ResponseWriter, req *http.Request, project *sdk.Project) {
+	page, perPage := queryInt(req, "page", 1), queryInt(req, "per_page", 25)
+	history := c.history[project.ProjectID]
+	start := (page - 1) * perPage
+	if start > len(history) {
+		start = len(history)
+	}
+	end := start + perPage
+	if end > len(history) {
+		end = len(history)
+	}
+	writeJSON(w, http.StatusOK, history[start:end])
+}
+
+func (c *Console) serveTrigger(w http.ResponseWriter, req *http.Request, project *sdk.Project) {
+	var config sdk.DeployConfig
+	if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid body: %s", err))
+		return
+	}
+	if config.Revision == "" {
+		writeError(w, http.StatusBadRequest, "revision is required")
+		return
+	}
+	if !hasEnvironment(project, config.Environment) {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("environment %s not found in project %s", config.Environment, project.ProjectID))
+		return
+	}
+
+	statuses, ok := c.statuses[config.Environment]
+	if !ok {
+		statuses, ok = c.statuses[AnyEnvironment]
+	}
+	if !ok || len(statuses) == 0 {
+		statuses = DefaultPipelineStatuses
+	}
+
+	id := c.nextPipelineID
+	c.nextPipelineID++
+	c.pipelines[id] = &pipeline{
+		projectID: project.ProjectID,
+		config:    config,
+		statuses:  statuses,
+	}
+	c.triggers = append(c.triggers, Trigger{ProjectID: project.ProjectID, PipelineID: id, Config: config})
+	writeJSON(w, http.StatusOK, sdk.DeployResponse{
+		ID:  id,
+		URL: fmt.Sprintf("http://%s/fake/pipelines/%d", req.Host, id),
+	})
+}
+
+func (c *Console) serveStatus(w http.ResponseWriter, project *sdk.Project, rawID string) {
+	id, err := strconv.Atoi(rawID)
+	p, ok := c.pipelines[id]
+	if err != nil || !ok || p.projectID != project.ProjectID {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pipeline %s not found", rawID))
+		return
+	}
+
+	index := p.requests
+	if index >= len(p.statuses) {
+		index = len(p.statuses) - 1
+	}
+	p.requests++
+	status := p.statuses[index]
+
+	if !p.finished && isFinal(status) {
+		p.finished = true
+		deploy := sdk.DeployItem{
+			ID:          id,
+			Status:      status,
+			Ref:         p.config.Revision,
+			DeployType:  p.config.DeployType,
+			User:        sdk.DeployUser{Name: "fake"},
+			FinishedAt:  time.Now().UTC(),
+			Environment: p.config.Environment,
+		}
+		c.history[p.projectID] = append([]sdk.DeployItem{deploy}, c.history[p.projectID]...)
+	}
+	writeJSON(w, http.StatusOK, sdk.PipelineStatus{ID: id, Status: status})
+}
+
+func (c *Console) serveSetStatus(w http.ResponseWriter, req *http.Request, rawID string) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Status == "" {
+		writeError(w, http.StatusBadRequest, "a body with the status is required")
+		return
+	}
+	id, err := strconv.Atoi(rawID)
+	if err != nil || !c.setPipelineStatus(id, body.Status) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("pipeline %s not found", rawID))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// project returns the project with the mongo id used in the console paths,
+// writing the not found error if missing.
+func (c *Console) project(w http.ResponseWriter, id string) (*sdk.Project, bool) {
+	for i := range c.projects {
+		if c.projects[i].ID == id {
+			return &c.projects[i], true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Sprintf("project %s not found", id))
+	return nil, false
+}
+
+// Server is a fake console listening on a local address, to be used in tests.
+type Server struct {
+	*httptest.Server
+	Console *Console
+}
+
+// NewServer starts a fake console with the state of fixtures. The server
+// must be closed once done.
+func NewServer(fixtures Fixtures) *Server {
+	console := NewConsole(fixtures)
+	return &Server{
+		Server:  httptest.NewServer(console),
+		Console: console,
+	}
+}
+
+// Options returns the sdk options to connect to the server.
+func (s *Server) Options() sdk.Options {
+	return sdk.Options{
+		APIBaseURL: s.URL + "/",
+		APIKey:     "fake-api-key",
+		APICookie:  "sid=fake",
+	}
+}
+
+// matchPath reports whether the path parts match pattern, where * matches
+// any part.
+func matchPath(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, part := range pattern {
+		if part != "*" && part != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func checkMethod(w http.ResponseWriter, req *http.Request, method string) bool {
+	if req.Method != method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", req.Method))
+		return false
+	}
+	return true
+}
+
+func queryInt(req *http.Request, name string, defaultValue int) int {
+	value, err := strconv.Atoi(req.URL.Query().Get(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func hasEnvironment(project *sdk.Project, env string) bool {
+	for _, e := range project.Environments {
+		if e.EnvID == env {
+			return true
+		}
+	}
+	return false
+}
+
+func isFinal(status string) bool {
+	switch status {
+	case sdk.DeployStatusSuccess, sdk.DeployStatusFailed, sdk.DeployStatusCanceled:
+		return true
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes an error with the body of the console errors.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"statusCode": status,
+		"error":      http.StatusText(status),
+		"message":    message,
+	})
+}
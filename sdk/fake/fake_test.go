@@ -0,0 +1,226 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidebianchi/go-jsonclient"
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func testServer(t *testing.T) (*Server, *sdk.MiaClient) {
+	t.Helper()
+	fixtures, err := ReadFixtures("testdata/fixtures.json")
+	require.NoError(t, err)
+	server := NewServer(fixtures)
+	client, err := sdk.New(server.Options())
+	require.NoError(t, err)
+	return server, client
+}
+
+func TestReadFixtures(t *testing.T) {
+	t.Run("reads the fixtures file", func(t *testing.T) {
+		fixtures, err := ReadFixtures("testdata/fixtures.json")
+		require.NoError(t, err)
+		require.Len(t, fixtures.Projects, 1)
+		require.Len(t, fixtures.DeployHistory["project-1"], 2)
+		require.Equal(t, []string{"success"}, fixtures.PipelineStatuses[AnyEnvironment])
+	})
+
+	t.Run("returns error if file does not exist", func(t *testing.T) {
+		_, err := ReadFixtures("testdata/not-exists.json")
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("returns error if file is not valid", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "miactl-fake")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "fixtures.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte("{not json"), 0644))
+
+		_, err = ReadFixtures(path)
+		require.Contains(t, err.Error(), "invalid fixtures file")
+	})
+}
+
+func TestConsole(t *testing.T) {
+	t.Run("serves projects", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		projects, err := client.Projects.Get()
+		require.NoError(t, err)
+		require.Len(t, projects, 1)
+		require.Equal(t, "project-1", projects[0].ProjectID)
+	})
+
+	t.Run("serves the deploy history by page", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		history, err := client.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "project-1"})
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		require.Equal(t, 12, history[0].ID)
+
+		history, err = client.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "project-1", Page: 2, PerPage: 1})
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, 11, history[0].ID)
+
+		history, err = client.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "project-1", Page: 3, PerPage: 1})
+		require.NoError(t, err)
+		require.Empty(t, history)
+	})
+
+	t.Run("returns project not found", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		_, err := client.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "not-exists"})
+		require.True(t, errors.Is(err, sdk.ErrProjectNotFound))
+	})
+
+	t.Run("triggered pipeline goes through the environment statuses", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		config := sdk.DeployConfig{Environment: "production", Revision: "v1.2.0", DeployType: "smart_deploy"}
+		response, err := client.Deploy.Trigger("project-1", config)
+		require.NoError(t, err)
+		require.Equal(t, 13, response.ID)
+		require.Equal(t, []Trigger{{ProjectID: "project-1", PipelineID: 13, Config: config}}, server.Console.Triggers())
+
+		for _, expected := range []string{"pending", "running", "success", "success"} {
+			status, err := client.Deploy.GetStatus("project-1", response.ID, "production")
+			require.NoError(t, err)
+			require.Equal(t, sdk.PipelineStatus{ID: 13, Status: expected}, status)
+		}
+
+		history, err := client.Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "project-1"})
+		require.NoError(t, err)
+		require.Len(t, history, 3)
+		require.Equal(t, 13, history[0].ID)
+		require.Equal(t, "v1.2.0", history[0].Ref)
+		require.Equal(t, sdk.DeployStatusSuccess, history[0].Status)
+		require.Equal(t, "production", history[0].Environment)
+	})
+
+	t.Run("uses the statuses of any environment", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		response, err := client.Deploy.Trigger("project-1", sdk.DeployConfig{Environment: "development", Revision: "master"})
+		require.NoError(t, err)
+		status, err := client.Deploy.GetStatus("project-1", response.ID, "development")
+		require.NoError(t, err)
+		require.Equal(t, sdk.DeployStatusSuccess, status.Status)
+	})
+
+	t.Run("uses the default statuses without fixtures statuses", func(t *testing.T) {
+		server := NewServer(Fixtures{Projects: sdk.Projects{{ID: "id", ProjectID: "project", Environments: []sdk.Environment{{EnvID: "env"}}}}})
+		defer server.Close()
+		client, err := sdk.New(server.Options())
+		require.NoError(t, err)
+
+		response, err := client.Deploy.Trigger("project", sdk.DeployConfig{Environment: "env", Revision: "master"})
+		require.NoError(t, err)
+		require.Equal(t, 1, response.ID)
+		for _, expected := range DefaultPipelineStatuses {
+			status, err := client.Deploy.GetStatus("project", response.ID, "env")
+			require.NoError(t, err)
+			require.Equal(t, expected, status.Status)
+		}
+	})
+
+	t.Run("statuses can be changed", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		server.Console.SetPipelineStatuses("production", sdk.DeployStatusRunning)
+		response, err := client.Deploy.Trigger("project-1", sdk.DeployConfig{Environment: "production", Revision: "v1.2.0"})
+		require.NoError(t, err)
+		status, err := client.Deploy.GetStatus("project-1", response.ID, "production")
+		require.NoError(t, err)
+		require.Equal(t, sdk.DeployStatusRunning, status.Status)
+
+		require.NoError(t, server.Console.SetPipelineStatus(response.ID, sdk.DeployStatusFailed))
+		status, err = client.Deploy.GetStatus("project-1", response.ID, "production")
+		require.NoError(t, err)
+		require.Equal(t, sdk.DeployStatusFailed, status.Status)
+		require.Equal(t, sdk.DeployStatusFailed, server.Console.History("project-1")[0].Status)
+
+		require.EqualError(t, server.Console.SetPipelineStatus(99, sdk.DeployStatusFailed), "pipeline 99 not found")
+	})
+
+	t.Run("rejects trigger of unknown environment", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		_, err := client.Deploy.Trigger("project-1", sdk.DeployConfig{Environment: "staging", Revision: "v1.2.0"})
+		var httpErr *jsonclient.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		require.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+		require.Empty(t, server.Console.Triggers())
+	})
+
+	t.Run("returns not found for unknown pipeline", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		_, err := client.Deploy.GetStatus("project-1", 99, "production")
+		var httpErr *jsonclient.HTTPError
+		require.True(t, errors.As(err, &httpErr))
+		require.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	})
+
+	t.Run("serves the scripting endpoints", func(t *testing.T) {
+		server, client := testServer(t)
+		defer server.Close()
+
+		response, err := client.Deploy.Trigger("project-1", sdk.DeployConfig{Environment: "production", Revision: "v1.2.0"})
+		require.NoError(t, err)
+
+		res, err := http.Get(fmt.Sprintf("%s/fake/triggers", server.URL))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var triggers []Trigger
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&triggers))
+		require.Equal(t, server.Console.Triggers(), triggers)
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/fake/pipelines/%d/status", server.URL, response.ID), bytes.NewBufferString(`{"status":"canceled"}`))
+		require.NoError(t, err)
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		status, err := client.Deploy.GetStatus("project-1", response.ID, "production")
+		require.NoError(t, err)
+		require.Equal(t, sdk.DeployStatusCanceled, status.Status)
+	})
+
+	t.Run("returns errors for unknown routes and methods", func(t *testing.T) {
+		server, _ := testServer(t)
+		defer server.Close()
+
+		res, err := http.Get(fmt.Sprintf("%s/api/unknown/", server.URL))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res, err = http.Post(fmt.Sprintf("%s/api/backend/projects/", server.URL), "application/json", bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	})
+}
@@ -0,0 +1,73 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// Marketplace item types
+const (
+	MarketplaceTypeTemplate = "template"
+	MarketplaceTypePlugin   = "plugin"
+	MarketplaceTypeExample  = "example"
+)
+
+// MarketplaceCategory groups marketplace items
+type MarketplaceCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// MarketplaceItem is a template, plugin or example which can be used to
+// create a service
+type MarketplaceItem struct {
+	ID          string              `json:"_id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Type        string              `json:"type"`
+	Category    MarketplaceCategory `json:"category"`
+}
+
+// MarketplaceItems is a list of marketplace item
+type MarketplaceItems []MarketplaceItem
+
+// MarketplaceQuery wraps query filters for marketplace items.
+// Empty fields are not used as filter.
+type MarketplaceQuery struct {
+	Search   string
+	Category string
+	Type     string
+}
+
+// MarketplaceClient is the console implementations of the IMarketplace interface
+type MarketplaceClient struct {
+	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
+}
+
+// Get method to fetch the marketplace items matching query
+func (m MarketplaceClient) Get(query MarketplaceQuery) (MarketplaceItems, error) {
+	params := url.Values{}
+	if query.Search != "" {
+		params.Set("search", query.Search)
+	}
+	if query.Category != "" {
+		params.Set("categoryId", query.Category)
+	}
+	if query.Type != "" {
+		params.Set("type", query.Type)
+	}
+	path := "api/backend/marketplace/"
+	if len(params) > 0 {
+		path = fmt.Sprintf("%s?%s", path, params.Encode())
+	}
+
+	items := MarketplaceItems{}
+	if err := doRequest(m.HTTPClient, m.JSONClient, http.MethodGet, path, nil, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
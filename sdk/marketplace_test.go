@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarketplaceGet(t *testing.T) {
+	marketplaceResponseBody := readTestData(t, "marketplace.json")
+	requestAssertions := func(expectedQuery string) func(t *testing.T, req *http.Request) {
+		return func(t *testing.T, req *http.Request) {
+			t.Helper()
+			require.Equal(t, "/api/backend/marketplace/", req.URL.Path)
+			require.Equal(t, expectedQuery, req.URL.RawQuery)
+			require.Equal(t, http.MethodGet, req.Method)
+			cookieSid, err := req.Cookie("sid")
+			require.NoError(t, err)
+			require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+		}
+	}
+
+	t.Run("correctly returns marketplace items", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions(""), marketplaceResponseBody, 200)
+		defer s.Close()
+		client := testCreateMarketplaceClient(t, fmt.Sprintf("%s/", s.URL))
+
+		items, err := client.Get(MarketplaceQuery{})
+		require.NoError(t, err)
+		require.Equal(t, MarketplaceItems{
+			{
+				ID:          "node-template",
+				Name:        "Node.js Template",
+				Description: "A template to create Node.js services",
+				Type:        MarketplaceTypeTemplate,
+				Category:    MarketplaceCategory{ID: "nodejs", Label: "Node.js"},
+			},
+			{
+				ID:          "crud-service",
+				Name:        "CRUD Service",
+				Description: "Expose collections through REST APIs",
+				Type:        MarketplaceTypePlugin,
+				Category:    MarketplaceCategory{ID: "data", Label: "Data Stores"},
+			},
+		}, items)
+	})
+
+	t.Run("passes filters as query parameters", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions("categoryId=nodejs&search=node&type=template"), "[]", 200)
+		defer s.Close()
+		client := testCreateMarketplaceClient(t, fmt.Sprintf("%s/", s.URL))
+
+		items, err := client.Get(MarketplaceQuery{Search: "node", Category: "nodejs", Type: MarketplaceTypeTemplate})
+		require.NoError(t, err)
+		require.Equal(t, MarketplaceItems{}, items)
+	})
+
+	t.Run("throws when server respond with 401", func(t *testing.T) {
+		responseBody := `{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`
+		s := testCreateResponseServer(t, requestAssertions(""), responseBody, 401)
+		defer s.Close()
+		client := testCreateMarketplaceClient(t, fmt.Sprintf("%s/", s.URL))
+
+		items, err := client.Get(MarketplaceQuery{})
+		require.Nil(t, items)
+		require.EqualError(t, err, fmt.Sprintf("GET %s/api/backend/marketplace/: 401 - %s", s.URL, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("throws if response body is not as expected", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions(""), `{"_id":"not-a-list"}`, 200)
+		defer s.Close()
+		client := testCreateMarketplaceClient(t, fmt.Sprintf("%s/", s.URL))
+
+		items, err := client.Get(MarketplaceQuery{})
+		require.Nil(t, items)
+		require.True(t, errors.Is(err, ErrGeneric))
+	})
+}
+
+func testCreateMarketplaceClient(t *testing.T, url string) IMarketplace {
+	t.Helper()
+	return MarketplaceClient{
+		JSONClient: testCreateClient(t, url),
+	}
+}
@@ -0,0 +1,111 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// Member roles
+const (
+	RoleGuest        = "guest"
+	RoleReporter     = "reporter"
+	RoleDeveloper    = "developer"
+	RoleMaintainer   = "maintainer"
+	RoleProjectAdmin = "project-admin"
+	RoleCompanyOwner = "company-owner"
+)
+
+// Member is a user with a role on a project or a company
+type Member struct {
+	ID    string `json:"_id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// Members is a list of member
+type Members []Member
+
+// MembersScope selects the members of a project, when ProjectID is set,
+// or of a company.
+type MembersScope struct {
+	ProjectID string
+	CompanyID string
+}
+
+// AddMemberRequest holds the user to be added as member
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// MembersClient is the console implementations of the IMembers interface
+type MembersClient struct {
+	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
+	projects   *projectCache
+}
+
+// List method to fetch the members of scope
+func (m MembersClient) List(scope MembersScope) (Members, error) {
+	path, err := m.membersPath(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	members := Members{}
+	if err := doRequest(m.HTTPClient, m.JSONClient, http.MethodGet, path, nil, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Add method adds the user to the members of scope
+func (m MembersClient) Add(scope MembersScope, request AddMemberRequest) (*Member, error) {
+	path, err := m.membersPath(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &Member{}
+	if err := doRequest(m.HTTPClient, m.JSONClient, http.MethodPost, path, request, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Remove method removes the member from scope
+func (m MembersClient) Remove(scope MembersScope, memberID string) error {
+	path, err := m.membersPath(scope)
+	if err != nil {
+		return err
+	}
+	return doRequest(m.HTTPClient, m.JSONClient, http.MethodDelete, fmt.Sprintf("%s%s/", path, url.PathEscape(memberID)), nil, nil)
+}
+
+// SetRole method changes the role of the member of scope
+func (m MembersClient) SetRole(scope MembersScope, memberID, role string) error {
+	path, err := m.membersPath(scope)
+	if err != nil {
+		return err
+	}
+	return doRequest(m.HTTPClient, m.JSONClient, http.MethodPatch, fmt.Sprintf("%s%s/", path, url.PathEscape(memberID)), setRoleRequest{Role: role}, nil)
+}
+
+func (m MembersClient) membersPath(scope MembersScope) (string, error) {
+	if scope.ProjectID == "" {
+		return fmt.Sprintf("api/backend/tenants/%s/members/", url.PathEscape(scope.CompanyID)), nil
+	}
+	project, err := m.projects.get(m.HTTPClient, m.JSONClient, scope.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("api/backend/projects/%s/members/", url.PathEscape(project.ID)), nil
+}
@@ -0,0 +1,194 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembersList(t *testing.T) {
+	projectsListResponseBody := readTestData(t, "projects.json")
+	membersListResponseBody := readTestData(t, "members.json")
+	projectRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.True(t, strings.HasSuffix(req.URL.Path, "/projects/"))
+		require.Equal(t, http.MethodGet, req.Method)
+	}
+	membersRequestAssertions := func(path string) func(t *testing.T, req *http.Request) {
+		return func(t *testing.T, req *http.Request) {
+			t.Helper()
+			require.Equal(t, path, req.URL.Path)
+			require.Equal(t, http.MethodGet, req.Method)
+			cookieSid, err := req.Cookie("sid")
+			require.NoError(t, err)
+			require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+		}
+	}
+	expectedMembers := Members{
+		{ID: "user-1", Name: "Jane Doe", Email: "jane.doe@example.com", Role: RoleMaintainer},
+		{ID: "user-2", Name: "John Doe", Email: "john.doe@example.com", Role: RoleDeveloper},
+	}
+
+	t.Run("correctly returns project members", func(t *testing.T) {
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: membersRequestAssertions("/api/backend/projects/mongo-id-2/members/"), body: membersListResponseBody, status: 200},
+		})
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		members, err := client.List(MembersScope{ProjectID: "project-2"})
+		require.NoError(t, err)
+		require.Equal(t, expectedMembers, members)
+	})
+
+	t.Run("correctly returns company members", func(t *testing.T) {
+		s := testCreateResponseServer(t, membersRequestAssertions("/api/backend/tenants/company-1/members/"), membersListResponseBody, 200)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		members, err := client.List(MembersScope{CompanyID: "company-1"})
+		require.NoError(t, err)
+		require.Equal(t, expectedMembers, members)
+	})
+
+	t.Run("Error occurs when projectId does not exist", func(t *testing.T) {
+		s := testCreateResponseServer(t, projectRequestAssertions, projectsListResponseBody, 200)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		members, err := client.List(MembersScope{ProjectID: "project-NaN"})
+		require.Nil(t, members)
+		require.EqualError(t, err, fmt.Sprintf("%s: project-NaN", ErrProjectNotFound))
+	})
+
+	t.Run("throws when server respond with 401", func(t *testing.T) {
+		responseBody := `{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`
+		s := testCreateResponseServer(t, membersRequestAssertions("/api/backend/tenants/company-1/members/"), responseBody, 401)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		members, err := client.List(MembersScope{CompanyID: "company-1"})
+		require.Nil(t, members)
+		require.EqualError(t, err, fmt.Sprintf("GET %s/api/backend/tenants/company-1/members/: 401 - %s", s.URL, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("throws if response body is not as expected", func(t *testing.T) {
+		s := testCreateResponseServer(t, membersRequestAssertions("/api/backend/tenants/company-1/members/"), `{"_id":"not-a-list"}`, 200)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		members, err := client.List(MembersScope{CompanyID: "company-1"})
+		require.Nil(t, members)
+		require.True(t, errors.Is(err, ErrGeneric))
+	})
+}
+
+func TestMembersEdit(t *testing.T) {
+	scope := MembersScope{CompanyID: "company-1"}
+	requestAssertions := func(method, path string, expectedBody map[string]interface{}) func(t *testing.T, req *http.Request) {
+		return func(t *testing.T, req *http.Request) {
+			t.Helper()
+			require.Equal(t, path, req.URL.Path)
+			require.Equal(t, method, req.Method)
+			if expectedBody != nil {
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+				require.Equal(t, expectedBody, body)
+			}
+		}
+	}
+
+	t.Run("adds member", func(t *testing.T) {
+		assertions := requestAssertions(http.MethodPost, "/api/backend/tenants/company-1/members/", map[string]interface{}{
+			"email": "jane.doe@example.com",
+			"role":  "developer",
+		})
+		responseBody := `{"_id":"user-1","name":"Jane Doe","email":"jane.doe@example.com","role":"developer"}`
+		s := testCreateResponseServer(t, assertions, responseBody, 200)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		member, err := client.Add(scope, AddMemberRequest{Email: "jane.doe@example.com", Role: RoleDeveloper})
+		require.NoError(t, err)
+		require.Equal(t, &Member{ID: "user-1", Name: "Jane Doe", Email: "jane.doe@example.com", Role: RoleDeveloper}, member)
+	})
+
+	t.Run("add member throws when server respond with 409", func(t *testing.T) {
+		responseBody := `{"statusCode":409,"error":"Conflict","message":"user is already a member"}`
+		s := testCreateResponseServer(t, nil, responseBody, 409)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		member, err := client.Add(scope, AddMemberRequest{Email: "jane.doe@example.com", Role: RoleDeveloper})
+		require.Nil(t, member)
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("removes member", func(t *testing.T) {
+		s := testCreateResponseServer(t, requestAssertions(http.MethodDelete, "/api/backend/tenants/company-1/members/user-1/", nil), "", 204)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		require.NoError(t, client.Remove(scope, "user-1"))
+	})
+
+	t.Run("remove member throws when server respond with 404", func(t *testing.T) {
+		responseBody := `{"statusCode":404,"error":"Not Found","message":"member not found"}`
+		s := testCreateResponseServer(t, nil, responseBody, 404)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		err := client.Remove(scope, "user-1")
+		require.EqualError(t, err, fmt.Sprintf("DELETE %s/api/backend/tenants/company-1/members/user-1/: 404 - %s", s.URL, responseBody))
+	})
+
+	t.Run("escapes the member and the company ids", func(t *testing.T) {
+		assertions := func(t *testing.T, req *http.Request) {
+			require.Equal(t, "/api/backend/tenants/company%2F1/members/user%2F1%3F/", req.URL.EscapedPath())
+		}
+		s := testCreateResponseServer(t, assertions, "", 204)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		require.NoError(t, client.Remove(MembersScope{CompanyID: "company/1"}, "user/1?"))
+	})
+
+	t.Run("resolves the project once", func(t *testing.T) {
+		s := testCreateMultiResponseServer(t, []response{
+			{body: readTestData(t, "projects.json"), status: 200},
+			{assertions: requestAssertions(http.MethodDelete, "/api/backend/projects/mongo-id-2/members/user-1/", nil), status: 204},
+			{assertions: requestAssertions(http.MethodDelete, "/api/backend/projects/mongo-id-2/members/user-2/", nil), status: 204},
+		})
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		require.NoError(t, client.Remove(MembersScope{ProjectID: "project-2"}, "user-1"))
+		require.NoError(t, client.Remove(MembersScope{ProjectID: "project-2"}, "user-2"))
+	})
+
+	t.Run("sets member role", func(t *testing.T) {
+		assertions := requestAssertions(http.MethodPatch, "/api/backend/tenants/company-1/members/user-1/", map[string]interface{}{
+			"role": "maintainer",
+		})
+		s := testCreateResponseServer(t, assertions, "", 204)
+		defer s.Close()
+		client := testCreateMembersClient(t, fmt.Sprintf("%s/", s.URL))
+
+		require.NoError(t, client.SetRole(scope, "user-1", RoleMaintainer))
+	})
+}
+
+func testCreateMembersClient(t *testing.T, url string) IMembers {
+	t.Helper()
+	return MembersClient{
+		JSONClient: testCreateClient(t, url),
+		projects:   newProjectCache(),
+	}
+}
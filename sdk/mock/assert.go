@@ -0,0 +1,97 @@
+package mock
+
+import (
+	"strings"
+)
+
+// TestingT is the subset of testing.T used by the assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertCalled asserts that method has been called with args, or at all if
+// no args are passed.
+func (m *Mock) AssertCalled(t TestingT, method string, args ...interface{}) bool {
+	t.Helper()
+	if len(m.matchingCalls(method, args)) == 0 {
+		t.Errorf("expected call %s%v, recorded calls:\n%s", method, args, m.recordedCalls())
+		return false
+	}
+	return true
+}
+
+// AssertCalledOnceWith asserts that method has been called exactly once
+// with args, e.g. once with a project id.
+func (m *Mock) AssertCalledOnceWith(t TestingT, method string, args ...interface{}) bool {
+	t.Helper()
+	if calls := m.matchingCalls(method, args); len(calls) != 1 {
+		t.Errorf("expected 1 call %s%v, found %d, recorded calls:\n%s", method, args, len(calls), m.recordedCalls())
+		return false
+	}
+	return true
+}
+
+// AssertNotCalled asserts that method has not been called with args, or at
+// all if no args are passed.
+func (m *Mock) AssertNotCalled(t TestingT, method string, args ...interface{}) bool {
+	t.Helper()
+	if calls := m.matchingCalls(method, args); len(calls) != 0 {
+		t.Errorf("unexpected call %s, recorded calls:\n%s", calls[0], m.recordedCalls())
+		return false
+	}
+	return true
+}
+
+// AssertNumberOfCalls asserts that method has been called n times.
+func (m *Mock) AssertNumberOfCalls(t TestingT, method string, n int) bool {
+	t.Helper()
+	if calls := m.Calls(method); len(calls) != n {
+		t.Errorf("expected %d calls of %s, found %d, recorded calls:\n%s", n, method, len(calls), m.recordedCalls())
+		return false
+	}
+	return true
+}
+
+// AssertExpectations asserts that every call has a programmed response and
+// that the expectations limited by Once or Times have been called exactly
+// the number of times set.
+func (m *Mock) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ok := true
+	for _, call := range m.unexpected {
+		t.Errorf("unexpected call %s", call)
+		ok = false
+	}
+	for _, expectation := range m.expectations {
+		if calls := expectation.calls + expectation.extra; expectation.times > 0 && calls != expectation.times {
+			t.Errorf("expected %d calls %s%v, found %d", expectation.times, expectation.method, expectation.args, calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (m *Mock) matchingCalls(method string, args []interface{}) []Call {
+	var calls []Call
+	for _, call := range m.Calls(method) {
+		if len(args) == 0 || matchArgs(args, call.Args) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+func (m *Mock) recordedCalls() string {
+	calls := m.Calls("")
+	if len(calls) == 0 {
+		return "  none"
+	}
+	lines := make([]string, 0, len(calls))
+	for _, call := range calls {
+		lines = append(lines, "  "+call.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+package mock
+
+import (
+	"github.com/mia-platform/miactl/sdk"
+)
+
+type projectsClient struct{ m *Mock }
+
+func (c projectsClient) Get() (sdk.Projects, error) {
+	r := c.m.call(ProjectsGet)
+	var projects sdk.Projects
+	r.get(0, &projects)
+	return projects, r.err(1)
+}
+
+func (c projectsClient) Create(request sdk.CreateProjectRequest) (*sdk.Project, error) {
+	r := c.m.call(ProjectsCreate, request)
+	var project *sdk.Project
+	r.get(0, &project)
+	return project, r.err(1)
+}
+
+type deployClient struct{ m *Mock }
+
+func (c deployClient) GetHistory(query sdk.DeployHistoryQuery) ([]sdk.DeployItem, error) {
+	r := c.m.call(DeployGetHistory, query)
+	var history []sdk.DeployItem
+	r.get(0, &history)
+	return history, r.err(1)
+}
+
+func (c deployClient) Trigger(projectID string, cfg sdk.DeployConfig) (sdk.DeployResponse, error) {
+	r := c.m.call(DeployTrigger, projectID, cfg)
+	var response sdk.DeployResponse
+	r.get(0, &response)
+	return response, r.err(1)
+}
+
+func (c deployClient) GetStatus(projectID string, pipelineID int, environment string) (sdk.PipelineStatus, error) {
+	r := c.m.call(DeployGetStatus, projectID, pipelineID, environment)
+	var status sdk.PipelineStatus
+	r.get(0, &status)
+	return status, r.err(1)
+}
+
+type companiesClient struct{ m *Mock }
+
+func (c companiesClient) Get() (sdk.Companies, error) {
+	r := c.m.call(CompaniesGet)
+	var companies sdk.Companies
+	r.get(0, &companies)
+	return companies, r.err(1)
+}
+
+type marketplaceClient struct{ m *Mock }
+
+func (c marketplaceClient) Get(query sdk.MarketplaceQuery) (sdk.MarketplaceItems, error) {
+	r := c.m.call(MarketplaceGet, query)
+	var items sdk.MarketplaceItems
+	r.get(0, &items)
+	return items, r.err(1)
+}
+
+type servicesClient struct{ m *Mock }
+
+func (c servicesClient) Create(projectID string, request sdk.CreateServiceRequest) (*sdk.CreateServiceResponse, error) {
+	r := c.m.call(ServicesCreate, projectID, request)
+	var response *sdk.CreateServiceResponse
+	r.get(0, &response)
+	return response, r.err(1)
+}
+
+type membersClient struct{ m *Mock }
+
+func (c membersClient) List(scope sdk.MembersScope) (sdk.Members, error) {
+	r := c.m.call(MembersList, scope)
+	var members sdk.Members
+	r.get(0, &members)
+	return members, r.err(1)
+}
+
+func (c membersClient) Add(scope sdk.MembersScope, request sdk.AddMemberRequest) (*sdk.Member, error) {
+	r := c.m.call(MembersAdd, scope, request)
+	var member *sdk.Member
+	r.get(0, &member)
+	return member, r.err(1)
+}
+
+func (c membersClient) Remove(scope sdk.MembersScope, memberID string) error {
+	return c.m.call(MembersRemove, scope, memberID).err(0)
+}
+
+func (c membersClient) SetRole(scope sdk.MembersScope, memberID, role string) error {
+	return c.m.call(MembersSetRole, scope, memberID, role).err(0)
+}
+
+type apiKeysClient struct{ m *Mock }
+
+func (c apiKeysClient) List(projectID string) (sdk.APIKeys, error) {
+	r := c.m.call(APIKeysList, projectID)
+	var apiKeys sdk.APIKeys
+	r.get(0, &apiKeys)
+	return apiKeys, r.err(1)
+}
+
+func (c apiKeysClient) Create(projectID string, request sdk.CreateAPIKeyRequest) (*sdk.APIKey, error) {
+	r := c.m.call(APIKeysCreate, projectID, request)
+	var apiKey *sdk.APIKey
+	r.get(0, &apiKey)
+	return apiKey, r.err(1)
+}
+
+func (c apiKeysClient) Delete(projectID, apiKeyID string) error {
+	return c.m.call(APIKeysDelete, projectID, apiKeyID).err(0)
+}
+
+func (c apiKeysClient) Rotate(projectID, apiKeyID string) (*sdk.APIKey, error) {
+	r := c.m.call(APIKeysRotate, projectID, apiKeyID)
+	var apiKey *sdk.APIKey
+	r.get(0, &apiKey)
+	return apiKey, r.err(1)
+}
@@ -0,0 +1,253 @@
+// Package mock provides a test double of every sdk client, for the tools
+// built on the sdk. Each call is recorded with its arguments and answered
+// with the responses programmed for it:
+//
+//	m := mock.New()
+//	m.On(mock.ProjectsGet).Return(sdk.Projects{{ProjectID: "my-project"}}, nil)
+//	m.On(mock.DeployTrigger, "my-project", mock.Anything).Return(sdk.DeployResponse{ID: 1}, nil).Once()
+//
+//	client := m.MiaClient()
+//	// ... run the code under test with client
+//
+//	m.AssertCalledOnceWith(t, mock.DeployTrigger, "my-project", mock.Anything)
+//	m.AssertExpectations(t)
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/mia-platform/miactl/sdk"
+)
+
+// Names of the mocked methods, as client.method.
+const (
+	NewMiaClient = "NewMiaClient"
+
+	ProjectsGet    = "Projects.Get"
+	ProjectsCreate = "Projects.Create"
+
+	DeployGetHistory = "Deploy.GetHistory"
+	DeployTrigger    = "Deploy.Trigger"
+	DeployGetStatus  = "Deploy.GetStatus"
+
+	CompaniesGet = "Companies.Get"
+
+	MarketplaceGet = "Marketplace.Get"
+
+	ServicesCreate = "Services.Create"
+
+	MembersList    = "Members.List"
+	MembersAdd     = "Members.Add"
+	MembersRemove  = "Members.Remove"
+	MembersSetRole = "Members.SetRole"
+
+	APIKeysList   = "APIKeys.List"
+	APIKeysCreate = "APIKeys.Create"
+	APIKeysDelete = "APIKeys.Delete"
+	APIKeysRotate = "APIKeys.Rotate"
+)
+
+// Anything matches any argument.
+const Anything = "mock.Anything"
+
+// ErrUnexpectedCall is returned by the calls without a programmed response.
+var ErrUnexpectedCall = errors.New("Unexpected call")
+
+// Call is a recorded call.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+func (c Call) String() string {
+	return fmt.Sprintf("%s%v", c.Method, c.Args)
+}
+
+// Expectation is a programmed response of a method, returned to the calls
+// matching its arguments.
+type Expectation struct {
+	method string
+	args   []interface{}
+	values []interface{}
+	// times is the number of calls answered, 0 for no limit
+	times int
+	calls int
+	// extra counts the calls matching the expectation after its limit,
+	// answered as unexpected
+	extra int
+}
+
+// Return sets the values returned by the method, in the order of the
+// method results.
+func (e *Expectation) Return(values ...interface{}) *Expectation {
+	e.values = values
+	return e
+}
+
+// Once limits the expectation to a single call.
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Times limits the expectation to n calls: the following calls are
+// answered by the next matching expectation.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) matches(method string, args []interface{}) bool {
+	return e.matchesCall(method, args) && (e.times == 0 || e.calls < e.times)
+}
+
+// matchesCall reports whether the call matches the method and the args of
+// the expectation, regardless of its limit.
+func (e *Expectation) matchesCall(method string, args []interface{}) bool {
+	// without arguments the expectation matches any call of the method
+	return e.method == method && (len(e.args) == 0 || matchArgs(e.args, args))
+}
+
+// Mock records the calls to the clients it returns and answers them with the
+// programmed responses.
+type Mock struct {
+	mu           sync.Mutex
+	calls        []Call
+	expectations []*Expectation
+	// unexpected are the calls matching no expectation
+	unexpected []Call
+}
+
+// New returns a mock without programmed responses.
+func New() *Mock {
+	return &Mock{}
+}
+
+// On programs a response of method for the calls with args, or for any
+// call if no args are passed. The expectations are matched in the order
+// they are programmed.
+func (m *Mock) On(method string, args ...interface{}) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expectation := &Expectation{method: method, args: args}
+	m.expectations = append(m.expectations, expectation)
+	return expectation
+}
+
+// MiaClient returns the sdk client whose calls are recorded by the mock.
+func (m *Mock) MiaClient() *sdk.MiaClient {
+	return &sdk.MiaClient{
+		Projects:    projectsClient{m},
+		Deploy:      deployClient{m},
+		Companies:   companiesClient{m},
+		Marketplace: marketplaceClient{m},
+		Services:    servicesClient{m},
+		Members:     membersClient{m},
+		APIKeys:     apiKeysClient{m},
+	}
+}
+
+// Creator returns a function with the signature of sdk.New, which records
+// the options and returns the mocked client, or the error programmed for NewMiaClient.
+func (m *Mock) Creator() func(sdk.Options) (*sdk.MiaClient, error) {
+	return func(opts sdk.Options) (*sdk.MiaClient, error) {
+		r := m.call(NewMiaClient, opts)
+		if err := r.err(0); err != nil {
+			return nil, err
+		}
+		return m.MiaClient(), nil
+	}
+}
+
+// Calls returns the recorded calls of method, or all the calls if method
+// is empty.
+func (m *Mock) Calls(method string) []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []Call
+	for _, call := range m.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset removes the recorded calls and the programmed responses.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.expectations = nil
+	m.unexpected = nil
+}
+
+// call records the call and returns the response of the first matching
+// expectation. A call matching only expectations past their limit is
+// counted on the first of them, to be reported by AssertExpectations.
+func (m *Mock) call(method string, args ...interface{}) response {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call := Call{Method: method, Args: args}
+	m.calls = append(m.calls, call)
+	for _, expectation := range m.expectations {
+		if expectation.matches(method, args) {
+			expectation.calls++
+			return response{method: method, values: expectation.values}
+		}
+	}
+	for _, expectation := range m.expectations {
+		if expectation.matchesCall(method, args) {
+			expectation.extra++
+			return response{method: method, unexpected: true}
+		}
+	}
+	m.unexpected = append(m.unexpected, call)
+	return response{method: method, unexpected: true}
+}
+
+// response holds the values returned by a call.
+type response struct {
+	method     string
+	values     []interface{}
+	unexpected bool
+}
+
+// get sets target to the value at index i, if any. It panics if the
+// programmed value has not the type of the method result.
+func (r response) get(i int, target interface{}) {
+	if i >= len(r.values) || r.values[i] == nil {
+		return
+	}
+	value := reflect.ValueOf(r.values[i])
+	result := reflect.ValueOf(target).Elem()
+	if !value.Type().AssignableTo(result.Type()) {
+		panic(fmt.Sprintf("mock: %s returns %s at position %d, not %T", r.method, result.Type(), i, r.values[i]))
+	}
+	result.Set(value)
+}
+
+// err returns the error at index i, or ErrUnexpectedCall if the call has
+// no programmed response.
+func (r response) err(i int) error {
+	if r.unexpected {
+		return fmt.Errorf("%w: %s", ErrUnexpectedCall, r.method)
+	}
+	var err error
+	r.get(i, &err)
+	return err
+}
+
+func matchArgs(expected, actual []interface{}) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i] != Anything && !reflect.DeepEqual(expected[i], actual[i]) {
+			return false
+		}
+	}
+	return true
+}
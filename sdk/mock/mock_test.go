@@ -0,0 +1,227 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mia-platform/miactl/sdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMock(t *testing.T) {
+	t.Run("returns the programmed response", func(t *testing.T) {
+		m := New()
+		projects := sdk.Projects{{ProjectID: "project-1"}}
+		m.On(ProjectsGet).Return(projects, nil)
+
+		got, err := m.MiaClient().Projects.Get()
+		require.NoError(t, err)
+		require.Equal(t, projects, got)
+	})
+
+	t.Run("returns the programmed error", func(t *testing.T) {
+		m := New()
+		m.On(MembersRemove).Return(sdk.ErrHTTP)
+
+		err := m.MiaClient().Members.Remove(sdk.MembersScope{ProjectID: "project-1"}, "member-1")
+		require.True(t, errors.Is(err, sdk.ErrHTTP))
+	})
+
+	t.Run("returns error on calls without programmed response", func(t *testing.T) {
+		m := New()
+
+		projects, err := m.MiaClient().Projects.Get()
+		require.Nil(t, projects)
+		require.EqualError(t, err, fmt.Sprintf("%s: %s", ErrUnexpectedCall, ProjectsGet))
+		require.True(t, errors.Is(err, ErrUnexpectedCall))
+	})
+
+	t.Run("matches the responses by arguments", func(t *testing.T) {
+		m := New()
+		m.On(APIKeysList, "project-1").Return(sdk.APIKeys{{ID: "key-1"}}, nil)
+		m.On(APIKeysList, "project-2").Return(nil, sdk.ErrHTTP)
+		client := m.MiaClient()
+
+		apiKeys, err := client.APIKeys.List("project-1")
+		require.NoError(t, err)
+		require.Equal(t, sdk.APIKeys{{ID: "key-1"}}, apiKeys)
+
+		_, err = client.APIKeys.List("project-2")
+		require.True(t, errors.Is(err, sdk.ErrHTTP))
+
+		_, err = client.APIKeys.List("project-3")
+		require.True(t, errors.Is(err, ErrUnexpectedCall))
+	})
+
+	t.Run("returns the responses limited by times in order", func(t *testing.T) {
+		m := New()
+		m.On(DeployGetStatus, "project-1", 42, Anything).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusRunning}, nil).Times(2)
+		m.On(DeployGetStatus, "project-1", 42, Anything).Return(sdk.PipelineStatus{ID: 42, Status: sdk.DeployStatusSuccess}, nil)
+		client := m.MiaClient()
+
+		var statuses []string
+		for i := 0; i < 4; i++ {
+			status, err := client.Deploy.GetStatus("project-1", 42, "production")
+			require.NoError(t, err)
+			statuses = append(statuses, status.Status)
+		}
+		require.Equal(t, []string{"running", "running", "success", "success"}, statuses)
+	})
+
+	t.Run("creates the client recording the options", func(t *testing.T) {
+		m := New()
+		m.On(NewMiaClient).Return(nil)
+
+		client, err := m.Creator()(sdk.Options{APIBaseURL: "http://console/"})
+		require.NoError(t, err)
+		require.NotNil(t, client)
+		m.AssertCalledOnceWith(t, NewMiaClient, sdk.Options{APIBaseURL: "http://console/"})
+	})
+
+	t.Run("returns the error programmed for the client creation", func(t *testing.T) {
+		m := New()
+		m.On(NewMiaClient).Return(sdk.ErrCreateClient)
+
+		client, err := m.Creator()(sdk.Options{})
+		require.Nil(t, client)
+		require.True(t, errors.Is(err, sdk.ErrCreateClient))
+	})
+
+	t.Run("panics if the programmed value has the wrong type", func(t *testing.T) {
+		m := New()
+		m.On(CompaniesGet).Return(sdk.Projects{}, nil)
+
+		require.PanicsWithValue(t, "mock: Companies.Get returns sdk.Companies at position 0, not sdk.Projects", func() {
+			m.MiaClient().Companies.Get()
+		})
+	})
+
+	t.Run("records concurrent calls", func(t *testing.T) {
+		m := New()
+		m.On(DeployTrigger).Return(sdk.DeployResponse{ID: 1}, nil)
+		client := m.MiaClient()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.Deploy.Trigger("project-1", sdk.DeployConfig{Environment: "production"})
+			}()
+		}
+		wg.Wait()
+		m.AssertNumberOfCalls(t, DeployTrigger, 10)
+	})
+
+	t.Run("resets calls and responses", func(t *testing.T) {
+		m := New()
+		m.On(CompaniesGet).Return(sdk.Companies{}, nil)
+		m.MiaClient().Companies.Get()
+
+		m.Reset()
+		require.Empty(t, m.Calls(""))
+		_, err := m.MiaClient().Companies.Get()
+		require.True(t, errors.Is(err, ErrUnexpectedCall))
+	})
+}
+
+func TestMockAssertions(t *testing.T) {
+	cfg := sdk.DeployConfig{Environment: "production", Revision: "v1.0.0"}
+	newTriggeredMock := func() *Mock {
+		m := New()
+		m.On(DeployTrigger, "project-1", Anything).Return(sdk.DeployResponse{ID: 1}, nil).Once()
+		m.MiaClient().Deploy.Trigger("project-1", cfg)
+		return m
+	}
+
+	t.Run("records the calls with their arguments", func(t *testing.T) {
+		m := newTriggeredMock()
+		require.Equal(t, []Call{{Method: DeployTrigger, Args: []interface{}{"project-1", cfg}}}, m.Calls(DeployTrigger))
+		require.Empty(t, m.Calls(DeployGetHistory))
+	})
+
+	t.Run("passes on matching calls", func(t *testing.T) {
+		m := newTriggeredMock()
+		tt := &testingT{}
+		require.True(t, m.AssertCalled(tt, DeployTrigger))
+		require.True(t, m.AssertCalled(tt, DeployTrigger, "project-1", cfg))
+		require.True(t, m.AssertCalledOnceWith(tt, DeployTrigger, "project-1", Anything))
+		require.True(t, m.AssertNotCalled(tt, DeployTrigger, "project-2", Anything))
+		require.True(t, m.AssertNotCalled(tt, DeployGetStatus))
+		require.True(t, m.AssertNumberOfCalls(tt, DeployTrigger, 1))
+		require.True(t, m.AssertExpectations(tt))
+		require.Empty(t, tt.errors)
+	})
+
+	t.Run("fails on missing calls", func(t *testing.T) {
+		m := newTriggeredMock()
+		tt := &testingT{}
+		require.False(t, m.AssertCalled(tt, DeployTrigger, "project-2", cfg))
+		require.Equal(t, []string{"expected call Deploy.Trigger[project-2 {production v1.0.0  false}], recorded calls:\n  Deploy.Trigger[project-1 {production v1.0.0  false}]"}, tt.errors)
+	})
+
+	t.Run("fails on calls made more than once", func(t *testing.T) {
+		m := newTriggeredMock()
+		m.MiaClient().Deploy.Trigger("project-1", cfg)
+		tt := &testingT{}
+		require.False(t, m.AssertCalledOnceWith(tt, DeployTrigger, "project-1", cfg))
+		require.False(t, m.AssertNumberOfCalls(tt, DeployTrigger, 1))
+		require.Len(t, tt.errors, 2)
+		require.True(t, strings.HasPrefix(tt.errors[0], "expected 1 call Deploy.Trigger[project-1 {production v1.0.0  false}], found 2"))
+	})
+
+	t.Run("fails on unexpected calls", func(t *testing.T) {
+		m := newTriggeredMock()
+		tt := &testingT{}
+		require.False(t, m.AssertNotCalled(tt, DeployTrigger, "project-1", Anything))
+
+		m.MiaClient().Deploy.GetHistory(sdk.DeployHistoryQuery{ProjectID: "project-1"})
+		require.False(t, m.AssertExpectations(tt))
+		require.Len(t, tt.errors, 2)
+		require.Equal(t, "unexpected call Deploy.GetHistory[{project-1 0 0}]", tt.errors[1])
+	})
+
+	t.Run("fails on expectations called more than their limit", func(t *testing.T) {
+		m := newTriggeredMock()
+		_, err := m.MiaClient().Deploy.Trigger("project-1", cfg)
+		require.True(t, errors.Is(err, ErrUnexpectedCall))
+		tt := &testingT{}
+		require.False(t, m.AssertExpectations(tt))
+		require.Equal(t, []string{"expected 1 calls Deploy.Trigger[project-1 mock.Anything], found 2"}, tt.errors)
+	})
+
+	t.Run("passes on expectations called their limit", func(t *testing.T) {
+		m := New()
+		m.On(MembersSetRole, Anything, "member-1", "developer").Return(nil).Times(2)
+		m.On(MembersSetRole).Return(nil)
+		for i := 0; i < 3; i++ {
+			m.MiaClient().Members.SetRole(sdk.MembersScope{}, "member-1", "developer")
+		}
+		tt := &testingT{}
+		require.True(t, m.AssertExpectations(tt))
+		require.Empty(t, tt.errors)
+	})
+
+	t.Run("fails on expectations not fully used", func(t *testing.T) {
+		m := New()
+		m.On(MembersSetRole, Anything, "member-1", "developer").Return(nil).Times(2)
+		m.MiaClient().Members.SetRole(sdk.MembersScope{}, "member-1", "developer")
+		tt := &testingT{}
+		require.False(t, m.AssertExpectations(tt))
+		require.Equal(t, []string{"expected 2 calls Members.SetRole[mock.Anything member-1 developer], found 1"}, tt.errors)
+	})
+}
+
+// testingT collects the errors of the assertions.
+type testingT struct {
+	errors []string
+}
+
+func (t *testingT) Helper() {}
+
+func (t *testingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
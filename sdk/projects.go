@@ -1,9 +1,9 @@
 package sdk
 
 import (
-	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/davidebianchi/go-jsonclient"
 )
@@ -34,6 +34,7 @@ type Project struct {
 	Environments         []Environment `json:"environments"`
 	ProjectID            string        `json:"projectId"`
 	Pipelines            Pipelines     `json:"pipelines"`
+	TenantID             string        `json:"tenantId"`
 }
 
 // Projects is a list of project
@@ -42,41 +43,41 @@ type Projects []Project
 // ProjectsClient is the console implementations of the IProjects interface
 type ProjectsClient struct {
 	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
 }
 
 // Get method to fetch the console projects
 func (p ProjectsClient) Get() (Projects, error) {
-	req, err := p.JSONClient.NewRequest(http.MethodGet, "api/backend/projects/", nil)
-	if err != nil {
-		return nil, err
-	}
-
 	projects := Projects{}
-	var httpErr *jsonclient.HTTPError
-	_, err = p.JSONClient.Do(req, &projects)
-	if err != nil {
-		if errors.As(err, &httpErr) {
-			return nil, httpErr
-		}
-		return nil, fmt.Errorf("%w: %s", ErrGeneric, err)
+	if err := doRequest(p.HTTPClient, p.JSONClient, http.MethodGet, "api/backend/projects/", nil, &projects); err != nil {
+		return nil, err
 	}
-
 	return projects, nil
 }
 
-func getProjectByID(client *jsonclient.Client, projectID string) (*Project, error) {
-	req, err := client.NewRequest(http.MethodGet, "api/backend/projects/", nil)
-	if err != nil {
+// CreateProjectRequest holds the settings of a project to be created
+type CreateProjectRequest struct {
+	Name         string        `json:"name"`
+	ProjectID    string        `json:"projectId"`
+	TenantID     string        `json:"tenantId"`
+	TemplateID   string        `json:"templateId,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Environments []Environment `json:"environments"`
+}
+
+// Create method to create a new console project
+func (p ProjectsClient) Create(request CreateProjectRequest) (*Project, error) {
+	project := &Project{}
+	if err := doRequest(p.HTTPClient, p.JSONClient, http.MethodPost, "api/backend/projects/", request, project); err != nil {
 		return nil, err
 	}
+	return project, nil
+}
 
+func getProjectByID(httpClient *http.Client, client *jsonclient.Client, projectID string) (*Project, error) {
 	var projects Projects
-	if _, err := client.Do(req, &projects); err != nil {
-		var httpErr *jsonclient.HTTPError
-		if errors.As(err, &httpErr) {
-			return nil, httpErr
-		}
-		return nil, fmt.Errorf("%w: %s", ErrGeneric, err)
+	if err := doRequest(httpClient, client, http.MethodGet, "api/backend/projects/", nil, &projects); err != nil {
+		return nil, err
 	}
 
 	var project *Project
@@ -92,3 +93,35 @@ func getProjectByID(client *jsonclient.Client, projectID string) (*Project, erro
 	}
 	return project, nil
 }
+
+// projectCache keeps the projects resolved by id, so that the commands
+// calling the console once per row, like the bulk imports, look up each
+// project once per client.
+type projectCache struct {
+	mu       sync.Mutex
+	projects map[string]*Project
+}
+
+func newProjectCache() *projectCache {
+	return &projectCache{projects: map[string]*Project{}}
+}
+
+// get returns the project with projectID, resolved with getProjectByID on
+// the first call. A nil cache resolves the project on each call.
+func (c *projectCache) get(httpClient *http.Client, client *jsonclient.Client, projectID string) (*Project, error) {
+	if c == nil {
+		return getProjectByID(httpClient, client, projectID)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if project, ok := c.projects[projectID]; ok {
+		return project, nil
+	}
+	project, err := getProjectByID(httpClient, client, projectID)
+	if err != nil {
+		return nil, err
+	}
+	c.projects[projectID] = project
+	return project, nil
+}
@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -77,6 +78,18 @@ func TestProjectsGet(t *testing.T) {
 		require.Equal(t, expectedProjects, projects)
 	})
 
+	t.Run("correctly returns projects of a recorded cassette", func(t *testing.T) {
+		client, replayer := testReplayCassette(t, "projects.json")
+
+		projects, err := client.Projects.Get()
+		require.NoError(t, err)
+		require.Len(t, projects, 2)
+		require.Equal(t, "project-2", projects[1].ProjectID)
+		require.Equal(t, "mongo-id-2", projects[1].ID)
+		require.Len(t, projects[1].Environments, 2)
+		require.Empty(t, replayer.Unused())
+	})
+
 	t.Run("throws when server respond with 401", func(t *testing.T) {
 		responseBody := `{"statusCode":401,"error":"Unauthorized","message":"Unauthorized"}`
 		s := testCreateResponseServer(t, requestAssertions, responseBody, 401)
@@ -118,7 +131,7 @@ func TestGetProjectByID(t *testing.T) {
 		defer s.Close()
 
 		client := testCreateClient(t, fmt.Sprintf("%s/", s.URL))
-		project, err := getProjectByID(client, "project1")
+		project, err := getProjectByID(http.DefaultClient, client, "project1")
 		require.Nil(t, project)
 		require.EqualError(t, err, fmt.Sprintf("GET %s/api/backend/projects/: 401 - %s", s.URL, responseBody))
 		require.True(t, errors.Is(err, ErrHTTP))
@@ -130,7 +143,7 @@ func TestGetProjectByID(t *testing.T) {
 		defer s.Close()
 
 		client := testCreateClient(t, fmt.Sprintf("%s/", s.URL))
-		project, err := getProjectByID(client, "project1")
+		project, err := getProjectByID(http.DefaultClient, client, "project1")
 		require.Nil(t, project)
 		require.EqualError(t, err, fmt.Sprintf("%s: json: cannot unmarshal number into Go struct field Project._id of type string", ErrGeneric))
 		require.True(t, errors.Is(err, ErrGeneric))
@@ -141,7 +154,7 @@ func TestGetProjectByID(t *testing.T) {
 		defer s.Close()
 
 		client := testCreateClient(t, fmt.Sprintf("%s/", s.URL))
-		project, err := getProjectByID(client, "project1")
+		project, err := getProjectByID(http.DefaultClient, client, "project1")
 		require.Nil(t, project)
 		require.EqualError(t, err, fmt.Sprintf("%s: project1", ErrProjectNotFound))
 		require.True(t, errors.Is(err, ErrProjectNotFound))
@@ -152,7 +165,7 @@ func TestGetProjectByID(t *testing.T) {
 		defer s.Close()
 
 		client := testCreateClient(t, fmt.Sprintf("%s/", s.URL))
-		project, err := getProjectByID(client, "project-2")
+		project, err := getProjectByID(http.DefaultClient, client, "project-2")
 		require.NoError(t, err)
 		require.Equal(t, &Project{
 			ID:                   "mongo-id-2",
@@ -179,6 +192,63 @@ func TestGetProjectByID(t *testing.T) {
 	})
 }
 
+func TestProjectsCreate(t *testing.T) {
+	request := CreateProjectRequest{
+		Name:       "Project 3",
+		ProjectID:  "project-3",
+		TenantID:   "company-1",
+		TemplateID: "template-1",
+		Environments: []Environment{{
+			DisplayName: "Development",
+			EnvID:       "development",
+			Cluster: Cluster{
+				Hostname:  "127.0.0.1",
+				Namespace: "project-3-development",
+			},
+		}},
+	}
+	requestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/backend/projects/", req.URL.Path)
+		require.Equal(t, http.MethodPost, req.Method)
+		cookieSid, err := req.Cookie("sid")
+		require.NoError(t, err)
+		require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+
+		var body CreateProjectRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		require.Equal(t, request, body)
+	}
+
+	t.Run("correctly creates project", func(t *testing.T) {
+		responseBody := `{"_id":"mongo-id-3","name":"Project 3","projectId":"project-3","tenantId":"company-1"}`
+		s := testCreateResponseServer(t, requestAssertions, responseBody, 200)
+		defer s.Close()
+		client := testCreateProjectClient(t, fmt.Sprintf("%s/", s.URL))
+
+		project, err := client.Create(request)
+		require.NoError(t, err)
+		require.Equal(t, &Project{
+			ID:        "mongo-id-3",
+			Name:      "Project 3",
+			ProjectID: "project-3",
+			TenantID:  "company-1",
+		}, project)
+	})
+
+	t.Run("throws when server respond with 409", func(t *testing.T) {
+		responseBody := `{"statusCode":409,"error":"Conflict","message":"project already exists"}`
+		s := testCreateResponseServer(t, requestAssertions, responseBody, 409)
+		defer s.Close()
+		client := testCreateProjectClient(t, fmt.Sprintf("%s/", s.URL))
+
+		project, err := client.Create(request)
+		require.Nil(t, project)
+		require.EqualError(t, err, fmt.Sprintf("POST %s/api/backend/projects/: 409 - %s", s.URL, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+}
+
 func testCreateProjectClient(t *testing.T, url string) IProjects {
 	t.Helper()
 	return ProjectsClient{
@@ -0,0 +1,44 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/davidebianchi/go-jsonclient"
+)
+
+// CreateServiceRequest holds the settings of a service to be created in the
+// project configuration from a marketplace item
+type CreateServiceRequest struct {
+	Name        string `json:"serviceName"`
+	TemplateID  string `json:"templateId"`
+	Description string `json:"description,omitempty"`
+}
+
+// CreateServiceResponse describes the created service and its repository
+type CreateServiceResponse struct {
+	ID            string `json:"id"`
+	Name          string `json:"serviceName"`
+	RepositoryURL string `json:"repositoryUrl"`
+}
+
+// ServicesClient is the console implementations of the IServices interface
+type ServicesClient struct {
+	JSONClient *jsonclient.Client
+	HTTPClient *http.Client
+}
+
+// Create method creates a new service in the configuration of the project
+func (s ServicesClient) Create(projectID string, request CreateServiceRequest) (*CreateServiceResponse, error) {
+	project, err := getProjectByID(s.HTTPClient, s.JSONClient, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("api/backend/projects/%s/service/", project.ID)
+	response := &CreateServiceResponse{}
+	if err := doRequest(s.HTTPClient, s.JSONClient, http.MethodPost, path, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
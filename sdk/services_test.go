@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServicesCreate(t *testing.T) {
+	projectsListResponseBody := readTestData(t, "projects.json")
+	request := CreateServiceRequest{
+		Name:        "my-service",
+		TemplateID:  "node-template",
+		Description: "my service",
+	}
+	projectRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.True(t, strings.HasSuffix(req.URL.Path, "/projects/"))
+		require.Equal(t, http.MethodGet, req.Method)
+	}
+	createRequestAssertions := func(t *testing.T, req *http.Request) {
+		t.Helper()
+		require.Equal(t, "/api/backend/projects/mongo-id-2/service/", req.URL.Path)
+		require.Equal(t, http.MethodPost, req.Method)
+		cookieSid, err := req.Cookie("sid")
+		require.NoError(t, err)
+		require.Equal(t, &http.Cookie{Name: "sid", Value: "my-random-sid"}, cookieSid)
+
+		var body CreateServiceRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		require.Equal(t, request, body)
+	}
+
+	t.Run("Error occurs when projectId does not exist", func(t *testing.T) {
+		s := testCreateResponseServer(t, projectRequestAssertions, projectsListResponseBody, 200)
+		defer s.Close()
+		client := testCreateServicesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		service, err := client.Create("project-NaN", request)
+		require.Nil(t, service)
+		require.EqualError(t, err, fmt.Sprintf("%s: project-NaN", ErrProjectNotFound))
+	})
+
+	t.Run("throws when server respond with 409", func(t *testing.T) {
+		responseBody := `{"statusCode":409,"error":"Conflict","message":"service already exists"}`
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: createRequestAssertions, body: responseBody, status: 409},
+		})
+		defer s.Close()
+		client := testCreateServicesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		service, err := client.Create("project-2", request)
+		require.Nil(t, service)
+		require.EqualError(t, err, fmt.Sprintf("POST %s/api/backend/projects/mongo-id-2/service/: 409 - %s", s.URL, responseBody))
+		require.True(t, errors.Is(err, ErrHTTP))
+	})
+
+	t.Run("correctly creates service", func(t *testing.T) {
+		s := testCreateMultiResponseServer(t, []response{
+			{assertions: projectRequestAssertions, body: projectsListResponseBody, status: 200},
+			{assertions: createRequestAssertions, body: `{"id":"service-id","serviceName":"my-service","repositoryUrl":"https://git.example.com/my-service"}`, status: 200},
+		})
+		defer s.Close()
+		client := testCreateServicesClient(t, fmt.Sprintf("%s/", s.URL))
+
+		service, err := client.Create("project-2", request)
+		require.NoError(t, err)
+		require.Equal(t, &CreateServiceResponse{
+			ID:            "service-id",
+			Name:          "my-service",
+			RepositoryURL: "https://git.example.com/my-service",
+		}, service)
+	})
+}
+
+func testCreateServicesClient(t *testing.T, url string) IServices {
+	t.Helper()
+	return ServicesClient{
+		JSONClient: testCreateClient(t, url),
+	}
+}
@@ -5,6 +5,8 @@ type ProjectsMock struct {
 	Error    error
 	Options  Options
 	Projects Projects
+
+	CreateAssertFn func(CreateProjectRequest)
 }
 
 // DeployMock is useful to be used to mock deploy client.
@@ -12,15 +14,90 @@ type DeployMock struct {
 	Error    error
 	AssertFn func(DeployHistoryQuery)
 	History  []DeployItem
+
+	TriggerAssertFn func(string, DeployConfig)
+	TriggerResponse DeployResponse
+	// Statuses are returned in order by subsequent GetStatus calls,
+	// the last one is repeated once all the others have been consumed.
+	Statuses []PipelineStatus
+
+	statusCalls int
+}
+
+// CompaniesMock is useful to be used to mock companies client
+type CompaniesMock struct {
+	Error     error
+	Companies Companies
+}
+
+// MarketplaceMock is useful to be used to mock marketplace client
+type MarketplaceMock struct {
+	Error    error
+	AssertFn func(MarketplaceQuery)
+	Items    MarketplaceItems
+}
+
+// ServicesMock is useful to be used to mock services client
+type ServicesMock struct {
+	Error          error
+	CreateAssertFn func(string, CreateServiceRequest)
+	CreateResponse *CreateServiceResponse
+}
+
+// MembersMock is useful to be used to mock members client
+type MembersMock struct {
+	Error           error
+	Members         Members
+	AddAssertFn     func(MembersScope, AddMemberRequest)
+	RemoveAssertFn  func(MembersScope, string)
+	SetRoleAssertFn func(MembersScope, string, string)
+}
+
+// APIKeysMock is useful to be used to mock api keys client
+type APIKeysMock struct {
+	Error          error
+	APIKeys        APIKeys
+	CreateAssertFn func(string, CreateAPIKeyRequest)
+	DeleteAssertFn func(string, string)
+	RotateAssertFn func(string, string)
 }
 
 // MockClientError passes error to mia client mock
 type MockClientError struct {
-	ProjectsError error
+	ProjectsError         error
+	Projects              Projects
+	ProjectCreateAssertFn func(CreateProjectRequest)
 
 	DeployError    error
 	DeployAssertFn func(DeployHistoryQuery)
 	DeployHistory  []DeployItem
+
+	DeployTriggerAssertFn func(string, DeployConfig)
+	DeployTriggerResponse DeployResponse
+	DeployStatuses        []PipelineStatus
+
+	CompaniesError error
+	Companies      Companies
+
+	MarketplaceError    error
+	MarketplaceAssertFn func(MarketplaceQuery)
+	MarketplaceItems    MarketplaceItems
+
+	ServicesError         error
+	ServiceCreateAssertFn func(string, CreateServiceRequest)
+	ServiceCreateResponse *CreateServiceResponse
+
+	MembersError          error
+	Members               Members
+	MemberAddAssertFn     func(MembersScope, AddMemberRequest)
+	MemberRemoveAssertFn  func(MembersScope, string)
+	MemberSetRoleAssertFn func(MembersScope, string, string)
+
+	APIKeysError         error
+	APIKeys              APIKeys
+	APIKeyCreateAssertFn func(string, CreateAPIKeyRequest)
+	APIKeyDeleteAssertFn func(string, string)
+	APIKeyRotateAssertFn func(string, string)
 }
 
 // WrapperMockMiaClient creates a mock of mia client
@@ -28,13 +105,48 @@ func WrapperMockMiaClient(errors MockClientError) func(opts Options) (*MiaClient
 	return func(opts Options) (*MiaClient, error) {
 		return &MiaClient{
 			Projects: &ProjectsMock{
-				Error:   errors.ProjectsError,
-				Options: opts,
+				Error:    errors.ProjectsError,
+				Options:  opts,
+				Projects: errors.Projects,
+
+				CreateAssertFn: errors.ProjectCreateAssertFn,
 			},
 			Deploy: &DeployMock{
 				Error:    errors.DeployError,
 				AssertFn: errors.DeployAssertFn,
 				History:  errors.DeployHistory,
+
+				TriggerAssertFn: errors.DeployTriggerAssertFn,
+				TriggerResponse: errors.DeployTriggerResponse,
+				Statuses:        errors.DeployStatuses,
+			},
+			Companies: &CompaniesMock{
+				Error:     errors.CompaniesError,
+				Companies: errors.Companies,
+			},
+			Marketplace: &MarketplaceMock{
+				Error:    errors.MarketplaceError,
+				AssertFn: errors.MarketplaceAssertFn,
+				Items:    errors.MarketplaceItems,
+			},
+			Services: &ServicesMock{
+				Error:          errors.ServicesError,
+				CreateAssertFn: errors.ServiceCreateAssertFn,
+				CreateResponse: errors.ServiceCreateResponse,
+			},
+			Members: &MembersMock{
+				Error:           errors.MembersError,
+				Members:         errors.Members,
+				AddAssertFn:     errors.MemberAddAssertFn,
+				RemoveAssertFn:  errors.MemberRemoveAssertFn,
+				SetRoleAssertFn: errors.MemberSetRoleAssertFn,
+			},
+			APIKeys: &APIKeysMock{
+				Error:          errors.APIKeysError,
+				APIKeys:        errors.APIKeys,
+				CreateAssertFn: errors.APIKeyCreateAssertFn,
+				DeleteAssertFn: errors.APIKeyDeleteAssertFn,
+				RotateAssertFn: errors.APIKeyRotateAssertFn,
 			},
 		}, nil
 	}
@@ -62,6 +174,23 @@ func (p ProjectsMock) Get() (Projects, error) {
 	return defaultMockProjects, nil
 }
 
+// Create method mock. It returns error or the project described by request
+func (p ProjectsMock) Create(request CreateProjectRequest) (*Project, error) {
+	if p.Error != nil {
+		return nil, p.Error
+	}
+	if p.CreateAssertFn != nil {
+		p.CreateAssertFn(request)
+	}
+	return &Project{
+		ID:           "created-id",
+		Name:         request.Name,
+		ProjectID:    request.ProjectID,
+		TenantID:     request.TenantID,
+		Environments: request.Environments,
+	}, nil
+}
+
 var defaultMockProjects = Projects{
 	Project{
 		ID:                   "id1",
@@ -97,6 +226,8 @@ var defaultMockProjects = Projects{
 }
 
 // GetHistory method mock. It returns error or a list of deploy items.
+// When the query specifies PerPage, only the requested page of the history
+// is returned.
 func (d DeployMock) GetHistory(query DeployHistoryQuery) ([]DeployItem, error) {
 	if d.Error != nil {
 		return nil, d.Error
@@ -106,5 +237,173 @@ func (d DeployMock) GetHistory(query DeployHistoryQuery) ([]DeployItem, error) {
 		d.AssertFn(query)
 	}
 
-	return d.History, nil
+	if query.PerPage <= 0 {
+		return d.History, nil
+	}
+
+	page := query.Page
+	if page <= 0 {
+		page = 1
+	}
+	start := (page - 1) * query.PerPage
+	if start >= len(d.History) {
+		return []DeployItem{}, nil
+	}
+	end := start + query.PerPage
+	if end > len(d.History) {
+		end = len(d.History)
+	}
+	return d.History[start:end], nil
+}
+
+// Trigger method mock. It returns error or the configured trigger response.
+func (d DeployMock) Trigger(projectID string, cfg DeployConfig) (DeployResponse, error) {
+	if d.Error != nil {
+		return DeployResponse{}, d.Error
+	}
+	if d.TriggerAssertFn != nil {
+		d.TriggerAssertFn(projectID, cfg)
+	}
+	return d.TriggerResponse, nil
+}
+
+// GetStatus method mock. It returns error or the next configured status.
+func (d *DeployMock) GetStatus(projectID string, pipelineID int, environment string) (PipelineStatus, error) {
+	if d.Error != nil {
+		return PipelineStatus{}, d.Error
+	}
+	if len(d.Statuses) == 0 {
+		return PipelineStatus{ID: pipelineID, Status: DeployStatusSuccess}, nil
+	}
+	status := d.Statuses[len(d.Statuses)-1]
+	if d.statusCalls < len(d.Statuses) {
+		status = d.Statuses[d.statusCalls]
+	}
+	d.statusCalls++
+	return status, nil
+}
+
+// Get method mock. It returns error or the configured list of companies
+func (c CompaniesMock) Get() (Companies, error) {
+	if c.Error != nil {
+		return nil, c.Error
+	}
+	return c.Companies, nil
+}
+
+// Get method mock. It returns error or the configured marketplace items
+func (m MarketplaceMock) Get(query MarketplaceQuery) (MarketplaceItems, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	if m.AssertFn != nil {
+		m.AssertFn(query)
+	}
+	return m.Items, nil
+}
+
+// Create method mock. It returns error or the configured response, which
+// defaults to a service named as requested
+func (s ServicesMock) Create(projectID string, request CreateServiceRequest) (*CreateServiceResponse, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.CreateAssertFn != nil {
+		s.CreateAssertFn(projectID, request)
+	}
+	if s.CreateResponse != nil {
+		return s.CreateResponse, nil
+	}
+	return &CreateServiceResponse{Name: request.Name}, nil
+}
+
+// List method mock. It returns error or the configured members
+func (m MembersMock) List(scope MembersScope) (Members, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	return m.Members, nil
+}
+
+// Add method mock. It returns error or a member built from the request
+func (m MembersMock) Add(scope MembersScope, request AddMemberRequest) (*Member, error) {
+	if m.Error != nil {
+		return nil, m.Error
+	}
+	if m.AddAssertFn != nil {
+		m.AddAssertFn(scope, request)
+	}
+	return &Member{Email: request.Email, Role: request.Role}, nil
+}
+
+// Remove method mock. It returns the configured error
+func (m MembersMock) Remove(scope MembersScope, memberID string) error {
+	if m.Error != nil {
+		return m.Error
+	}
+	if m.RemoveAssertFn != nil {
+		m.RemoveAssertFn(scope, memberID)
+	}
+	return nil
+}
+
+// SetRole method mock. It returns the configured error
+func (m MembersMock) SetRole(scope MembersScope, memberID, role string) error {
+	if m.Error != nil {
+		return m.Error
+	}
+	if m.SetRoleAssertFn != nil {
+		m.SetRoleAssertFn(scope, memberID, role)
+	}
+	return nil
+}
+
+// List method mock. It returns error or the configured api keys
+func (a APIKeysMock) List(projectID string) (APIKeys, error) {
+	if a.Error != nil {
+		return nil, a.Error
+	}
+	return a.APIKeys, nil
+}
+
+// Create method mock. It returns error or an api key built from the
+// request, with key "created-key"
+func (a APIKeysMock) Create(projectID string, request CreateAPIKeyRequest) (*APIKey, error) {
+	if a.Error != nil {
+		return nil, a.Error
+	}
+	if a.CreateAssertFn != nil {
+		a.CreateAssertFn(projectID, request)
+	}
+	return &APIKey{ID: "created-id", Name: request.Name, Roles: request.Roles, Key: "created-key"}, nil
+}
+
+// Delete method mock. It returns the configured error
+func (a APIKeysMock) Delete(projectID, apiKeyID string) error {
+	if a.Error != nil {
+		return a.Error
+	}
+	if a.DeleteAssertFn != nil {
+		a.DeleteAssertFn(projectID, apiKeyID)
+	}
+	return nil
+}
+
+// Rotate method mock. It returns error or the api key with the id, with key
+// "rotated-key"
+func (a APIKeysMock) Rotate(projectID, apiKeyID string) (*APIKey, error) {
+	if a.Error != nil {
+		return nil, a.Error
+	}
+	if a.RotateAssertFn != nil {
+		a.RotateAssertFn(projectID, apiKeyID)
+	}
+	apiKey := &APIKey{ID: apiKeyID}
+	for _, existing := range a.APIKeys {
+		if existing.ID == apiKeyID {
+			*apiKey = existing
+		}
+	}
+	apiKey.Key = "rotated-key"
+	return apiKey, nil
 }
@@ -47,6 +47,11 @@ func TestWrapperMockMiaClient(t *testing.T) {
 				AssertFn: nil,
 				History:  nil,
 			},
+			Companies:   &CompaniesMock{},
+			Marketplace: &MarketplaceMock{},
+			Services:    &ServicesMock{},
+			Members:     &MembersMock{},
+			APIKeys:     &APIKeysMock{},
 		}, miaClient)
 	})
 
@@ -102,4 +107,19 @@ func TestWrapperMockMiaClient(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, defaultMockProjects, retProjects)
 	})
+
+	t.Run("deploy mock returns configured statuses in order", func(t *testing.T) {
+		deployClient := &DeployMock{
+			Statuses: []PipelineStatus{
+				{ID: 1, Status: DeployStatusPending},
+				{ID: 1, Status: DeployStatusSuccess},
+			},
+		}
+
+		for _, expected := range []string{DeployStatusPending, DeployStatusSuccess, DeployStatusSuccess} {
+			status, err := deployClient.GetStatus("project-1", 1, "development")
+			require.NoError(t, err)
+			require.Equal(t, expected, status.Status)
+		}
+	})
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/davidebianchi/go-jsonclient"
+	"github.com/mia-platform/miactl/sdk/cassette"
 	"github.com/stretchr/testify/require"
 )
 
@@ -70,3 +71,21 @@ func readTestData(t *testing.T, fileName string) string {
 	require.NoError(t, err)
 	return string(fileContent)
 }
+
+// testReplayCassette returns a client answered by the interactions of the
+// cassette in testdata/cassettes, with the replayer to check that they have
+// all been used.
+func testReplayCassette(t *testing.T, name string) (*MiaClient, *cassette.Replayer) {
+	t.Helper()
+	replayer, err := cassette.Load(fmt.Sprintf("./testdata/cassettes/%s", name))
+	require.NoError(t, err)
+
+	client, err := New(Options{
+		APIBaseURL: "http://console.example.com/",
+		APIKey:     "my-api-key",
+		APICookie:  "sid=my-random-sid",
+		Transport:  replayer,
+	})
+	require.NoError(t, err)
+	return client, replayer
+}